@@ -5,17 +5,26 @@ package main
 // @description This is a sample API for a URL shortener with Swagger documentation.
 // @host localhost:8080
 // @BasePath /
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name Authorization
+// @securityDefinitions.apikey AdminToken
+// @in header
+// @name Authorization
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"net"
+	"net/http"
 	"os"
 	"os/signal"
 	"path"
 	"path/filepath"
-	"runtime"
+	"strconv"
+	"sync"
 	"syscall"
 	"time"
 
@@ -24,8 +33,12 @@ import (
 	"url-shortener/config"
 
 	_ "url-shortener/docs"
+	"url-shortener/migrations"
+	"url-shortener/pkg/alphabet"
 	"url-shortener/pkg/logging"
+	"url-shortener/pkg/metrics"
 	"url-shortener/pkg/storage/postgres"
+	"url-shortener/pkg/storage/redis"
 
 	"url-shortener/internal/controller"
 	"url-shortener/internal/repository"
@@ -33,17 +46,37 @@ import (
 )
 
 const (
-	logFile            = "logs/server.log"
-	serverStartTimeout = 10 * time.Second
+	logFile = "logs/server.log"
 )
 
 func main() {
-	storageFlag := flag.String("storage", "cache", "flag for specifying storage")
-	flag.Parse()
-	fmt.Println(*storageFlag)
+	projectRoot, err := os.Getwd()
+	if err != nil {
+		panic(err)
+	}
+	envFile := filepath.Join(projectRoot, ".env")
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(os.Args[2:], envFile, os.Stdout, os.Stderr))
+	}
 
-	if *storageFlag != "cache" && *storageFlag != "postgres" {
-		panic("the argument: %s, is not supported, specify argument cache or postgres")
+	// 	init config
+	flags := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	printConfig := flags.Bool("print-config", false, "print effective configuration with secrets redacted and exit")
+	cfg, err := config.Load(config.Options{
+		Args:    os.Args[1:],
+		FlagSet: flags,
+		EnvFile: envFile,
+	})
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid configuration: %v\n", err)
+		os.Exit(2)
+	}
+	if *printConfig {
+		fmt.Print(cfg.Redacted())
+		return
 	}
 
 	// 	init logger
@@ -52,43 +85,263 @@ func main() {
 	if err != nil {
 		panic(err)
 	}
+	logger.Infof("effective configuration:\n%s", cfg.Redacted())
 
-	// 	init config
-	projectRoot, err := os.Getwd()
-	if err != nil {
-		logger.Fatalf("Error getting working directory: %v", err)
+	// 	init metrics
+	var registry *metrics.Registry
+	if cfg.Metrics.Enabled {
+		registry = metrics.NewRegistry()
 	}
-	envFilePath := filepath.Join(projectRoot, ".env")
-	cfg := config.GetConfig(logFile, envFilePath)
 
 	// 	init storage
 
 	var storage service.Storage
-	if *storageFlag == "cache" {
-		storage = repository.NewCacheStorage()
-	} else {
-		pool, err := postgres.NewClient(context.Background(), cfg.DataBase)
+	var analyticsStorage service.AnalyticsStorage
+	var keyStorage service.KeyStorage
+	var counter service.Counter
+	var pool *postgres.Pool
+	health := service.NewHealthService(cfg.Health.Timeout)
+	switch cfg.Storage {
+	case "cache":
+		if cfg.Cache.Dir == "" {
+			storage = repository.NewCacheStorage()
+		} else {
+			cacheStorage, err := repository.OpenCacheStorage(cfg.Cache.Dir, cfg.Cache.SnapshotRecords)
+			if err != nil {
+				panic(err)
+			}
+			defer cacheStorage.Close()
+			storage = cacheStorage
+		}
+		analyticsStorage = repository.NewCacheAnalyticsStorage()
+		keyStorage = repository.NewCacheKeyStorage()
+		health.AddCheck("storage", staticCheck("cache"))
+	case "redis":
+		// Статистика переходов и API-ключи хранятся в Redis рядом со ссылками
+		client, err := redis.NewClient(context.Background(), cfg.Redis)
+		if err != nil {
+			panic(err)
+		}
+		defer client.Close()
+		storage = repository.NewRedisStorage(client)
+		counter = repository.NewRedisCounter(client)
+		analyticsStorage = repository.NewRedisAnalyticsStorage(client)
+		keyStorage = repository.NewRedisKeyStorage(client)
+		health.AddCheck("redis", func(ctx context.Context) (string, error) {
+			return cfg.Redis.Addr, client.Ping(ctx)
+		})
+	case "file":
+		// Статистика переходов и API-ключи хранятся в том же файле bbolt, что и ссылки
+		fileStorage, err := repository.NewFileStorage(cfg.File.Path)
 		if err != nil {
 			panic(err)
 		}
-		storage = repository.NewDataBaseStorage(&pool)
+		defer fileStorage.Close()
+		storage = fileStorage
+		analyticsStorage = repository.NewFileAnalyticsStorage(fileStorage)
+		keyStorage = repository.NewFileKeyStorage(fileStorage)
+		health.AddCheck("storage", staticCheck("file"))
+	default:
+		client, err := postgres.NewClient(context.Background(), cfg.DataBase)
+		if err != nil {
+			panic(err)
+		}
+		pool = &client
+		defer pool.Close()
+		storage = repository.NewDataBaseStorage(pool)
+		counter = repository.NewDataBaseCounter(pool)
+		analyticsStorage = repository.NewDataBaseAnalyticsStorage(pool)
+		keyStorage = repository.NewDataBaseKeyStorage(pool)
+		health.AddCheck("postgres", func(ctx context.Context) (string, error) {
+			return "", pool.Ping(ctx)
+		})
+		migrator, err := postgres.NewMigrator(client, migrations.FS)
+		if err != nil {
+			panic(err)
+		}
+		defer migrator.Close()
+		if cfg.AutoMigrate {
+			applied, err := migrator.Up(context.Background())
+			if err != nil {
+				logger.Fatalf("failed to apply migrations: %v", err)
+			}
+			for _, m := range applied {
+				logger.Infof("applied migration %s", m.Name)
+			}
+		}
+		health.AddCheck("migrations", func(ctx context.Context) (string, error) {
+			version, err := migrator.Version(ctx)
+			if err != nil {
+				return "", err
+			}
+			if latest := migrator.Latest(); version < latest {
+				return strconv.FormatInt(version, 10), fmt.Errorf("pending migrations: database is at %d, latest is %d", version, latest)
+			}
+			return strconv.FormatInt(version, 10), nil
+		})
+		if registry != nil {
+			pool.RegisterMetrics(registry)
+		}
+	}
+	if counter == nil {
+		// Счётчик в памяти начинается с текущего времени (2^20 номеров в секунду),
+		// чтобы после перезапуска не перебирать уже выданные номера
+		counter = service.NewMemoryCounter(uint64(time.Now().Unix()) << 20)
+	}
+	generator, err := service.NewCodeGenerator(cfg.Code.Strategy,
+		service.CodeOptions{Alphabet: cfg.Code.Alphabet, Length: cfg.Code.Length}, []byte(cfg.Code.Secret), counter)
+	if err != nil {
+		logger.Fatalf("invalid code generation settings: %v", err)
+	}
+
+	var serviceMetrics *service.Metrics
+	if registry != nil {
+		serviceMetrics = service.NewMetrics(registry, generator.MaxAttempts())
+		storage = service.NewMetricsStorage(storage, cfg.Storage, serviceMetrics)
+	}
+	storage = service.NewTimeoutStorage(storage, service.Timeouts{
+		Read:  cfg.Timeouts.Read,
+		Write: cfg.Timeouts.Write,
+		Batch: cfg.Timeouts.Batch,
+	})
+	if cfg.LRU.Enabled {
+		lru := service.NewLRUStorage(storage, service.LRUOptions{
+			Size:        cfg.LRU.Size,
+			TTL:         cfg.LRU.TTL,
+			NegativeTTL: cfg.LRU.NegativeTTL,
+		})
+		if registry != nil {
+			lru.RegisterMetrics(registry)
+		}
+		storage = lru
+	}
+	var fold func(string) string
+	if cfg.Code.CaseInsensitive {
+		var ok bool
+		if fold, ok = alphabet.Fold(alphabet.Resolve(cfg.Code.Alphabet)); !ok {
+			logger.Fatalf("code alphabet %q mixes upper and lower case letters, case-insensitive codes are not possible", cfg.Code.Alphabet)
+		}
+		// Внешний слой, чтобы кэш и метрики видели уже приведённые коды
+		analyticsStorage = service.NewCaseFoldAnalyticsStorage(analyticsStorage, storage, fold)
+		storage = service.NewCaseFoldStorage(storage, fold)
 	}
 	// // 	init service
-	service := service.NewShortenerService(storage)
+	sweeper := service.NewSweeper(storage, cfg.Expiry.SweepInterval, logger)
+	// Фоновые задачи останавливаются после завершения HTTP-сервера и до закрытия хранилищ,
+	// отложенные вызовы выполняются в обратном порядке
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+	defer func() {
+		stopWorkers()
+		workers.Wait()
+		logger.Info("background workers stopped")
+	}()
+	workers.Add(2)
+	go func() {
+		defer workers.Done()
+		sweeper.Run(workersCtx)
+	}()
+
+	analytics := service.NewAnalyticsService(storage, analyticsStorage, cfg.Analytics.BufferSize, logger)
+	go func() {
+		defer workers.Done()
+		analytics.Run(workersCtx)
+	}()
+
+	authHandler := handler.NewAuthHandler(service.NewAuthService(keyStorage), cfg.Auth.AdminToken, logger)
+
+	normalizer := service.URLNormalizer{
+		AllowedSchemes: cfg.URL.AllowedSchemes,
+		SortQuery:      cfg.URL.SortQuery,
+		StripFragment:  cfg.URL.StripFragment,
+	}
+	service := service.NewShortenerService(storage, normalizer)
+	service.Generator = generator
+	service.Metrics = serviceMetrics
+	service.Fold = fold
 
 	// 	init router
 	router := gin.Default()
+	// Без доверенных прокси IP клиента - адрес соединения, а не подделываемый X-Forwarded-For
+	if err := router.SetTrustedProxies(cfg.Listen.TrustedProxies); err != nil {
+		logger.Fatalf("invalid trusted proxies: %v", err)
+	}
+	if registry != nil {
+		httpMetrics := handler.NewHTTPMetrics(registry)
+		router.Use(httpMetrics.Middleware())
+		httpMetrics.Register(router)
+	}
 
-	handler := handler.NewHandler(service, logger)
-	handler.Register(router)
-	start(router, storage, logger, cfg)
+	middlewares := newMiddlewares(cfg, authHandler, pool, logger)
+	authHandler.Register(router)
+	handler.NewHealthHandler(health).Register(router)
 
+	handler := handler.NewHandler(service, analytics, logger, handler.Options{
+		RedirectStatus: cfg.Redirect.Status,
+		BatchMaxSize:   cfg.Batch.MaxSize,
+		BatchWorkers:   cfg.Batch.Workers,
+		BaseURL:        cfg.Public.BaseURL,
+	})
+	handler.Register(router, middlewares)
+	start(router, health, logger, cfg)
+
+}
+
+func newMiddlewares(cfg *config.Config, authHandler *handler.AuthHandler, pool *postgres.Pool, logger *logging.Logger) handler.Middlewares {
+	var middlewares handler.Middlewares
+	if cfg.Auth.Enabled {
+		middlewares.Auth = append(middlewares.Auth, authHandler.Authenticate)
+	}
+	if !cfg.RateLimit.Enabled {
+		return middlewares
+	}
+
+	limiter := handler.NewRateLimiter(newRateLimitStore(cfg.RateLimit, pool, logger), logger, handler.RateLimiterOptions{
+		Timeout:  cfg.RateLimit.Timeout,
+		FailOpen: cfg.RateLimit.FailOpen,
+	})
+	if policy := (service.RateLimitPolicy{Rate: cfg.RateLimit.ShortenRate, Burst: cfg.RateLimit.ShortenBurst}); policy.Enabled() {
+		middlewares.Shorten = append(middlewares.Shorten, limiter.Limit("shorten", policy))
+	}
+	if policy := (service.RateLimitPolicy{Rate: cfg.RateLimit.ExpandRate, Burst: cfg.RateLimit.ExpandBurst}); policy.Enabled() {
+		middlewares.Expand = append(middlewares.Expand, limiter.Limit("expand", policy))
+	}
+	if policy := (service.RateLimitPolicy{Rate: cfg.RateLimit.BatchRate, Burst: cfg.RateLimit.BatchBurst}); policy.Enabled() {
+		middlewares.Batch = append(middlewares.Batch, limiter.LimitBatch("batch", policy))
+	}
+	return middlewares
 }
 
-func start(router *gin.Engine, storage service.Storage, logger *logging.Logger, cfg *config.Config) {
+func newRateLimitStore(cfg config.RateLimit, pool *postgres.Pool, logger *logging.Logger) service.RateLimitStore {
+	switch cfg.Store {
+	case "memory":
+		return repository.NewCacheRateLimitStore()
+	case "postgres":
+		if pool == nil {
+			logger.Fatal("rate limit store postgres requires -storage=postgres")
+		}
+		return repository.NewDataBaseRateLimitStore(pool)
+	default:
+		logger.Fatalf("unsupported rate limit store: %s, specify memory or postgres", cfg.Store)
+		return nil
+	}
+}
+
+// Проверка хранилища без внешних зависимостей, всегда успешна
+func staticCheck(backend string) service.HealthCheck {
+	return func(context.Context) (string, error) {
+		return backend, nil
+	}
+}
+
+// Обслуживает запросы до сигнала SIGINT/SIGTERM, после чего переводит /readyz в состояние
+// "не готов", дожидается завершения начатых запросов не дольше cfg.Shutdown.DrainTimeout
+// и удаляет файл unix-сокета. Фоновые задачи и хранилища закрывает вызывающий после возврата
+func start(router *gin.Engine, health *service.HealthService, logger *logging.Logger, cfg *config.Config) {
 	logger.Info("start application")
 	var listener net.Listener
 	var listenErr error
+	var socketPath string
 
 	if cfg.Listen.Type == "socket" {
 		appDir, err := filepath.Abs(filepath.Dir(os.Args[0]))
@@ -96,9 +349,13 @@ func start(router *gin.Engine, storage service.Storage, logger *logging.Logger,
 			logger.Fatal(err)
 		}
 		logger.Info("create socket")
-		socketPath := path.Join(appDir, "app.sock")
+		socketPath = path.Join(appDir, "app.sock")
 		logger.Debugf("socket path: %s", socketPath)
 
+		// Сокет мог остаться после аварийного завершения предыдущего запуска
+		if err := os.Remove(socketPath); err != nil && !os.IsNotExist(err) {
+			logger.Fatal(err)
+		}
 		logger.Info("listen unix socket")
 		listener, listenErr = net.Listen("unix", socketPath)
 		logger.Infof("server is listening on unix socket: %s", socketPath)
@@ -112,39 +369,41 @@ func start(router *gin.Engine, storage service.Storage, logger *logging.Logger,
 		logger.Fatal(listenErr)
 	}
 
-	func(ctx context.Context) {
-		ctx, cancel := context.WithCancel(ctx)
-		go func() {
-			defer cancel()
-			logger.Fatal(router.RunListener(listener))
-		}()
-		notifyCtx, notify := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
-		defer notify()
-
-		go func() {
-			defer cancel()
-			<-notifyCtx.Done()
-			closer := make(chan struct{})
-
-			go func() {
-				closer <- struct{}{}
-			}()
-
-			shutdownCtx, shutdown := context.WithTimeout(context.Background(), serverStartTimeout)
-			defer shutdown()
-			runtime.Gosched()
-
-			select {
-			case <-closer:
-				logger.Info("shutting down gracefully")
-			case <-shutdownCtx.Done():
-				logger.Info("shutting down forcefully")
-			}
-		}()
+	server := &http.Server{Handler: router}
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.Serve(listener)
+	}()
+
+	notifyCtx, notify := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer notify()
+	select {
+	case err := <-serveErr:
+		logger.Errorf("server stopped: %v", err)
+	case <-notifyCtx.Done():
+		logger.Info("shutdown signal received")
+	}
+	// Повторный сигнал завершает процесс сразу
+	notify()
 
-		<-ctx.Done()
-		cancel()
+	health.StartShutdown()
+	if delay := cfg.Shutdown.ReadinessDelay; delay > 0 {
+		logger.Infof("readiness is off, waiting %s before draining", delay)
+		time.Sleep(delay)
+	}
 
-	}(context.Background())
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Shutdown.DrainTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Warnf("shutting down forcefully: %v", err)
+		server.Close()
+	} else {
+		logger.Info("shutting down gracefully")
+	}
 
+	if socketPath != "" {
+		if err := os.Remove(socketPath); err != nil && !os.IsNotExist(err) {
+			logger.Errorf("failed to remove socket %s: %v", socketPath, err)
+		}
+	}
 }
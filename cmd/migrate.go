@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"url-shortener/config"
+	"url-shortener/migrations"
+	"url-shortener/pkg/storage/postgres"
+)
+
+const migrateUsage = `usage: %s migrate <command> [flags]
+
+commands:
+  up       apply all pending migrations
+  down     roll back the last applied migration
+  status   list migrations and whether they are applied
+  version  print the version of the last applied migration
+
+Connection flags are the same as the server's, run "%[1]s migrate <command> -h" to list them.
+`
+
+// Выполняет подкоманду migrate и возвращает код завершения процесса
+func runMigrate(args []string, envFile string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, migrateUsage, os.Args[0])
+	}
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		flags.Usage()
+		return 2
+	}
+	command := args[0]
+	switch command {
+	case "up", "down", "status", "version":
+	default:
+		fmt.Fprintf(stderr, "unknown migrate command %q\n\n", command)
+		flags.Usage()
+		return 2
+	}
+
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "usage: %s migrate %s [flags]\n\nflags:\n", os.Args[0], command)
+		flags.PrintDefaults()
+	}
+	cfg, err := config.Load(config.Options{Args: args[1:], FlagSet: flags, EnvFile: envFile})
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err == nil {
+		err = cfg.DataBase.Validate()
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "invalid configuration: %v\n", err)
+		return 2
+	}
+
+	ctx, stop := signalContext()
+	defer stop()
+	pool, err := postgres.NewClient(ctx, cfg.DataBase)
+	if err != nil {
+		fmt.Fprintf(stderr, "connect to database: %v\n", err)
+		return 1
+	}
+	defer pool.Close()
+	migrator, err := postgres.NewMigrator(pool, migrations.FS)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	defer migrator.Close()
+
+	switch command {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, m := range applied {
+			fmt.Fprintf(stdout, "applied %s\n", m.Name)
+		}
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		if len(applied) == 0 {
+			fmt.Fprintln(stdout, "no pending migrations")
+		}
+	case "down":
+		m, err := migrator.Down(ctx)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		fmt.Fprintf(stdout, "rolled back %s\n", m.Name)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "MIGRATION\tAPPLIED AT")
+		for _, s := range statuses {
+			appliedAt := "pending"
+			if s.Applied {
+				appliedAt = s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%s\t%s\n", s.Name, appliedAt)
+		}
+		w.Flush()
+	case "version":
+		version, err := migrator.Version(ctx)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		fmt.Fprintln(stdout, version)
+	}
+	return 0
+}
+
+// Контекст, отменяемый по SIGINT/SIGTERM
+func signalContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+}
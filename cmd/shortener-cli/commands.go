@@ -0,0 +1,302 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"url-shortener/pkg/client"
+)
+
+func (app *cli) shorten(args []string) int {
+	flags := app.flagSet("shorten", "<long_url>")
+	alias := flags.String("alias", "", "desired short code")
+	ttl := flags.Duration("ttl", 0, "link lifetime, e.g. 24h")
+	expiresAt := flags.String("expires-at", "", "link expiry time in RFC 3339")
+	if code, ok := app.parse(flags, args, 1); !ok {
+		return code
+	}
+	req := client.ShortenRequest{LongURL: flags.Arg(0), Alias: *alias, TTLSeconds: int64(ttl.Seconds())}
+	if *ttl > 0 && req.TTLSeconds == 0 {
+		fmt.Fprintln(app.stderr, "shorten: -ttl must be at least 1s")
+		return exitUsage
+	}
+	if *expiresAt != "" {
+		t, err := time.Parse(time.RFC3339, *expiresAt)
+		if err != nil {
+			fmt.Fprintf(app.stderr, "shorten: invalid -expires-at: %v\n", err)
+			return exitUsage
+		}
+		req.ExpiresAt = &t
+	}
+
+	ctx, cancel := app.context()
+	defer cancel()
+	code, err := app.client.Shorten(ctx, req)
+	if err != nil {
+		return app.fail(err)
+	}
+	return app.print(map[string]string{"short_url": code}, func(w io.Writer) {
+		fmt.Fprintln(w, code)
+	})
+}
+
+func (app *cli) expand(args []string) int {
+	flags := app.flagSet("expand", "<code>")
+	if code, ok := app.parse(flags, args, 1); !ok {
+		return code
+	}
+	ctx, cancel := app.context()
+	defer cancel()
+	longURL, err := app.client.Expand(ctx, flags.Arg(0))
+	if err != nil {
+		return app.fail(err)
+	}
+	return app.print(map[string]string{"long_url": longURL}, func(w io.Writer) {
+		fmt.Fprintln(w, longURL)
+	})
+}
+
+// Результат пакетного сокращения вместе с исходной ссылкой
+type batchLine struct {
+	LongURL string `json:"long_url"`
+	client.BatchResult
+}
+
+// Сколько ссылок отправляется одним запросом: лимит сервера BATCH_MAX_SIZE по умолчанию
+const defaultBatchChunk = 1000
+
+func (app *cli) batch(args []string) int {
+	flags := app.flagSet("batch", "")
+	file := flags.String("file", "-", "input file, - for stdin")
+	chunk := flags.Int("chunk", defaultBatchChunk, "max URLs per request, must not exceed the server BATCH_MAX_SIZE")
+	if code, ok := app.parse(flags, args, 0); !ok {
+		return code
+	}
+	if *chunk < 1 {
+		fmt.Fprintln(app.stderr, "batch: -chunk must be positive")
+		return exitUsage
+	}
+	in := app.stdin
+	if *file != "-" {
+		f, err := os.Open(*file)
+		if err != nil {
+			return app.fail(err)
+		}
+		defer f.Close()
+		in = f
+	}
+	reqs, err := readBatch(in)
+	if err != nil {
+		fmt.Fprintf(app.stderr, "batch: %v\n", err)
+		return exitUsage
+	}
+	if len(reqs) == 0 {
+		fmt.Fprintln(app.stderr, "batch: no URLs in input")
+		return exitUsage
+	}
+
+	// Пакет больше лимита сервера отправляется частями. Если часть не удалась, результаты
+	// уже отправленных частей всё равно печатаются: их ссылки сохранены
+	lines := make([]batchLine, 0, len(reqs))
+	exit := exitOK
+	var failed error
+	for start := 0; start < len(reqs); start += *chunk {
+		part := reqs[start:min(start+*chunk, len(reqs))]
+		results, err := app.shortenBatch(part)
+		if err != nil {
+			failed = err
+			break
+		}
+		for i, res := range results {
+			line := batchLine{BatchResult: res}
+			if i < len(part) {
+				line.LongURL = part[i].LongURL
+			}
+			lines = append(lines, line)
+			exit = worseExit(exit, exitCode(res.Err()))
+		}
+	}
+	if failed != nil && len(lines) == 0 {
+		return app.fail(failed)
+	}
+	if code := app.print(lines, func(w io.Writer) {
+		fmt.Fprintln(w, "STATUS\tSHORT URL\tLONG URL\tERROR")
+		for _, line := range lines {
+			errText := line.Error
+			if line.Reason != "" {
+				errText += " (" + line.Reason + ")"
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", line.Status, line.ShortURL, line.LongURL, errText)
+		}
+	}); code != exitOK {
+		return code
+	}
+	if failed != nil {
+		fmt.Fprintf(app.stderr, "error: %d of %d URLs not sent: %v\n", len(reqs)-len(lines), len(reqs), failed)
+		return worseExit(exit, exitCode(failed))
+	}
+	return exit
+}
+
+// Отправляет одну часть пакета со своим таймаутом
+func (app *cli) shortenBatch(reqs []client.ShortenRequest) ([]client.BatchResult, error) {
+	ctx, cancel := app.context()
+	defer cancel()
+	return app.client.ShortenBatch(ctx, reqs)
+}
+
+// Код завершения пакета с неудачными ссылками: ошибка сервера важнее отказа, отказ важнее отсутствия
+func worseExit(a, b int) int {
+	rank := map[int]int{exitOK: 0, exitNotFound: 1, exitRejected: 2, exitError: 3}
+	if rank[b] > rank[a] {
+		return b
+	}
+	return a
+}
+
+// Читает пакет ссылок: JSON-массив запросов, либо по одному запросу на строку -
+// JSON-объект или длинная ссылка с необязательным коротким кодом через пробел.
+// Пустые строки и строки, начинающиеся с #, пропускаются
+func readBatch(r io.Reader) ([]client.ShortenRequest, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var reqs []client.ShortenRequest
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &reqs); err != nil {
+			return nil, fmt.Errorf("invalid JSON array: %w", err)
+		}
+		return reqs, nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "{") {
+			var req client.ShortenRequest
+			if err := json.Unmarshal([]byte(line), &req); err != nil {
+				return nil, fmt.Errorf("line %d: %w", n, err)
+			}
+			reqs = append(reqs, req)
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) > 2 {
+			return nil, fmt.Errorf("line %d: expected \"<long_url> [alias]\"", n)
+		}
+		req := client.ShortenRequest{LongURL: fields[0]}
+		if len(fields) == 2 {
+			req.Alias = fields[1]
+		}
+		reqs = append(reqs, req)
+	}
+	return reqs, scanner.Err()
+}
+
+func (app *cli) stats(args []string) int {
+	flags := app.flagSet("stats", "<code>")
+	days := flags.Int("days", 0, "period in days, server default if 0")
+	if code, ok := app.parse(flags, args, 1); !ok {
+		return code
+	}
+	ctx, cancel := app.context()
+	defer cancel()
+	stats, err := app.client.Stats(ctx, flags.Arg(0), *days)
+	if err != nil {
+		return app.fail(err)
+	}
+	return app.print(stats, func(w io.Writer) {
+		fmt.Fprintln(w, "DATE\tCLICKS")
+		for _, day := range stats.Daily {
+			fmt.Fprintf(w, "%s\t%d\n", day.Date, day.Clicks)
+		}
+		fmt.Fprintf(w, "total\t%d\n", stats.Total)
+	})
+}
+
+func (app *cli) delete(args []string) int {
+	flags := app.flagSet("delete", "<code>")
+	if code, ok := app.parse(flags, args, 1); !ok {
+		return code
+	}
+	ctx, cancel := app.context()
+	defer cancel()
+	if err := app.client.DeleteLink(ctx, flags.Arg(0)); err != nil {
+		return app.fail(err)
+	}
+	return app.print(map[string]string{"deleted": flags.Arg(0)}, func(w io.Writer) {
+		fmt.Fprintf(w, "deleted %s\n", flags.Arg(0))
+	})
+}
+
+func (app *cli) keys(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(app.stderr, "usage: shortener-cli keys create <name> | list | revoke <id>")
+		return exitUsage
+	}
+	switch args[0] {
+	case "create":
+		flags := app.flagSet("keys create", "<name>")
+		if code, ok := app.parse(flags, args[1:], 1); !ok {
+			return code
+		}
+		ctx, cancel := app.context()
+		defer cancel()
+		key, err := app.client.CreateKey(ctx, flags.Arg(0))
+		if err != nil {
+			return app.fail(err)
+		}
+		return app.print(key, func(w io.Writer) {
+			fmt.Fprintln(w, "ID\tNAME\tKEY")
+			fmt.Fprintf(w, "%s\t%s\t%s\n", key.ID, key.Name, key.Key)
+		})
+	case "list":
+		flags := app.flagSet("keys list", "")
+		if code, ok := app.parse(flags, args[1:], 0); !ok {
+			return code
+		}
+		ctx, cancel := app.context()
+		defer cancel()
+		keys, err := app.client.ListKeys(ctx)
+		if err != nil {
+			return app.fail(err)
+		}
+		return app.print(keys, func(w io.Writer) {
+			fmt.Fprintln(w, "ID\tNAME\tCREATED\tREVOKED")
+			for _, key := range keys {
+				revoked := "-"
+				if key.RevokedAt != nil {
+					revoked = key.RevokedAt.Format(time.RFC3339)
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", key.ID, key.Name, key.CreatedAt.Format(time.RFC3339), revoked)
+			}
+		})
+	case "revoke":
+		flags := app.flagSet("keys revoke", "<id>")
+		if code, ok := app.parse(flags, args[1:], 1); !ok {
+			return code
+		}
+		ctx, cancel := app.context()
+		defer cancel()
+		if err := app.client.RevokeKey(ctx, flags.Arg(0)); err != nil {
+			return app.fail(err)
+		}
+		return app.print(map[string]string{"revoked": flags.Arg(0)}, func(w io.Writer) {
+			fmt.Fprintf(w, "revoked %s\n", flags.Arg(0))
+		})
+	default:
+		fmt.Fprintf(app.stderr, "unknown keys command %q\n", args[0])
+		return exitUsage
+	}
+}
@@ -0,0 +1,216 @@
+// Командная строка для HTTP API сервиса сокращения ссылок
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"url-shortener/pkg/client"
+)
+
+// Коды завершения. Отсутствующая или истёкшая ссылка отличается от отказа сервиса
+// в запросе (неверный ввод, ключ, конфликт, лимит) и от ошибки сервера или сети
+const (
+	exitOK       = 0
+	exitError    = 1 // Ошибка сервера (5xx), сети или ввода-вывода
+	exitUsage    = 2 // Неверные аргументы командной строки
+	exitNotFound = 3 // Ссылка или ключ не найдены (404) либо ссылка истекла (410)
+	exitRejected = 4 // Остальные ответы 4xx
+)
+
+const usage = `usage: %s [flags] <command> [command flags] [args]
+
+commands:
+  shorten <long_url>        shorten a URL and print its code
+  expand <code>             print the long URL of a code
+  batch [-file path]        shorten URLs read from a file or stdin
+  stats <code>              print click statistics of a code
+  delete <code>             delete a short link
+  keys create <name>        create an API key (admin)
+  keys list                 list API keys (admin)
+  keys revoke <id>          revoke an API key (admin)
+
+flags:
+`
+
+const exitCodesUsage = `
+exit codes:
+  0  success
+  1  server, network or I/O error
+  2  invalid command line
+  3  link or key not found, or link expired
+  4  request rejected: invalid input, bad API key, conflict, rate limit
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// Общие параметры и окружение подкоманд
+type cli struct {
+	client  *client.Client
+	output  string
+	timeout time.Duration
+	stdin   io.Reader
+	stdout  io.Writer
+	stderr  io.Writer
+}
+
+// Выполняет команду и возвращает код завершения процесса
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("shortener-cli", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, usage, flags.Name())
+		flags.PrintDefaults()
+		fmt.Fprint(stderr, exitCodesUsage)
+	}
+	server := flags.String("server", envOr("SHORTENER_URL", "http://localhost:8080"), "service base URL (env SHORTENER_URL)")
+	apiKey := flags.String("api-key", "", "API key for shorten, batch and delete (env SHORTENER_API_KEY)")
+	adminToken := flags.String("admin-token", "", "admin token for keys (env SHORTENER_ADMIN_TOKEN)")
+	socket := flags.String("socket", os.Getenv("SHORTENER_SOCKET"), "connect through unix socket, e.g. app.sock when LISTEN_TYPE=socket (env SHORTENER_SOCKET)")
+	output := flags.String("output", envOr("SHORTENER_OUTPUT", "table"), "output format: table or json (env SHORTENER_OUTPUT)")
+	timeout := flags.Duration("timeout", 30*time.Second, "request timeout")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	// Секреты не подставляются значениями флагов по умолчанию, чтобы не попасть в справку
+	if *apiKey == "" {
+		*apiKey = os.Getenv("SHORTENER_API_KEY")
+	}
+	if *adminToken == "" {
+		*adminToken = os.Getenv("SHORTENER_ADMIN_TOKEN")
+	}
+	if *output != "table" && *output != "json" {
+		fmt.Fprintf(stderr, "unsupported output %q, specify table or json\n", *output)
+		return exitUsage
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	opts := []client.Option{client.WithAPIKey(*apiKey), client.WithAdminToken(*adminToken)}
+	if *socket != "" {
+		opts = append(opts, client.WithUnixSocket(*socket))
+	}
+	c, err := client.New(*server, opts...)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitUsage
+	}
+	app := &cli{client: c, output: *output, timeout: *timeout, stdin: stdin, stdout: stdout, stderr: stderr}
+
+	command, rest := flags.Arg(0), flags.Args()[1:]
+	commands := map[string]func([]string) int{
+		"shorten": app.shorten,
+		"expand":  app.expand,
+		"batch":   app.batch,
+		"stats":   app.stats,
+		"delete":  app.delete,
+		"keys":    app.keys,
+	}
+	cmd, ok := commands[command]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command %q\n\n", command)
+		flags.Usage()
+		return exitUsage
+	}
+	return cmd(rest)
+}
+
+// Набор флагов подкоманды, args описывает позиционные аргументы в справке
+func (app *cli) flagSet(name, args string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(app.stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(app.stderr, "usage: shortener-cli %s [flags] %s\n", name, args)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// Разбирает флаги подкоманды и проверяет число позиционных аргументов.
+// Возвращает код завершения, если выполнять команду не нужно
+func (app *cli) parse(flags *flag.FlagSet, args []string, nargs int) (int, bool) {
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK, false
+		}
+		return exitUsage, false
+	}
+	if flags.NArg() != nargs {
+		fmt.Fprintf(app.stderr, "%s: expected %d argument(s), got %d\n", flags.Name(), nargs, flags.NArg())
+		flags.Usage()
+		return exitUsage, false
+	}
+	return exitOK, true
+}
+
+// Контекст запроса с таймаутом, отменяемый по SIGINT/SIGTERM
+func (app *cli) context() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	ctx, cancel := context.WithTimeout(ctx, app.timeout)
+	return ctx, func() {
+		cancel()
+		stop()
+	}
+}
+
+// Печатает ошибку и возвращает соответствующий ей код завершения
+func (app *cli) fail(err error) int {
+	fmt.Fprintln(app.stderr, "error:", err)
+	return exitCode(err)
+}
+
+func exitCode(err error) int {
+	var apiErr *client.Error
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, client.ErrNotFound), errors.Is(err, client.ErrExpired):
+		return exitNotFound
+	case errors.As(err, &apiErr) && apiErr.StatusCode < http.StatusInternalServerError:
+		return exitRejected
+	default:
+		return exitError
+	}
+}
+
+// Печатает v в формате JSON либо таблицей, которую строит table
+func (app *cli) print(v interface{}, table func(w io.Writer)) int {
+	if app.output == "json" {
+		encoder := json.NewEncoder(app.stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(v); err != nil {
+			return app.fail(err)
+		}
+		return exitOK
+	}
+	w := tabwriter.NewWriter(app.stdout, 0, 0, 2, ' ', 0)
+	table(w)
+	if err := w.Flush(); err != nil {
+		return app.fail(err)
+	}
+	return exitOK
+}
+
+func envOr(key, fallback string) string {
+	if v, ok := os.LookupEnv(key); ok && v != "" {
+		return v
+	}
+	return fallback
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"url-shortener/pkg/client"
+)
+
+// Сервис с ответами, которые выбираются по коду или ссылке запроса. Пакеты больше
+// defaultBatchChunk отклоняются, как сервис с BATCH_MAX_SIZE по умолчанию, пакет
+// со ссылкой https://example.com/unavailable целиком отвечает 503
+type fakeServer struct {
+	*httptest.Server
+	mu      sync.Mutex
+	batches []int
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	s := &fakeServer{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /expand", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ShortURL string `json:"short_url"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		switch req.ShortURL {
+		case "missing":
+			writeJSON(w, http.StatusNotFound, map[string]string{"message": "not found"})
+		case "gone":
+			writeJSON(w, http.StatusGone, map[string]string{"message": "link expired"})
+		default:
+			writeJSON(w, http.StatusOK, map[string]string{"long_url": "https://example.com/" + req.ShortURL})
+		}
+	})
+	mux.HandleFunc("POST /shorten", func(w http.ResponseWriter, r *http.Request) {
+		var req client.ShortenRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		switch req.Alias {
+		case "taken":
+			writeJSON(w, http.StatusConflict, map[string]string{"message": "alias is taken"})
+		case "fail":
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"message": "storage is down"})
+		default:
+			writeJSON(w, http.StatusCreated, map[string]string{"short_url": req.Alias})
+		}
+	})
+	mux.HandleFunc("POST /shorten/batch", func(w http.ResponseWriter, r *http.Request) {
+		var reqs []client.ShortenRequest
+		json.NewDecoder(r.Body).Decode(&reqs)
+		s.mu.Lock()
+		s.batches = append(s.batches, len(reqs))
+		s.mu.Unlock()
+		if len(reqs) > defaultBatchChunk {
+			writeJSON(w, http.StatusRequestEntityTooLarge, map[string]string{"message": "batch is too large"})
+			return
+		}
+		for _, req := range reqs {
+			if req.LongURL == "https://example.com/unavailable" {
+				writeJSON(w, http.StatusServiceUnavailable, map[string]string{"message": "shutting down"})
+				return
+			}
+		}
+		results := make([]client.BatchResult, len(reqs))
+		for i, req := range reqs {
+			switch {
+			case req.Alias == "taken":
+				results[i] = client.BatchResult{Status: http.StatusConflict, Error: "alias is taken"}
+			case strings.Contains(req.LongURL, "fail"):
+				results[i] = client.BatchResult{Status: http.StatusInternalServerError, Error: "storage is down"}
+			default:
+				results[i] = client.BatchResult{Status: http.StatusCreated, ShortURL: fmt.Sprintf("c%d", i)}
+			}
+		}
+		writeJSON(w, http.StatusOK, results)
+	})
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func TestRun(t *testing.T) {
+	server := newFakeServer(t)
+	cases := []struct {
+		name  string
+		args  []string
+		stdin string
+		want  int
+	}{
+		{name: "help", args: []string{"-h"}, want: exitOK},
+		{name: "no command", want: exitUsage},
+		{name: "unknown command", args: []string{"open", "abc"}, want: exitUsage},
+		{name: "missing argument", args: []string{"expand"}, want: exitUsage},
+		{name: "expand", args: []string{"expand", "abc"}, want: exitOK},
+		{name: "not found", args: []string{"expand", "missing"}, want: exitNotFound},
+		{name: "expired", args: []string{"expand", "gone"}, want: exitNotFound},
+		{name: "shorten", args: []string{"shorten", "-alias", "abc", "https://example.com"}, want: exitOK},
+		{name: "rejected", args: []string{"shorten", "-alias", "taken", "https://example.com"}, want: exitRejected},
+		{name: "server error", args: []string{"shorten", "-alias", "fail", "https://example.com"}, want: exitError},
+		{name: "batch", args: []string{"batch"}, stdin: "https://example.com/a\nhttps://example.com/b b\n", want: exitOK},
+		{name: "batch rejected", args: []string{"batch"}, stdin: "https://example.com/a\nhttps://example.com/b taken\n", want: exitRejected},
+		{name: "batch server error", args: []string{"batch"}, stdin: "https://example.com/fail\nhttps://example.com/b taken\n", want: exitError},
+		{name: "batch empty", args: []string{"batch"}, stdin: "# nothing\n", want: exitUsage},
+		{name: "batch invalid chunk", args: []string{"batch", "-chunk", "0"}, stdin: "https://example.com/a\n", want: exitUsage},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			args := append([]string{"-server", server.URL}, tc.args...)
+			assert.Equal(t, tc.want, run(args, strings.NewReader(tc.stdin), &stdout, &stderr), stderr.String())
+		})
+	}
+}
+
+func TestRun_BatchChunks(t *testing.T) {
+	server := newFakeServer(t)
+	var input strings.Builder
+	for i := 0; i < 2500; i++ {
+		fmt.Fprintf(&input, "https://example.com/%d\n", i)
+	}
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"-server", server.URL, "-output", "json", "batch"}, strings.NewReader(input.String()), &stdout, &stderr)
+	require.Equal(t, exitOK, code, stderr.String())
+	assert.Equal(t, []int{1000, 1000, 500}, server.batches)
+	var lines []batchLine
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &lines))
+	require.Len(t, lines, 2500)
+	// Результаты частей склеиваются в порядке ввода
+	assert.Equal(t, "https://example.com/0", lines[0].LongURL)
+	assert.Equal(t, "https://example.com/2499", lines[2499].LongURL)
+	assert.Equal(t, "c499", lines[2499].ShortURL)
+}
+
+func TestRun_BatchChunkTooLarge(t *testing.T) {
+	server := newFakeServer(t)
+	input := strings.Repeat("https://example.com\n", defaultBatchChunk+1)
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"-server", server.URL, "batch", "-chunk", "2000"}, strings.NewReader(input), &stdout, &stderr)
+	assert.Equal(t, exitRejected, code)
+	assert.Empty(t, stdout.String())
+	assert.Contains(t, stderr.String(), "413")
+}
+
+// Результаты отправленных частей печатаются, даже если следующая часть не обработана
+func TestRun_BatchPartialFailure(t *testing.T) {
+	server := newFakeServer(t)
+	input := "https://example.com/a\nhttps://example.com/b\nhttps://example.com/unavailable\n"
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"-server", server.URL, "-output", "json", "batch", "-chunk", "2"}, strings.NewReader(input), &stdout, &stderr)
+	assert.Equal(t, exitError, code)
+	var lines []batchLine
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &lines))
+	assert.Len(t, lines, 2)
+	assert.Contains(t, stderr.String(), "1 of 3 URLs not sent")
+}
+
+func TestExitCode(t *testing.T) {
+	cases := []struct {
+		err  error
+		want int
+	}{
+		{nil, exitOK},
+		{&client.Error{StatusCode: http.StatusNotFound}, exitNotFound},
+		{&client.Error{StatusCode: http.StatusGone}, exitNotFound},
+		{&client.Error{StatusCode: http.StatusBadRequest}, exitRejected},
+		{&client.Error{StatusCode: http.StatusConflict}, exitRejected},
+		{&client.Error{StatusCode: http.StatusTooManyRequests}, exitRejected},
+		{&client.Error{StatusCode: http.StatusInternalServerError}, exitError},
+		{&client.Error{StatusCode: http.StatusServiceUnavailable}, exitError},
+		{fmt.Errorf("request: %w", &client.Error{StatusCode: http.StatusNotFound}), exitNotFound},
+		{errors.New("connection refused"), exitError},
+	}
+	for _, tc := range cases {
+		assert.Equal(t, tc.want, exitCode(tc.err), fmt.Sprint(tc.err))
+	}
+}
@@ -1,50 +1,193 @@
 package config
 
 import (
-	"sync"
-	"url-shortener/pkg/logging"
-
-	"github.com/caarlos0/env/v6"
-	"github.com/joho/godotenv"
+	"net"
+	"net/url"
+	"time"
 )
 
+// Конфигурация собирается функцией Load из значений по умолчанию (теги envDefault), файла конфигурации,
+// переменных окружения и флагов командной строки. Теги env вложенных структур задают имена секций
+// файла конфигурации, ключ параметра в секции - имя поля в snake_case
 type Config struct {
-	Listen   Listen   `env:"LISTEN"`
-	DataBase DataBase `env:"DATABASE"`
+	Storage     string    `env:"STORAGE" envDefault:"cache"`      // Хранилище ссылок: cache, postgres, redis или file
+	AutoMigrate bool      `env:"AUTO_MIGRATE" envDefault:"false"` // Применять миграции PostgreSQL при запуске сервера
+	Listen      Listen    `env:"LISTEN"`
+	DataBase    DataBase  `env:"DATABASE"`
+	Redis       Redis     `env:"REDIS"`
+	File        File      `env:"FILE"`
+	Cache       Cache     `env:"CACHE"`
+	LRU         LRU       `env:"LRU"`
+	Redirect    Redirect  `env:"REDIRECT"`
+	Expiry      Expiry    `env:"EXPIRY"`
+	Analytics   Analytics `env:"ANALYTICS"`
+	Auth        Auth      `env:"AUTH"`
+	RateLimit   RateLimit `env:"RATE_LIMIT"`
+	URL         URL       `env:"URL"`
+	Public      Public    `env:"PUBLIC"`
+	Code        Code      `env:"CODE"`
+	Batch       Batch     `env:"BATCH"`
+	Timeouts    Timeouts  `env:"TIMEOUT"`
+	Metrics     Metrics   `env:"METRICS"`
+	Health      Health    `env:"HEALTH"`
+	Shutdown    Shutdown  `env:"SHUTDOWN"`
 }
 
+// Listen задаёт, где сервер принимает соединения: Type port - TCP-адрес BindIP:Port,
+// socket - unix-сокет app.sock рядом с исполняемым файлом. TrustedProxies - адреса и подсети прокси,
+// от которых принимается X-Forwarded-For для определения IP клиента; по умолчанию не доверяется никому
 type Listen struct {
-	Type   string `env:"LISTEN_TYPE" env-default:"port"`
-	BindIP string `env:"BIND_IP" env-default:"127.0.0.1"`
-	Port   string `env:"PORT" env-default:"8080"`
+	Type           string   `env:"LISTEN_TYPE" envDefault:"port"`
+	BindIP         string   `env:"BIND_IP" envDefault:"127.0.0.1"`
+	Port           string   `env:"PORT" envDefault:"8080"`
+	TrustedProxies []string `env:"TRUSTED_PROXIES" envSeparator:","`
 }
 
+// DataBase задаёт подключение к PostgreSQL для хранилища postgres и хранилища лимитов postgres
 type DataBase struct {
-	Host     string `env:"DB_HOST" env-default:"postgres"`
-	Port     string `env:"DB_PORT" env-default:"5432"`
-	Username string `env:"DB_USERNAME" env-default:"postgres"`
-	Password string `env:"DB_PASSWORD" env-default:"postgres"`
-	DBName   string `env:"DB_NAME" env-default:"postgres"`
-}
-
-var instance *Config
-var once sync.Once
-
-func GetConfig(logFile, envFilePath string) *Config {
-	once.Do(func() {
-		logger, err := logging.GetLogger(logFile)
-		if err != nil {
-			panic(err)
-		}
-		logger.Info("read application configuration")
-		instance = &Config{}
-		if err := godotenv.Load(envFilePath); err != nil {
-			logger.Fatal("Error loading .env file")
-		}
-		if err := env.Parse(instance); err != nil {
-			logger.Fatalf("Failed to parse env variables: %v", err)
-		}
-
-	})
-	return instance
+	Host     string `env:"DB_HOST" envDefault:"postgres"`
+	Port     string `env:"DB_PORT" envDefault:"5432"`
+	Username string `env:"DB_USERNAME" envDefault:"postgres"`
+	Password string `env:"DB_PASSWORD" envDefault:"postgres" secret:"true"`
+	DBName   string `env:"DB_NAME" envDefault:"postgres"`
+}
+
+// Строка подключения в формате URL, имя пользователя и пароль экранируются
+func (d DataBase) DSN() string {
+	u := url.URL{
+		Scheme: "postgresql",
+		User:   url.UserPassword(d.Username, d.Password),
+		Host:   net.JoinHostPort(d.Host, d.Port),
+		Path:   "/" + d.DBName,
+	}
+	return u.String()
+}
+
+// Redis задаёт подключение к Redis-совместимому серверу для хранилища -storage=redis
+type Redis struct {
+	Addr     string `env:"REDIS_ADDR" envDefault:"localhost:6379"`
+	Password string `env:"REDIS_PASSWORD" secret:"true"`
+	DB       int    `env:"REDIS_DB" envDefault:"0"`
+	PoolSize int    `env:"REDIS_POOL_SIZE" envDefault:"10"` // Наибольшее число открытых соединений
+}
+
+// File задаёт путь к файлу встроенного хранилища -storage=file
+type File struct {
+	Path string `env:"FILE_PATH" envDefault:"data/links.db"`
+}
+
+// Cache включает сохранение хранилища -storage=cache на диск: журнал изменений и снимки в каталоге Dir.
+// При пустом Dir данные хранятся только в памяти. Снимок пишется после каждых SnapshotRecords записей журнала
+type Cache struct {
+	Dir             string `env:"CACHE_DIR"`
+	SnapshotRecords int    `env:"CACHE_SNAPSHOT_RECORDS" envDefault:"10000"`
+}
+
+// LRU включает кэш ссылок в памяти процесса перед хранилищем: Size - максимальное количество кодов,
+// TTL - время жизни найденной ссылки, NegativeTTL - время жизни отметки об отсутствии кода
+type LRU struct {
+	Enabled     bool          `env:"LRU_ENABLED" envDefault:"false"`
+	Size        int           `env:"LRU_SIZE" envDefault:"10000"`
+	TTL         time.Duration `env:"LRU_TTL" envDefault:"1m"`
+	NegativeTTL time.Duration `env:"LRU_NEGATIVE_TTL" envDefault:"5s"`
+}
+
+// Metrics включает сбор метрик и эндпоинт /metrics в текстовом формате Prometheus
+type Metrics struct {
+	Enabled bool `env:"METRICS_ENABLED" envDefault:"true"`
+}
+
+// Health задаёт ограничение времени каждой проверки зависимости в /readyz
+type Health struct {
+	Timeout time.Duration `env:"HEALTH_TIMEOUT" envDefault:"2s"`
+}
+
+// Shutdown задаёт порядок остановки по SIGINT/SIGTERM: сначала /readyz начинает отвечать 503
+// и сервер ещё ReadinessDelay принимает запросы, пока балансировщик не исключит экземпляр,
+// затем незавершённые запросы обрабатываются не дольше DrainTimeout
+type Shutdown struct {
+	ReadinessDelay time.Duration `env:"SHUTDOWN_READINESS_DELAY" envDefault:"0s"`
+	DrainTimeout   time.Duration `env:"SHUTDOWN_DRAIN_TIMEOUT" envDefault:"15s"`
+}
+
+// Redirect описывает поведение публичного перехода по короткой ссылке
+type Redirect struct {
+	Status int `env:"REDIRECT_STATUS" envDefault:"302"`
+}
+
+// Expiry задаёт периодичность удаления ссылок с истёкшим сроком действия
+type Expiry struct {
+	SweepInterval time.Duration `env:"EXPIRY_SWEEP_INTERVAL" envDefault:"1m"`
+}
+
+// Analytics задаёт размер очереди событий переходов, ожидающих записи в хранилище
+type Analytics struct {
+	BufferSize int `env:"ANALYTICS_BUFFER_SIZE" envDefault:"1024"`
+}
+
+// Auth включает проверку API-ключей на эндпоинтах управления ссылками.
+// AdminToken открывает доступ к созданию и отзыву ключей, при пустом значении эти эндпоинты отключены
+type Auth struct {
+	Enabled    bool   `env:"AUTH_ENABLED" envDefault:"false"`
+	AdminToken string `env:"AUTH_ADMIN_TOKEN" secret:"true"`
+}
+
+// RateLimit задаёт ограничение частоты запросов для групп эндпоинтов: Rate - запросов в секунду в среднем,
+// Burst - сколько запросов можно сделать подряд. Нулевое значение Rate отключает ограничение для группы.
+// Пакетное сокращение считается в ссылках: каждая ссылка пакета - один запрос, поэтому BatchBurst не меньше Batch.MaxSize.
+// Store - хранилище состояния лимитов: memory (в памяти процесса) или postgres (общие квоты для нескольких экземпляров).
+// Timeout ограничивает проверку лимита в хранилище, FailOpen пропускает запросы при ошибке хранилища (иначе 503)
+type RateLimit struct {
+	Enabled      bool          `env:"RATE_LIMIT_ENABLED" envDefault:"false"`
+	Store        string        `env:"RATE_LIMIT_STORE" envDefault:"memory"`
+	ShortenRate  float64       `env:"RATE_LIMIT_SHORTEN_RATE" envDefault:"1"`
+	ShortenBurst int           `env:"RATE_LIMIT_SHORTEN_BURST" envDefault:"10"`
+	ExpandRate   float64       `env:"RATE_LIMIT_EXPAND_RATE" envDefault:"20"`
+	ExpandBurst  int           `env:"RATE_LIMIT_EXPAND_BURST" envDefault:"100"`
+	BatchRate    float64       `env:"RATE_LIMIT_BATCH_RATE" envDefault:"10"`
+	BatchBurst   int           `env:"RATE_LIMIT_BATCH_BURST" envDefault:"1000"`
+	Timeout      time.Duration `env:"RATE_LIMIT_TIMEOUT" envDefault:"200ms"`
+	FailOpen     bool          `env:"RATE_LIMIT_FAIL_OPEN" envDefault:"true"`
+}
+
+// URL задаёт правила проверки и нормализации длинных ссылок
+type URL struct {
+	AllowedSchemes []string `env:"URL_ALLOWED_SCHEMES" envDefault:"http,https" envSeparator:","`
+	SortQuery      bool     `env:"URL_SORT_QUERY" envDefault:"false"`
+	StripFragment  bool     `env:"URL_STRIP_FRAGMENT" envDefault:"false"`
+}
+
+// Public задаёт внешний адрес сервиса, из которого собираются полные короткие ссылки (например, для QR-кодов).
+// При пустом BaseURL используются схема и хост запроса
+type Public struct {
+	BaseURL string `env:"PUBLIC_BASE_URL"`
+}
+
+// Code задаёт генерацию коротких кодов. Strategy: hash - SHA-256 от ссылки (одна ссылка - один код),
+// random - случайный код, counter - номер из счётчика хранилища, переставленный по ключу Secret,
+// hmac - HMAC-SHA256 от ссылки по ключу Secret. Alphabet - символы кода или имя набора (legacy, base62,
+// crockford32, lowercase, human), по умолчанию legacy для hash и hmac и base62 для random и counter.
+// Length ограничена длиной колонки urls.short_url. CaseInsensitive - коды находятся при любом регистре букв,
+// требует алфавита одного регистра
+type Code struct {
+	Strategy        string `env:"CODE_STRATEGY" envDefault:"hash"`
+	Alphabet        string `env:"CODE_ALPHABET"`
+	Length          int    `env:"CODE_LENGTH" envDefault:"10"`
+	Secret          string `env:"CODE_SECRET" secret:"true"`
+	CaseInsensitive bool   `env:"CODE_CASE_INSENSITIVE" envDefault:"false"`
+}
+
+// Batch ограничивает пакетное сокращение ссылок: MaxSize - максимальное количество ссылок в запросе,
+// Workers - количество параллельных обработчиков одного пакета
+type Batch struct {
+	MaxSize int `env:"BATCH_MAX_SIZE" envDefault:"1000"`
+	Workers int `env:"BATCH_WORKERS" envDefault:"8"`
+}
+
+// Timeouts ограничивает время одной операции хранилища ссылок: Read - чтение, Write - запись и удаление,
+// Batch - пакетная вставка. При превышении запрос завершается с кодом 504, нулевое значение отключает ограничение
+type Timeouts struct {
+	Read  time.Duration `env:"TIMEOUT_READ" envDefault:"2s"`
+	Write time.Duration `env:"TIMEOUT_WRITE" envDefault:"5s"`
+	Batch time.Duration `env:"TIMEOUT_BATCH" envDefault:"30s"`
 }
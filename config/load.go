@@ -0,0 +1,267 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/caarlos0/env/v6"
+	"github.com/joho/godotenv"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Переменная окружения с путём к файлу конфигурации, флаг -config имеет приоритет
+const configFileEnv = "CONFIG_FILE"
+
+// Источники конфигурации. Значения применяются по возрастанию приоритета: значения по умолчанию,
+// файл EnvFile (общие настройки проекта), файл конфигурации, переменные окружения, флаги
+type Options struct {
+	// Аргументы командной строки без имени программы. Неразобранные аргументы доступны через FlagSet.Args
+	Args []string
+	// Набор флагов, в который добавляются флаги конфигурации. Позволяет вызывающему
+	// объявить собственные флаги до разбора, при nil создаётся новый набор
+	FlagSet *flag.FlagSet
+	// Переменные окружения, при nil используется окружение процесса
+	Env map[string]string
+	// Путь к .env файлу. Отсутствие файла не является ошибкой
+	EnvFile string
+}
+
+// Параметр конфигурации: путь в файле конфигурации (например listen.bind_ip),
+// имя флага (listen.bind-ip), имя переменной окружения и поле структуры Config
+type param struct {
+	path   string
+	flag   string
+	env    string
+	field  reflect.StructField
+	value  reflect.Value
+	secret bool
+}
+
+// Собирает и проверяет конфигурацию. Флаги конфигурации повторяют пути параметров в файле
+// с дефисами вместо подчёркиваний (-storage, -listen.bind-ip, -auto-migrate), путь к файлу задаёт флаг -config
+// или переменная CONFIG_FILE, формат определяется расширением: .yaml, .yml или .toml
+func Load(opts Options) (*Config, error) {
+	fs := opts.FlagSet
+	if fs == nil {
+		fs = flag.NewFlagSet("url-shortener", flag.ContinueOnError)
+	}
+	cfg := &Config{}
+	params := walk(cfg)
+
+	configFile := fs.String("config", "", "path to YAML or TOML config file (env "+configFileEnv+")")
+	flags := make(map[string]*flagValue, len(params))
+	for _, p := range params {
+		v := &flagValue{isBool: p.field.Type.Kind() == reflect.Bool}
+		flags[p.path] = v
+		fs.Var(v, p.flag, "overrides env `"+p.env+"`")
+	}
+	if err := fs.Parse(opts.Args); err != nil {
+		return nil, err
+	}
+
+	environ := opts.Env
+	if environ == nil {
+		environ = osEnviron()
+	}
+
+	// Значения всех слоёв приводятся к строкам переменных окружения и разбираются один раз,
+	// отсутствующие параметры получают значения из тегов envDefault
+	values := make(map[string]string)
+	if opts.EnvFile != "" {
+		dotenv, err := godotenv.Read(opts.EnvFile)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("read %s: %w", opts.EnvFile, err)
+		}
+		mergeInto(values, dotenv)
+	}
+	path := *configFile
+	if path == "" {
+		path = environ[configFileEnv]
+	}
+	if path != "" {
+		fileValues, err := readFile(path, params)
+		if err != nil {
+			return nil, err
+		}
+		mergeInto(values, fileValues)
+	}
+	mergeInto(values, environ)
+	for _, p := range params {
+		if v := flags[p.path]; v.set {
+			values[p.env] = v.value
+		}
+	}
+
+	if err := env.Parse(cfg, env.Options{Environment: values}); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Читает файл конфигурации и возвращает значения параметров по именам переменных окружения
+func readFile(path string, params []param) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var tree map[string]interface{}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &tree)
+	case ".toml":
+		err = toml.Unmarshal(data, &tree)
+	default:
+		return nil, fmt.Errorf("config file %s: unsupported format %q, use .yaml, .yml or .toml", path, ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	byPath := make(map[string]param, len(params))
+	for _, p := range params {
+		byPath[p.path] = p
+	}
+	values := make(map[string]string)
+	if err := flatten("", tree, byPath, values); err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+	return values, nil
+}
+
+func flatten(prefix string, tree map[string]interface{}, params map[string]param, values map[string]string) error {
+	for key, raw := range tree {
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+		if section, ok := raw.(map[string]interface{}); ok {
+			if err := flatten(path, section, params, values); err != nil {
+				return err
+			}
+			continue
+		}
+		p, ok := params[path]
+		if !ok {
+			return fmt.Errorf("unknown key %s", path)
+		}
+		values[p.env] = formatValue(raw, p.field)
+	}
+	return nil
+}
+
+// Приводит значение из файла к строке в формате переменной окружения
+func formatValue(raw interface{}, field reflect.StructField) string {
+	switch v := raw.(type) {
+	case nil:
+		return ""
+	case []interface{}:
+		sep := field.Tag.Get("envSeparator")
+		if sep == "" {
+			sep = ","
+		}
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = fmt.Sprint(item)
+		}
+		return strings.Join(items, sep)
+	}
+	return fmt.Sprint(raw)
+}
+
+// Перечисляет параметры конфигурации: поля верхнего уровня и поля вложенных структур-секций
+func walk(cfg *Config) []param {
+	var params []param
+	root := reflect.ValueOf(cfg).Elem()
+	for i := 0; i < root.NumField(); i++ {
+		field := root.Type().Field(i)
+		value := root.Field(i)
+		if field.Type.Kind() != reflect.Struct {
+			params = append(params, newParam(snakeCase(field.Name), field, value))
+			continue
+		}
+		section := strings.ToLower(field.Tag.Get("env"))
+		for j := 0; j < value.NumField(); j++ {
+			leaf := field.Type.Field(j)
+			params = append(params, newParam(section+"."+snakeCase(leaf.Name), leaf, value.Field(j)))
+		}
+	}
+	return params
+}
+
+func newParam(path string, field reflect.StructField, value reflect.Value) param {
+	return param{
+		path:   path,
+		flag:   strings.ReplaceAll(path, "_", "-"),
+		env:    field.Tag.Get("env"),
+		field:  field,
+		value:  value,
+		secret: field.Tag.Get("secret") == "true",
+	}
+}
+
+// BindIP -> bind_ip, DBName -> db_name, NegativeTTL -> negative_ttl
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prevLower := unicode.IsLower(runes[i-1])
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if prevLower || (unicode.IsUpper(runes[i-1]) && nextLower) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+func mergeInto(dst, src map[string]string) {
+	for k, v := range src {
+		dst[k] = v
+	}
+}
+
+func osEnviron() map[string]string {
+	environ := make(map[string]string)
+	for _, kv := range os.Environ() {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			environ[k] = v
+		}
+	}
+	return environ
+}
+
+// Значение флага конфигурации. Хранится строкой и разбирается вместе с остальными слоями
+type flagValue struct {
+	value  string
+	set    bool
+	isBool bool
+}
+
+func (v *flagValue) String() string {
+	if v == nil {
+		return ""
+	}
+	return v.value
+}
+
+func (v *flagValue) Set(s string) error {
+	v.value, v.set = s, true
+	return nil
+}
+
+// Позволяет писать -metrics.enabled вместо -metrics.enabled=true
+func (v *flagValue) IsBoolFlag() bool {
+	return v.isBool
+}
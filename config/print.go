@@ -0,0 +1,64 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const redacted = "******"
+
+// Возвращает действующую конфигурацию в формате YAML, пригодном для файла конфигурации.
+// Значения секретных параметров (пароли, токены) заменяются на ******
+func (c Config) Redacted() string {
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	sections := make(map[string]*yaml.Node)
+	for _, p := range walk(&c) {
+		parent, key := root, p.path
+		if section, leaf, ok := strings.Cut(p.path, "."); ok {
+			if sections[section] == nil {
+				sections[section] = &yaml.Node{Kind: yaml.MappingNode}
+				root.Content = append(root.Content, scalar(section), sections[section])
+			}
+			parent, key = sections[section], leaf
+		}
+		parent.Content = append(parent.Content, scalar(key), valueNode(p))
+	}
+	out, err := yaml.Marshal(root)
+	if err != nil {
+		return fmt.Sprintf("config: %v", err)
+	}
+	return string(out)
+}
+
+func valueNode(p param) *yaml.Node {
+	v := p.value.Interface()
+	if p.secret {
+		if s, _ := v.(string); s != "" {
+			v = redacted
+		}
+	}
+	switch v := v.(type) {
+	case time.Duration:
+		return scalar(v.String())
+	case []string:
+		seq := &yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle}
+		for _, item := range v {
+			seq.Content = append(seq.Content, scalar(item))
+		}
+		return seq
+	case string:
+		return scalar(v)
+	}
+	node := &yaml.Node{}
+	if err := node.Encode(v); err != nil {
+		return scalar(fmt.Sprint(v))
+	}
+	return node
+}
+
+func scalar(s string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: s}
+}
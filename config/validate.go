@@ -0,0 +1,162 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+
+	"url-shortener/pkg/alphabet"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Проверяет значения конфигурации и возвращает все найденные ошибки разом
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	switch c.Storage {
+	case "cache", "postgres", "redis", "file":
+	default:
+		errs = append(errs, fmt.Errorf("storage: unsupported value %q, specify cache, postgres, redis or file", c.Storage))
+	}
+
+	switch c.Listen.Type {
+	case "port":
+		check(validPort(c.Listen.Port), "listen.port: %q is not a port in range 1-65535", c.Listen.Port)
+		check(c.Listen.BindIP == "" || net.ParseIP(c.Listen.BindIP) != nil, "listen.bind_ip: %q is not an IP address", c.Listen.BindIP)
+	case "socket":
+	default:
+		errs = append(errs, fmt.Errorf("listen.type: unsupported value %q, specify port or socket", c.Listen.Type))
+	}
+	for _, proxy := range c.Listen.TrustedProxies {
+		check(validProxy(proxy), "listen.trusted_proxies: %q is not an IP address or CIDR", proxy)
+	}
+
+	if c.Storage == "postgres" || (c.RateLimit.Enabled && c.RateLimit.Store == "postgres") {
+		if err := c.DataBase.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	check(!c.AutoMigrate || c.Storage == "postgres", "auto_migrate: requires storage postgres")
+	if c.Storage == "redis" {
+		_, _, err := net.SplitHostPort(c.Redis.Addr)
+		check(err == nil, "redis.addr: %q is not a host:port address", c.Redis.Addr)
+		check(c.Redis.DB >= 0, "redis.db: must not be negative")
+		check(c.Redis.PoolSize > 0, "redis.pool_size: must be positive")
+	}
+	if c.Storage == "file" {
+		check(c.File.Path != "", "file.path: must not be empty")
+	}
+	if c.RateLimit.Enabled {
+		check(c.RateLimit.Store == "memory" || c.RateLimit.Store == "postgres",
+			"rate_limit.store: unsupported value %q, specify memory or postgres", c.RateLimit.Store)
+		check(c.RateLimit.Store != "postgres" || c.Storage == "postgres", "rate_limit.store: postgres requires storage postgres")
+		check(c.RateLimit.ShortenRate >= 0 && c.RateLimit.ExpandRate >= 0 && c.RateLimit.BatchRate >= 0, "rate_limit: rates must not be negative")
+		check(c.RateLimit.BatchRate == 0 || c.RateLimit.BatchBurst >= c.Batch.MaxSize,
+			"rate_limit.batch_burst: %d is less than batch.max_size %d, full batches would always be rejected", c.RateLimit.BatchBurst, c.Batch.MaxSize)
+		check(c.RateLimit.Timeout >= 0, "rate_limit.timeout: must not be negative")
+	}
+
+	check(c.Cache.SnapshotRecords > 0, "cache.snapshot_records: must be positive")
+	check(!c.LRU.Enabled || c.LRU.Size > 0, "lru.size: must be positive")
+	check(c.LRU.TTL >= 0 && c.LRU.NegativeTTL >= 0, "lru: ttl must not be negative")
+	check(c.Redirect.Status == 301 || c.Redirect.Status == 302 || c.Redirect.Status == 307 || c.Redirect.Status == 308,
+		"redirect.status: %d is not one of 301, 302, 307, 308", c.Redirect.Status)
+	check(c.Expiry.SweepInterval > 0, "expiry.sweep_interval: must be positive")
+	check(c.Analytics.BufferSize > 0, "analytics.buffer_size: must be positive")
+	check(c.Batch.MaxSize > 0, "batch.max_size: must be positive")
+	check(c.Batch.Workers > 0, "batch.workers: must be positive")
+	check(c.Timeouts.Read >= 0 && c.Timeouts.Write >= 0 && c.Timeouts.Batch >= 0, "timeout: values must not be negative")
+	check(c.Health.Timeout >= 0, "health.timeout: must not be negative")
+	check(c.Shutdown.ReadinessDelay >= 0, "shutdown.readiness_delay: must not be negative")
+	check(c.Shutdown.DrainTimeout > 0, "shutdown.drain_timeout: must be positive")
+	check(len(c.URL.AllowedSchemes) > 0, "url.allowed_schemes: must not be empty")
+	check(c.Public.BaseURL == "" || validBaseURL(c.Public.BaseURL),
+		"public.base_url: %q is not an absolute http or https URL without query and fragment", c.Public.BaseURL)
+	if err := c.Code.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+func validProxy(s string) bool {
+	if _, _, err := net.ParseCIDR(s); err == nil {
+		return true
+	}
+	return net.ParseIP(s) != nil
+}
+
+// Допустимая длина кода, верхняя граница - длина колонки urls.short_url
+const (
+	minCodeLength = 4
+	maxCodeLength = 32
+)
+
+// Проверяет стратегию, алфавит и длину коротких кодов
+func (c Code) Validate() error {
+	var errs []error
+	switch c.Strategy {
+	case "hash", "random":
+	case "counter", "hmac":
+		if c.Secret == "" {
+			errs = append(errs, fmt.Errorf("code.secret: required for strategy %s", c.Strategy))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("code.strategy: unsupported value %q, specify hash, random, counter or hmac", c.Strategy))
+	}
+	if c.Length < minCodeLength || c.Length > maxCodeLength {
+		errs = append(errs, fmt.Errorf("code.length: %d is not in range %d-%d", c.Length, minCodeLength, maxCodeLength))
+	}
+	chars := alphabet.Resolve(c.Alphabet)
+	if chars != "" {
+		if err := alphabet.Validate(chars); err != nil {
+			errs = append(errs, fmt.Errorf("code.alphabet: %w", err))
+		}
+	}
+	if c.CaseInsensitive {
+		if chars == "" {
+			errs = append(errs, errors.New("code.case_insensitive: requires code.alphabet with letters of a single case"))
+		} else if _, ok := alphabet.Fold(chars); !ok {
+			errs = append(errs, fmt.Errorf("code.case_insensitive: alphabet %q mixes upper and lower case letters", c.Alphabet))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Проверяет параметры подключения к PostgreSQL
+func (d DataBase) Validate() error {
+	var errs []error
+	if d.Host == "" {
+		errs = append(errs, errors.New("database.host: must not be empty"))
+	}
+	if !validPort(d.Port) {
+		errs = append(errs, fmt.Errorf("database.port: %q is not a port in range 1-65535", d.Port))
+	}
+	if d.DBName == "" {
+		errs = append(errs, errors.New("database.db_name: must not be empty"))
+	}
+	// Ошибка разбора не содержит пароль: pgconn заменяет его на xxxxxx
+	if _, err := pgconn.ParseConfig(d.DSN()); err != nil {
+		errs = append(errs, fmt.Errorf("database: invalid connection settings: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+func validBaseURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" &&
+		u.User == nil && u.RawQuery == "" && u.Fragment == "" && !u.ForceQuery
+}
+
+func validPort(port string) bool {
+	n, err := strconv.Atoi(port)
+	return err == nil && n >= 1 && n <= 65535
+}
@@ -15,9 +15,739 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/admin/keys": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Администрирование"
+                ],
+                "summary": "Список ключей доступа",
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Ключи доступа",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.APIKey"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Неверный токен администратора",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Создаёт API-ключ. Значение ключа возвращается только в этом ответе.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Администрирование"
+                ],
+                "summary": "Создать ключ доступа",
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "parameters": [
+                    {
+                        "description": "Параметры ключа",
+                        "name": "key",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.APIKeyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Созданный ключ",
+                        "schema": {
+                            "$ref": "#/definitions/model.NewAPIKey"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный ввод",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Неверный токен администратора",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/keys/{id}": {
+            "delete": {
+                "tags": [
+                    "Администрирование"
+                ],
+                "summary": "Отозвать ключ доступа",
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Идентификатор ключа",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Ключ отозван"
+                    },
+                    "401": {
+                        "description": "Неверный токен администратора",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Ключ не найден",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
         "/expand": {
             "get": {
-                "description": "Преобразует короткую ссылку в исходную длинную ссылку.",
+                "description": "Преобразует короткую ссылку в исходную длинную ссылку.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Расширение URL"
+                ],
+                "summary": "Расширить короткую ссылку до её оригинальной формы",
+                "parameters": [
+                    {
+                        "description": "Короткая ссылка",
+                        "name": "shortUrl",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.ShortURL"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Расширенная длинная ссылка",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный ввод",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Ссылка не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "410": {
+                        "description": "Срок действия ссылки истёк",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Запрос отменён",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "Истекло время ожидания хранилища",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/healthz": {
+            "get": {
+                "description": "Отвечает 200, пока процесс сервиса запущен. Зависимости не проверяются.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Мониторинг"
+                ],
+                "summary": "Проверка работоспособности",
+                "responses": {
+                    "200": {
+                        "description": "Процесс работает",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/links/{code}": {
+            "get": {
+                "description": "Возвращает длинную ссылку, срок действия, владельца и время создания и изменения, в том числе для истёкшей ссылки.\nПри включённой аутентификации доступны только ссылки, созданные тем же API-ключом.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Управление ссылками"
+                ],
+                "summary": "Сведения о короткой ссылке",
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Короткий код",
+                        "name": "code",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Сведения о ссылке",
+                        "schema": {
+                            "$ref": "#/definitions/model.LinkInfo"
+                        }
+                    },
+                    "401": {
+                        "description": "Неверный API-ключ",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Ссылка принадлежит другому ключу",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Ссылка не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Запрос отменён",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "Истекло время ожидания хранилища",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Удаляет ссылку, после чего код может быть занят заново.\nПри включённой аутентификации доступны только ссылки, созданные тем же API-ключом.",
+                "tags": [
+                    "Управление ссылками"
+                ],
+                "summary": "Удалить короткую ссылку",
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Короткий код",
+                        "name": "code",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Ссылка удалена"
+                    },
+                    "401": {
+                        "description": "Неверный API-ключ",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Ссылка принадлежит другому ключу",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Ссылка не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Запрос отменён",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "Истекло время ожидания хранилища",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Меняет длинную ссылку и/или срок действия (expires_at или ttl_seconds), короткий код сохраняется.\n\"expires_at\": null делает ссылку бессрочной.\nПри включённой аутентификации доступны только ссылки, созданные тем же API-ключом.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Управление ссылками"
+                ],
+                "summary": "Изменить короткую ссылку",
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Короткий код",
+                        "name": "code",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Изменения",
+                        "name": "patch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.LinkPatch"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Изменённая ссылка",
+                        "schema": {
+                            "$ref": "#/definitions/model.LinkInfo"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный ввод",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Неверный API-ключ",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Ссылка принадлежит другому ключу",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Ссылка не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Длинная ссылка уже сохранена под другим кодом",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Недопустимая длинная ссылка или слишком долгое время жизни",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ValidationErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Запрос отменён",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "Истекло время ожидания хранилища",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/links/{code}/qr": {
+            "get": {
+                "description": "Возвращает QR-код с полной короткой ссылкой в формате PNG или SVG. Адрес собирается из PUBLIC_BASE_URL,\nа если он не задан - из схемы и хоста запроса. Переход при генерации кода в статистику не записывается.",
+                "produces": [
+                    "image/png",
+                    "image/svg+xml"
+                ],
+                "tags": [
+                    "Расширение URL"
+                ],
+                "summary": "QR-код короткой ссылки",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Короткий код",
+                        "name": "code",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "png",
+                            "svg"
+                        ],
+                        "type": "string",
+                        "default": "png",
+                        "description": "Формат изображения",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 2048,
+                        "minimum": 64,
+                        "type": "integer",
+                        "default": 256,
+                        "description": "Сторона изображения в пикселях",
+                        "name": "size",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 16,
+                        "minimum": 0,
+                        "type": "integer",
+                        "default": 4,
+                        "description": "Ширина светлого поля в модулях",
+                        "name": "margin",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "L",
+                            "M",
+                            "Q",
+                            "H"
+                        ],
+                        "type": "string",
+                        "default": "M",
+                        "description": "Уровень коррекции ошибок",
+                        "name": "level",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "000000",
+                        "description": "Цвет модулей, RRGGBB или RGB",
+                        "name": "fg",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "default": "ffffff",
+                        "description": "Цвет фона, RRGGBB или RGB",
+                        "name": "bg",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Изображение QR-кода",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный ввод",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Ссылка не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "410": {
+                        "description": "Срок действия ссылки истёк",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Запрос отменён",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "Истекло время ожидания хранилища",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/links/{code}/stats": {
+            "get": {
+                "description": "Возвращает общее количество переходов и разбивку по дням (UTC) за последние days дней.\nПри включённой аутентификации доступна только статистика ссылок, созданных тем же API-ключом.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Статистика"
+                ],
+                "summary": "Статистика переходов по короткой ссылке",
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Короткий код",
+                        "name": "code",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "maximum": 365,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 30,
+                        "description": "Количество дней в разбивке",
+                        "name": "days",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Статистика переходов",
+                        "schema": {
+                            "$ref": "#/definitions/model.LinkStats"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный ввод",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Неверный API-ключ",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Ссылка принадлежит другому ключу",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Ссылка не найдена",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Запрос отменён",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "Истекло время ожидания хранилища",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/metrics": {
+            "get": {
+                "description": "Возвращает счётчики и гистограммы запросов, сервиса и хранилища в текстовом формате Prometheus.",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Мониторинг"
+                ],
+                "summary": "Метрики сервиса",
+                "responses": {
+                    "200": {
+                        "description": "Метрики в текстовом формате Prometheus",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/readyz": {
+            "get": {
+                "description": "Проверяет доступность хранилища и версию миграций. Возвращает 503, если какая-либо зависимость недоступна или сервис завершает работу.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Мониторинг"
+                ],
+                "summary": "Проверка готовности",
+                "responses": {
+                    "200": {
+                        "description": "Сервис готов принимать запросы",
+                        "schema": {
+                            "$ref": "#/definitions/model.Readiness"
+                        }
+                    },
+                    "503": {
+                        "description": "Сервис не готов",
+                        "schema": {
+                            "$ref": "#/definitions/model.Readiness"
+                        }
+                    }
+                }
+            }
+        },
+        "/shorten": {
+            "post": {
+                "description": "Преобразует длинную ссылку в компактную форму. Если указан alias, он используется в качестве короткого кода.\nСрок действия ссылки задаётся полем expires_at или ttl_seconds.",
                 "consumes": [
                     "application/json"
                 ],
@@ -25,23 +755,28 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Расширение URL"
+                    "Сокращение URL"
+                ],
+                "summary": "Сократить длинную ссылку",
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
                 ],
-                "summary": "Расширить короткую ссылку до её оригинальной формы",
                 "parameters": [
                     {
-                        "description": "Короткая ссылка",
-                        "name": "shortUrl",
+                        "description": "Длинная ссылка",
+                        "name": "longUrl",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/model.ShortURL"
+                            "$ref": "#/definitions/model.LongURL"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Расширенная длинная ссылка",
+                        "description": "Сокращённая ссылка",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -55,46 +790,92 @@ const docTemplate = `{
                             "$ref": "#/definitions/handler.ErrorResponse"
                         }
                     },
+                    "401": {
+                        "description": "Неверный API-ключ",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Короткий код уже занят",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Недопустимая длинная ссылка или слишком долгое время жизни",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ValidationErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Внутренняя ошибка сервера",
                         "schema": {
                             "$ref": "#/definitions/handler.ErrorResponse"
                         }
+                    },
+                    "503": {
+                        "description": "Запрос отменён",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "Истекло время ожидания хранилища",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
                     }
                 }
             }
         },
-        "/shorten": {
+        "/shorten/batch": {
             "post": {
-                "description": "Преобразует длинную ссылку в компактную форму.",
+                "description": "Принимает JSON-массив объектов или поток NDJSON (Content-Type: application/x-ndjson) в формате запроса /shorten.\nРезультаты возвращаются в порядке запроса в том же формате, ошибка одной ссылки не влияет на остальные.\nЛимит запросов списывается по одному на каждую ссылку пакета.",
                 "consumes": [
-                    "application/json"
+                    "application/json",
+                    "application/x-ndjson"
                 ],
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "application/x-ndjson"
                 ],
                 "tags": [
                     "Сокращение URL"
                 ],
-                "summary": "Сократить длинную ссылку",
+                "summary": "Сократить пакет длинных ссылок",
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
                 "parameters": [
                     {
-                        "description": "Длинная ссылка",
-                        "name": "longUrl",
+                        "description": "Длинные ссылки",
+                        "name": "longUrls",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/model.LongURL"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.LongURL"
+                            }
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Сокращённая ссылка",
+                        "description": "Результаты по каждой ссылке",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.BatchItemResult"
                             }
                         }
                     },
@@ -104,11 +885,79 @@ const docTemplate = `{
                             "$ref": "#/definitions/handler.ErrorResponse"
                         }
                     },
+                    "401": {
+                        "description": "Неверный API-ключ",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "Превышен максимальный размер пакета",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/{code}": {
+            "get": {
+                "description": "Перенаправляет на исходную длинную ссылку, код ответа задаётся в конфигурации.",
+                "produces": [
+                    "text/html"
+                ],
+                "tags": [
+                    "Расширение URL"
+                ],
+                "summary": "Перейти по короткой ссылке",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Короткий код",
+                        "name": "code",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "302": {
+                        "description": "Перенаправление на длинную ссылку"
+                    },
+                    "404": {
+                        "description": "Ссылка не найдена"
+                    },
+                    "410": {
+                        "description": "Срок действия ссылки истёк"
+                    },
+                    "429": {
+                        "description": "Превышен лимит запросов",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Внутренняя ошибка сервера",
                         "schema": {
                             "$ref": "#/definitions/handler.ErrorResponse"
                         }
+                    },
+                    "503": {
+                        "description": "Запрос отменён",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "Истекло время ожидания хранилища",
+                        "schema": {
+                            "$ref": "#/definitions/handler.ErrorResponse"
+                        }
                     }
                 }
             }
@@ -124,6 +973,167 @@ const docTemplate = `{
                 }
             }
         },
+        "handler.ValidationErrorResponse": {
+            "description": "Формат ответа об ошибке проверки длинной ссылки или времени жизни",
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "field": {
+                    "description": "Поле запроса с ошибкой",
+                    "type": "string"
+                },
+                "reason": {
+                    "description": "Причина: malformed, missing_scheme, scheme_not_allowed, missing_host, invalid_host, too_long, ttl_too_long",
+                    "type": "string"
+                }
+            }
+        },
+        "model.APIKey": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "revoked_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.APIKeyRequest": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.BatchItemResult": {
+            "description": "Результат сокращения одной ссылки пакетного запроса",
+            "type": "object",
+            "properties": {
+                "short_url": {
+                    "description": "Сокращённая ссылка, если сокращение удалось",
+                    "type": "string"
+                },
+                "status": {
+                    "description": "HTTP-код результата, как для одиночного запроса",
+                    "type": "integer"
+                },
+                "error": {
+                    "description": "Описание ошибки",
+                    "type": "string"
+                },
+                "reason": {
+                    "description": "Причина отклонения длинной ссылки или времени жизни для статуса 422",
+                    "type": "string"
+                }
+            }
+        },
+        "model.DailyClicks": {
+            "type": "object",
+            "properties": {
+                "date": {
+                    "description": "День в формате YYYY-MM-DD (UTC)",
+                    "type": "string"
+                },
+                "clicks": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.DependencyStatus": {
+            "description": "Результат проверки одной зависимости",
+            "type": "object",
+            "properties": {
+                "status": {
+                    "description": "ok или unavailable",
+                    "type": "string"
+                },
+                "detail": {
+                    "description": "Дополнительные сведения, например версия миграций",
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "duration_ms": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.LinkInfo": {
+            "description": "Сведения о короткой ссылке",
+            "type": "object",
+            "properties": {
+                "short_url": {
+                    "type": "string"
+                },
+                "long_url": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "description": "Момент истечения ссылки, отсутствует у бессрочных",
+                    "type": "string"
+                },
+                "owner": {
+                    "description": "Идентификатор API-ключа, создавшего ссылку",
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.LinkPatch": {
+            "description": "Изменение короткой ссылки, отсутствующие поля не меняются",
+            "type": "object",
+            "properties": {
+                "long_url": {
+                    "description": "Новая длинная ссылка",
+                    "type": "string"
+                },
+                "expires_at": {
+                    "description": "Новый момент истечения в формате RFC 3339, null делает ссылку бессрочной",
+                    "type": "string"
+                },
+                "ttl_seconds": {
+                    "description": "Новое время жизни в секундах от момента запроса, не больше 10 лет",
+                    "type": "integer"
+                }
+            }
+        },
+        "model.LinkStats": {
+            "description": "Статистика переходов по короткой ссылке",
+            "type": "object",
+            "properties": {
+                "short_url": {
+                    "type": "string"
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "daily": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.DailyClicks"
+                    }
+                }
+            }
+        },
         "model.LongURL": {
             "type": "object",
             "required": [
@@ -132,6 +1142,56 @@ const docTemplate = `{
             "properties": {
                 "long_url": {
                     "type": "string"
+                },
+                "alias": {
+                    "description": "Желаемый короткий код (необязательно)",
+                    "type": "string"
+                },
+                "expires_at": {
+                    "description": "Момент истечения ссылки в формате RFC 3339 (необязательно)",
+                    "type": "string"
+                },
+                "ttl_seconds": {
+                    "description": "Время жизни ссылки в секундах (необязательно, не больше 10 лет)",
+                    "type": "integer"
+                }
+            }
+        },
+        "model.NewAPIKey": {
+            "description": "Созданный ключ доступа, значение key показывается только один раз",
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "key": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.Readiness": {
+            "description": "Готовность сервиса принимать запросы с результатами проверки каждой зависимости",
+            "type": "object",
+            "properties": {
+                "status": {
+                    "description": "ok или unavailable",
+                    "type": "string"
+                },
+                "shutting_down": {
+                    "description": "Сервис завершает работу и не принимает новые запросы",
+                    "type": "boolean"
+                },
+                "checks": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/model.DependencyStatus"
+                    }
                 }
             }
         },
@@ -146,6 +1206,18 @@ const docTemplate = `{
                 }
             }
         }
+    },
+    "securityDefinitions": {
+        "AdminToken": {
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
+        },
+        "ApiKeyAuth": {
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
+        }
     }
 }`
 
@@ -0,0 +1,166 @@
+package handler
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+
+	"url-shortener/internal/model"
+	"url-shortener/internal/service"
+	"url-shortener/pkg/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	keysUrl = "/admin/keys"
+	keyUrl  = "/admin/keys/:id"
+
+	// Ключ контекста gin, под которым сохраняется идентификатор API-ключа владельца запроса
+	OwnerKey = "owner"
+)
+
+type authService interface {
+	Authenticate(context.Context, string) (model.APIKey, error)
+	CreateKey(context.Context, string) (model.NewAPIKey, error)
+	ListKeys(context.Context) ([]model.APIKey, error)
+	RevokeKey(context.Context, string) error
+}
+
+type AuthHandler struct {
+	authService
+	adminToken string
+	logger     Logger
+}
+
+// adminToken - токен для управления ключами, при пустом значении эндпоинты /admin/keys не регистрируются
+func NewAuthHandler(authService authService, adminToken string, logger Logger) *AuthHandler {
+	return &AuthHandler{authService: authService, adminToken: adminToken, logger: logger}
+}
+
+func (h *AuthHandler) Register(router *gin.Engine) {
+	if h.adminToken == "" {
+		return
+	}
+	admin := router.Group("", h.RequireAdmin)
+	admin.POST(keysUrl, h.CreateKey)
+	admin.GET(keysUrl, h.ListKeys)
+	admin.DELETE(keyUrl, h.RevokeKey)
+}
+
+// Middleware проверяет заголовок Authorization: Bearer <ключ> и сохраняет владельца ключа в контексте
+func (h *AuthHandler) Authenticate(ctx *gin.Context) {
+	rawKey, ok := bearerToken(ctx)
+	if !ok {
+		ctx.Header("WWW-Authenticate", "Bearer")
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, ErrorResponse{Message: "missing api key"})
+		return
+	}
+	key, err := h.authService.Authenticate(ctx.Request.Context(), rawKey)
+	if errors.Is(err, service.ErrUnauthorized) {
+		ctx.Header("WWW-Authenticate", "Bearer")
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, ErrorResponse{Message: err.Error()})
+		return
+	}
+	if err != nil {
+		h.logger.Errorf("Ошибка при проверке ключа: %v", err)
+		ctx.AbortWithStatusJSON(http.StatusInternalServerError, ErrorResponse{Message: err.Error()})
+		return
+	}
+	ctx.Set(OwnerKey, key.ID)
+	ctx.Next()
+}
+
+func (h *AuthHandler) RequireAdmin(ctx *gin.Context) {
+	token, ok := bearerToken(ctx)
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(h.adminToken)) != 1 {
+		ctx.Header("WWW-Authenticate", "Bearer")
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, ErrorResponse{Message: "invalid admin token"})
+		return
+	}
+	ctx.Next()
+}
+
+// @Summary Создать ключ доступа
+// @Description Создаёт API-ключ. Значение ключа возвращается только в этом ответе.
+// @Tags Администрирование
+// @Accept json
+// @Produce json
+// @Security AdminToken
+// @Param key body model.APIKeyRequest true "Параметры ключа"
+// @Success 201 {object} model.NewAPIKey "Созданный ключ"
+// @Failure 400 {object} ErrorResponse "Неверный ввод"
+// @Failure 401 {object} ErrorResponse "Неверный токен администратора"
+// @Failure 500 {object} ErrorResponse "Внутренняя ошибка сервера"
+// @Router /admin/keys [post]
+func (h *AuthHandler) CreateKey(ctx *gin.Context) {
+	var req model.APIKeyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Message: err.Error()})
+		ctx.Abort()
+		return
+	}
+	key, err := h.authService.CreateKey(ctx.Request.Context(), req.Name)
+	if err != nil {
+		h.logger.Errorf("Ошибка при создании ключа: %v", err)
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Message: err.Error()})
+		ctx.Abort()
+		return
+	}
+	ctx.JSON(http.StatusCreated, key)
+}
+
+// @Summary Список ключей доступа
+// @Tags Администрирование
+// @Produce json
+// @Security AdminToken
+// @Success 200 {array} model.APIKey "Ключи доступа"
+// @Failure 401 {object} ErrorResponse "Неверный токен администратора"
+// @Failure 500 {object} ErrorResponse "Внутренняя ошибка сервера"
+// @Router /admin/keys [get]
+func (h *AuthHandler) ListKeys(ctx *gin.Context) {
+	keys, err := h.authService.ListKeys(ctx.Request.Context())
+	if err != nil {
+		h.logger.Errorf("Ошибка при получении ключей: %v", err)
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Message: err.Error()})
+		ctx.Abort()
+		return
+	}
+	ctx.JSON(http.StatusOK, keys)
+}
+
+// @Summary Отозвать ключ доступа
+// @Tags Администрирование
+// @Security AdminToken
+// @Param id path string true "Идентификатор ключа"
+// @Success 204 "Ключ отозван"
+// @Failure 401 {object} ErrorResponse "Неверный токен администратора"
+// @Failure 404 {object} ErrorResponse "Ключ не найден"
+// @Failure 500 {object} ErrorResponse "Внутренняя ошибка сервера"
+// @Router /admin/keys/{id} [delete]
+func (h *AuthHandler) RevokeKey(ctx *gin.Context) {
+	err := h.authService.RevokeKey(ctx.Request.Context(), ctx.Param("id"))
+	if errors.Is(err, storage.ErrKeyNotFound) {
+		ctx.JSON(http.StatusNotFound, ErrorResponse{Message: err.Error()})
+		ctx.Abort()
+		return
+	}
+	if err != nil {
+		h.logger.Errorf("Ошибка при отзыве ключа: %v", err)
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Message: err.Error()})
+		ctx.Abort()
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+func bearerToken(ctx *gin.Context) (string, bool) {
+	header := ctx.GetHeader("Authorization")
+	const prefix = "Bearer "
+	if len(header) <= len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return "", false
+	}
+	return strings.TrimSpace(header[len(prefix):]), true
+}
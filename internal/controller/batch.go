@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"time"
+
+	"url-shortener/internal/model"
+	"url-shortener/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+const batchUrl = "/shorten/batch"
+
+const ndjsonContentType = "application/x-ndjson"
+
+const (
+	defaultBatchMaxSize = 1000
+	defaultBatchWorkers = 8
+)
+
+var errBatchTooLarge = errors.New("batch is too large")
+
+// @Summary Сократить пакет длинных ссылок
+// @Description Принимает JSON-массив объектов или поток NDJSON (Content-Type: application/x-ndjson) в формате запроса /shorten.
+// @Description Результаты возвращаются в порядке запроса в том же формате, ошибка одной ссылки не влияет на остальные.
+// @Description Лимит запросов списывается по одному на каждую ссылку пакета.
+// @Tags Сокращение URL
+// @Accept json,x-ndjson
+// @Produce json,x-ndjson
+// @Security ApiKeyAuth
+// @Param longUrls body []model.LongURL true "Длинные ссылки"
+// @Success 200 {array} model.BatchItemResult "Результаты по каждой ссылке"
+// @Failure 400 {object} ErrorResponse "Неверный ввод"
+// @Failure 401 {object} ErrorResponse "Неверный API-ключ"
+// @Failure 413 {object} ErrorResponse "Превышен максимальный размер пакета"
+// @Failure 429 {object} ErrorResponse "Превышен лимит запросов"
+// @Router /shorten/batch [post]
+func (h *Handler) ShorteningBatch(ctx *gin.Context) {
+	mediaType, _, _ := mime.ParseMediaType(ctx.GetHeader("Content-Type"))
+	ndjson := mediaType == ndjsonContentType
+
+	var requests []model.LongURL
+	var err error
+	if ndjson {
+		requests, err = h.decodeNDJSON(ctx.Request.Body)
+	} else {
+		requests, err = h.decodeArray(ctx.Request.Body)
+	}
+	if errors.Is(err, errBatchTooLarge) {
+		ctx.JSON(http.StatusRequestEntityTooLarge, ErrorResponse{Message: err.Error()})
+		ctx.Abort()
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Message: err.Error()})
+		ctx.Abort()
+		return
+	}
+	if !chargeRateLimit(ctx, len(requests)) {
+		return
+	}
+
+	results := h.shortenBatch(ctx.Request.Context(), requests, ctx.GetString(OwnerKey))
+	if !ndjson {
+		ctx.JSON(http.StatusOK, results)
+		return
+	}
+	ctx.Header("Content-Type", ndjsonContentType)
+	ctx.Status(http.StatusOK)
+	encoder := json.NewEncoder(ctx.Writer)
+	for _, res := range results {
+		if err := encoder.Encode(res); err != nil {
+			h.logger.Errorf("Ошибка записи ответа: %v", err)
+			return
+		}
+	}
+}
+
+// Проверяет срок действия каждой ссылки и передаёт корректные в сервис одним пакетом
+func (h *Handler) shortenBatch(ctx context.Context, requests []model.LongURL, owner string) []model.BatchItemResult {
+	results := make([]model.BatchItemResult, len(requests))
+	var links []model.Link
+	var indexes []int
+	now := time.Now()
+	for i, req := range requests {
+		if req.URL == "" {
+			results[i] = model.BatchItemResult{Status: http.StatusBadRequest, Error: "long_url is required"}
+			continue
+		}
+		expiresAt, err := service.ResolveExpiry(req.ExpiresAt, req.TTLSeconds, now)
+		if err != nil {
+			status, reason := expiryErrorStatus(err)
+			results[i] = model.BatchItemResult{Status: status, Error: err.Error(), Reason: reason}
+			continue
+		}
+		links = append(links, model.Link{ShortURL: req.Alias, LongURL: req.URL, ExpiresAt: expiresAt, Owner: owner})
+		indexes = append(indexes, i)
+	}
+	if len(links) == 0 {
+		return results
+	}
+
+	for n, res := range h.shortenerService.BatchShortening(ctx, links, h.opts.BatchWorkers) {
+		i := indexes[n]
+		if res.Err != nil {
+			status, reason := h.shortenErrorStatus(res.Err)
+			results[i] = model.BatchItemResult{Status: status, Error: res.Err.Error(), Reason: reason}
+			continue
+		}
+		results[i] = model.BatchItemResult{ShortURL: res.ShortURL, Status: http.StatusOK}
+	}
+	return results
+}
+
+func (h *Handler) decodeArray(body io.Reader) ([]model.LongURL, error) {
+	decoder := json.NewDecoder(body)
+	if tok, err := decoder.Token(); err != nil {
+		return nil, err
+	} else if tok != json.Delim('[') {
+		return nil, errors.New("request body must be a JSON array")
+	}
+	var requests []model.LongURL
+	for decoder.More() {
+		if len(requests) == h.opts.BatchMaxSize {
+			return nil, fmt.Errorf("%w: at most %d links allowed", errBatchTooLarge, h.opts.BatchMaxSize)
+		}
+		var req model.LongURL
+		if err := decoder.Decode(&req); err != nil {
+			return nil, fmt.Errorf("item %d: %w", len(requests), err)
+		}
+		requests = append(requests, req)
+	}
+	if _, err := decoder.Token(); err != nil {
+		return nil, err
+	}
+	return requests, nil
+}
+
+func (h *Handler) decodeNDJSON(body io.Reader) ([]model.LongURL, error) {
+	decoder := json.NewDecoder(body)
+	var requests []model.LongURL
+	for {
+		var req model.LongURL
+		err := decoder.Decode(&req)
+		if err == io.EOF {
+			return requests, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", len(requests)+1, err)
+		}
+		if len(requests) == h.opts.BatchMaxSize {
+			return nil, fmt.Errorf("%w: at most %d links allowed", errBatchTooLarge, h.opts.BatchMaxSize)
+		}
+		requests = append(requests, req)
+	}
+}
@@ -1,9 +1,15 @@
 package handler
 
 import (
+	"context"
+	"errors"
 	"net/http"
+	"strconv"
+	"time"
 
 	"url-shortener/internal/model"
+	"url-shortener/internal/service"
+	"url-shortener/pkg/storage"
 
 	"github.com/gin-gonic/gin"
 	_ "github.com/swaggo/files"
@@ -14,18 +20,61 @@ import (
 )
 
 const (
-	extendUrl  = "/expand"
-	shortenUrl = "/shorten"
+	extendUrl   = "/expand"
+	shortenUrl  = "/shorten"
+	redirectUrl = "/:code"
+	statsUrl    = "/links/:code/stats"
 )
 
+const defaultStatsDays = 30
+
+const notFoundPage = `<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>404 Not Found</title></head>
+<body><h1>404 Not Found</h1><p>Короткая ссылка не найдена.</p></body>
+</html>
+`
+
+const gonePage = `<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>410 Gone</title></head>
+<body><h1>410 Gone</h1><p>Срок действия короткой ссылки истёк.</p></body>
+</html>
+`
+
+// Коды ответа, допустимые для перехода по короткой ссылке
+var redirectStatuses = map[int]bool{
+	http.StatusMovedPermanently:  true,
+	http.StatusFound:             true,
+	http.StatusTemporaryRedirect: true,
+	http.StatusPermanentRedirect: true,
+}
+
 // @Description Формат ответа об ошибке
 type ErrorResponse struct {
 	Message string `json:"message"`
 }
 
+// @Description Формат ответа об ошибке проверки длинной ссылки или времени жизни
+type ValidationErrorResponse struct {
+	Message string `json:"message"`
+	Field   string `json:"field"`  // Поле запроса с ошибкой
+	Reason  string `json:"reason"` // Причина: malformed, missing_scheme, scheme_not_allowed, missing_host, invalid_host, too_long, ttl_too_long
+}
+
 type shortenerService interface {
-	Shortening(string) (string, error)
-	Expansion(string) (string, error)
+	Shortening(context.Context, model.Link) (string, error)
+	CustomShortening(context.Context, model.Link) (string, error)
+	BatchShortening(context.Context, []model.Link, int) []service.BatchResult
+	Expansion(context.Context, string) (string, error)
+	GetLink(context.Context, string, string) (model.Link, error)
+	UpdateLink(context.Context, string, string, string, *time.Time) (model.Link, error)
+	DeleteLink(context.Context, string, string) error
+}
+
+type analyticsService interface {
+	RecordClick(model.Click)
+	Stats(context.Context, string, string, int) (model.LinkStats, error)
 }
 
 type Logger interface {
@@ -38,18 +87,60 @@ type Logger interface {
 	Fatalf(format string, args ...interface{})
 }
 
+// Параметры обработчика, нулевые значения заменяются значениями по умолчанию
+type Options struct {
+	RedirectStatus int    // Код ответа для перехода по короткой ссылке: 301, 302, 307 или 308 (по умолчанию 302)
+	BatchMaxSize   int    // Максимальное количество ссылок в пакетном запросе (по умолчанию 1000)
+	BatchWorkers   int    // Количество параллельных обработчиков пакета (по умолчанию 8)
+	BaseURL        string // Внешний адрес сервиса для полных коротких ссылок, пустой - схема и хост запроса
+}
+
 type Handler struct {
 	shortenerService
-	logger Logger
+	analytics analyticsService
+	logger    Logger
+	opts      Options
 }
 
-func NewHandler(shortenerService shortenerService, logger Logger) *Handler {
-	return &Handler{shortenerService: shortenerService, logger: logger}
+func NewHandler(shortenerService shortenerService, analytics analyticsService, logger Logger, opts Options) *Handler {
+	if !redirectStatuses[opts.RedirectStatus] {
+		opts.RedirectStatus = http.StatusFound
+	}
+	if opts.BatchMaxSize <= 0 {
+		opts.BatchMaxSize = defaultBatchMaxSize
+	}
+	if opts.BatchWorkers <= 0 {
+		opts.BatchWorkers = defaultBatchWorkers
+	}
+	return &Handler{shortenerService: shortenerService, analytics: analytics, logger: logger, opts: opts}
 }
 
-func (h *Handler) Register(router *gin.Engine) {
-	router.GET(extendUrl, h.Expansion)
-	router.POST(shortenUrl, h.Shortening)
+// Дополнительные middleware для групп эндпоинтов
+type Middlewares struct {
+	Auth    []gin.HandlerFunc // Эндпоинты, создающие ссылки и управляющие ими. Переход по ссылке всегда анонимный
+	Shorten []gin.HandlerFunc // Сокращение ссылок, выполняются после Auth
+	Batch   []gin.HandlerFunc // Пакетное сокращение, выполняются после Auth (RateLimiter.LimitBatch)
+	Expand  []gin.HandlerFunc // Расширение ссылок и переход по ним
+}
+
+func chain(handler gin.HandlerFunc, middlewares ...[]gin.HandlerFunc) []gin.HandlerFunc {
+	var handlers []gin.HandlerFunc
+	for _, m := range middlewares {
+		handlers = append(handlers, m...)
+	}
+	return append(handlers, handler)
+}
+
+func (h *Handler) Register(router *gin.Engine, mw Middlewares) {
+	router.GET(extendUrl, chain(h.Expansion, mw.Expand)...)
+	router.POST(shortenUrl, chain(h.Shortening, mw.Auth, mw.Shorten)...)
+	router.POST(batchUrl, chain(h.ShorteningBatch, mw.Auth, mw.Batch)...)
+	router.GET(redirectUrl, chain(h.Redirect, mw.Expand)...)
+	router.GET(statsUrl, chain(h.Stats, mw.Auth)...)
+	router.GET(qrUrl, chain(h.QRCode, mw.Expand)...)
+	router.GET(linkUrl, chain(h.GetLink, mw.Auth)...)
+	router.PATCH(linkUrl, chain(h.UpdateLink, mw.Auth)...)
+	router.DELETE(linkUrl, chain(h.DeleteLink, mw.Auth)...)
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler)) // Добавляем Swagger UI
 }
 
@@ -61,7 +152,12 @@ func (h *Handler) Register(router *gin.Engine) {
 // @Param shortUrl body model.ShortURL true "Короткая ссылка"
 // @Success 200 {object} map[string]string "Расширенная длинная ссылка"
 // @Failure 400 {object} ErrorResponse "Неверный ввод"
+// @Failure 404 {object} ErrorResponse "Ссылка не найдена"
+// @Failure 410 {object} ErrorResponse "Срок действия ссылки истёк"
+// @Failure 429 {object} ErrorResponse "Превышен лимит запросов"
 // @Failure 500 {object} ErrorResponse "Внутренняя ошибка сервера"
+// @Failure 503 {object} ErrorResponse "Запрос отменён"
+// @Failure 504 {object} ErrorResponse "Истекло время ожидания хранилища"
 // @Router /expand [get]
 func (h *Handler) Expansion(ctx *gin.Context) {
 	var shortUrl model.ShortURL
@@ -72,25 +168,150 @@ func (h *Handler) Expansion(ctx *gin.Context) {
 		ctx.Abort()
 		return
 	}
-	res, err := h.shortenerService.Expansion(shortUrl.URL)
+	res, err := h.shortenerService.Expansion(ctx.Request.Context(), shortUrl.URL)
+	if errors.Is(err, storage.ErrNotFound) {
+		ctx.JSON(http.StatusNotFound, ErrorResponse{Message: err.Error()})
+		ctx.Abort()
+		return
+	}
+	if errors.Is(err, storage.ErrExpired) {
+		ctx.JSON(http.StatusGone, ErrorResponse{Message: err.Error()})
+		ctx.Abort()
+		return
+	}
 	if err != nil {
 		h.logger.Errorf("Ошибка при расширении: %v", err)
-		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Message: err.Error()})
+		ctx.JSON(errorStatus(err), ErrorResponse{Message: err.Error()})
 		ctx.Abort()
 		return
 	}
+	h.recordClick(ctx, shortUrl.URL)
 	ctx.JSON(http.StatusOK, map[string]string{"long_url": res})
 }
 
+// @Summary Перейти по короткой ссылке
+// @Description Перенаправляет на исходную длинную ссылку, код ответа задаётся в конфигурации.
+// @Tags Расширение URL
+// @Produce html
+// @Param code path string true "Короткий код"
+// @Success 302 "Перенаправление на длинную ссылку"
+// @Failure 404 "Ссылка не найдена"
+// @Failure 410 "Срок действия ссылки истёк"
+// @Failure 429 {object} ErrorResponse "Превышен лимит запросов"
+// @Failure 500 {object} ErrorResponse "Внутренняя ошибка сервера"
+// @Failure 503 {object} ErrorResponse "Запрос отменён"
+// @Failure 504 {object} ErrorResponse "Истекло время ожидания хранилища"
+// @Router /{code} [get]
+func (h *Handler) Redirect(ctx *gin.Context) {
+	res, err := h.shortenerService.Expansion(ctx.Request.Context(), ctx.Param("code"))
+	if errors.Is(err, storage.ErrNotFound) {
+		ctx.Data(http.StatusNotFound, "text/html; charset=utf-8", []byte(notFoundPage))
+		ctx.Abort()
+		return
+	}
+	if errors.Is(err, storage.ErrExpired) {
+		ctx.Data(http.StatusGone, "text/html; charset=utf-8", []byte(gonePage))
+		ctx.Abort()
+		return
+	}
+	if err != nil {
+		h.logger.Errorf("Ошибка при переходе: %v", err)
+		ctx.JSON(errorStatus(err), ErrorResponse{Message: err.Error()})
+		ctx.Abort()
+		return
+	}
+
+	location, err := redirectLocation(res)
+	if err != nil {
+		h.logger.Errorf("Некорректная ссылка для перехода %q: %v", res, err)
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Message: err.Error()})
+		ctx.Abort()
+		return
+	}
+	h.recordClick(ctx, ctx.Param("code"))
+	ctx.Redirect(h.opts.RedirectStatus, location)
+}
+
+// @Summary Статистика переходов по короткой ссылке
+// @Description Возвращает общее количество переходов и разбивку по дням (UTC) за последние days дней.
+// @Description При включённой аутентификации доступна только статистика ссылок, созданных тем же API-ключом.
+// @Tags Статистика
+// @Produce json
+// @Security ApiKeyAuth
+// @Param code path string true "Короткий код"
+// @Param days query int false "Количество дней в разбивке" default(30) minimum(1) maximum(365)
+// @Success 200 {object} model.LinkStats "Статистика переходов"
+// @Failure 400 {object} ErrorResponse "Неверный ввод"
+// @Failure 401 {object} ErrorResponse "Неверный API-ключ"
+// @Failure 403 {object} ErrorResponse "Ссылка принадлежит другому ключу"
+// @Failure 404 {object} ErrorResponse "Ссылка не найдена"
+// @Failure 500 {object} ErrorResponse "Внутренняя ошибка сервера"
+// @Failure 503 {object} ErrorResponse "Запрос отменён"
+// @Failure 504 {object} ErrorResponse "Истекло время ожидания хранилища"
+// @Router /links/{code}/stats [get]
+func (h *Handler) Stats(ctx *gin.Context) {
+	days := defaultStatsDays
+	if raw := ctx.Query("days"); raw != "" {
+		var err error
+		if days, err = strconv.Atoi(raw); err != nil {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{Message: err.Error()})
+			ctx.Abort()
+			return
+		}
+	}
+
+	stats, err := h.analytics.Stats(ctx.Request.Context(), ctx.Param("code"), ctx.GetString(OwnerKey), days)
+	if errors.Is(err, service.ErrInvalidPeriod) {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Message: err.Error()})
+		ctx.Abort()
+		return
+	}
+	if errors.Is(err, storage.ErrNotFound) {
+		ctx.JSON(http.StatusNotFound, ErrorResponse{Message: err.Error()})
+		ctx.Abort()
+		return
+	}
+	if errors.Is(err, service.ErrForbidden) {
+		ctx.JSON(http.StatusForbidden, ErrorResponse{Message: err.Error()})
+		ctx.Abort()
+		return
+	}
+	if err != nil {
+		h.logger.Errorf("Ошибка при получении статистики: %v", err)
+		ctx.JSON(errorStatus(err), ErrorResponse{Message: err.Error()})
+		ctx.Abort()
+		return
+	}
+	ctx.JSON(http.StatusOK, stats)
+}
+
+func (h *Handler) recordClick(ctx *gin.Context, shortUrl string) {
+	h.analytics.RecordClick(model.Click{
+		ShortURL:  shortUrl,
+		Timestamp: time.Now().UTC(),
+		Referrer:  ctx.Request.Referer(),
+		UserAgent: ctx.Request.UserAgent(),
+		ClientIP:  ctx.ClientIP(),
+	})
+}
+
 // @Summary Сократить длинную ссылку
-// @Description Преобразует длинную ссылку в компактную форму.
+// @Description Преобразует длинную ссылку в компактную форму. Если указан alias, он используется в качестве короткого кода.
+// @Description Срок действия ссылки задаётся полем expires_at или ttl_seconds.
 // @Tags Сокращение URL
 // @Accept json
 // @Produce json
+// @Security ApiKeyAuth
 // @Param longUrl body model.LongURL true "Длинная ссылка"
 // @Success 200 {object} map[string]string "Сокращённая ссылка"
 // @Failure 400 {object} ErrorResponse "Неверный ввод"
+// @Failure 401 {object} ErrorResponse "Неверный API-ключ"
+// @Failure 409 {object} ErrorResponse "Короткий код уже занят"
+// @Failure 422 {object} ValidationErrorResponse "Недопустимая длинная ссылка или слишком долгое время жизни"
+// @Failure 429 {object} ErrorResponse "Превышен лимит запросов"
 // @Failure 500 {object} ErrorResponse "Внутренняя ошибка сервера"
+// @Failure 503 {object} ErrorResponse "Запрос отменён"
+// @Failure 504 {object} ErrorResponse "Истекло время ожидания хранилища"
 // @Router /shorten [post]
 func (h *Handler) Shortening(ctx *gin.Context) {
 	var longUrl model.LongURL
@@ -102,13 +323,82 @@ func (h *Handler) Shortening(ctx *gin.Context) {
 		return
 	}
 
-	res, err := h.shortenerService.Shortening(longUrl.URL)
+	expiresAt, err := service.ResolveExpiry(longUrl.ExpiresAt, longUrl.TTLSeconds, time.Now())
 	if err != nil {
-		h.logger.Errorf("Ошибка при сокращении: %v", err)
-		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Message: err.Error()})
+		expiryError(ctx, err)
+		return
+	}
+
+	link := model.Link{
+		ShortURL:  longUrl.Alias,
+		LongURL:   longUrl.URL,
+		ExpiresAt: expiresAt,
+		Owner:     ctx.GetString(OwnerKey),
+	}
+	var res string
+	if link.ShortURL != "" {
+		res, err = h.shortenerService.CustomShortening(ctx.Request.Context(), link)
+	} else {
+		res, err = h.shortenerService.Shortening(ctx.Request.Context(), link)
+	}
+	if err != nil {
+		status, reason := h.shortenErrorStatus(err)
+		if status == http.StatusUnprocessableEntity {
+			ctx.JSON(status, ValidationErrorResponse{Message: err.Error(), Field: "long_url", Reason: reason})
+		} else {
+			ctx.JSON(status, ErrorResponse{Message: err.Error()})
+		}
 		ctx.Abort()
 		return
 	}
 
 	ctx.JSON(http.StatusOK, map[string]string{"short_url": res})
 }
+
+// Отвечает на ошибку срока действия кодом и телом из expiryErrorStatus
+func expiryError(ctx *gin.Context, err error) {
+	if status, reason := expiryErrorStatus(err); status == http.StatusUnprocessableEntity {
+		ctx.JSON(status, ValidationErrorResponse{Message: err.Error(), Field: "ttl_seconds", Reason: reason})
+	} else {
+		ctx.JSON(status, ErrorResponse{Message: err.Error()})
+	}
+	ctx.Abort()
+}
+
+// Код ответа для ошибки срока действия и причина отклонения для ответа 422:
+// слишком долгое время жизни - 422 с полем ttl_seconds, остальные ошибки - 400
+func expiryErrorStatus(err error) (int, string) {
+	if errors.Is(err, service.ErrTTLTooLong) {
+		return http.StatusUnprocessableEntity, service.ReasonTTLTooLong
+	}
+	return http.StatusBadRequest, ""
+}
+
+// Определяет код ответа для ошибки сокращения ссылки и причину отклонения для ответа 422
+func (h *Handler) shortenErrorStatus(err error) (int, string) {
+	var validationErr *service.URLValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		return http.StatusUnprocessableEntity, validationErr.Reason
+	case errors.Is(err, service.ErrInvalidAlias), errors.Is(err, service.ErrInvalidExpiry):
+		return http.StatusBadRequest, ""
+	case errors.Is(err, service.ErrAliasTaken):
+		return http.StatusConflict, ""
+	default:
+		h.logger.Errorf("Ошибка при сокращении: %v", err)
+		return errorStatus(err), ""
+	}
+}
+
+// Код ответа для ошибки хранилища: превышение срока операции - 504,
+// отмена запроса - 503, остальные ошибки - 500
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, context.Canceled):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"url-shortener/internal/model"
+	"url-shortener/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	healthzUrl = "/healthz"
+	readyzUrl  = "/readyz"
+)
+
+type healthService interface {
+	Ready(context.Context) model.Readiness
+}
+
+type HealthHandler struct {
+	healthService
+}
+
+func NewHealthHandler(healthService healthService) *HealthHandler {
+	return &HealthHandler{healthService: healthService}
+}
+
+func (h *HealthHandler) Register(router *gin.Engine) {
+	router.GET(healthzUrl, h.Healthz)
+	router.GET(readyzUrl, h.Readyz)
+}
+
+// @Summary Проверка работоспособности
+// @Description Отвечает 200, пока процесс сервиса запущен. Зависимости не проверяются.
+// @Tags Мониторинг
+// @Produce json
+// @Success 200 {object} map[string]string "Процесс работает"
+// @Router /healthz [get]
+func (h *HealthHandler) Healthz(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, map[string]string{"status": service.HealthOK})
+}
+
+// @Summary Проверка готовности
+// @Description Проверяет доступность хранилища и версию миграций. Возвращает 503, если какая-либо зависимость недоступна или сервис завершает работу.
+// @Tags Мониторинг
+// @Produce json
+// @Success 200 {object} model.Readiness "Сервис готов принимать запросы"
+// @Failure 503 {object} model.Readiness "Сервис не готов"
+// @Router /readyz [get]
+func (h *HealthHandler) Readyz(ctx *gin.Context) {
+	res := h.healthService.Ready(ctx.Request.Context())
+	status := http.StatusOK
+	if res.Status != service.HealthOK {
+		status = http.StatusServiceUnavailable
+	}
+	ctx.JSON(status, res)
+}
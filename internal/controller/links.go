@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"url-shortener/internal/model"
+	"url-shortener/internal/service"
+	"url-shortener/pkg/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+const linkUrl = "/links/:code"
+
+// @Summary Сведения о короткой ссылке
+// @Description Возвращает длинную ссылку, срок действия, владельца и время создания и изменения, в том числе для истёкшей ссылки.
+// @Description При включённой аутентификации доступны только ссылки, созданные тем же API-ключом.
+// @Tags Управление ссылками
+// @Produce json
+// @Security ApiKeyAuth
+// @Param code path string true "Короткий код"
+// @Success 200 {object} model.LinkInfo "Сведения о ссылке"
+// @Failure 401 {object} ErrorResponse "Неверный API-ключ"
+// @Failure 403 {object} ErrorResponse "Ссылка принадлежит другому ключу"
+// @Failure 404 {object} ErrorResponse "Ссылка не найдена"
+// @Failure 500 {object} ErrorResponse "Внутренняя ошибка сервера"
+// @Failure 503 {object} ErrorResponse "Запрос отменён"
+// @Failure 504 {object} ErrorResponse "Истекло время ожидания хранилища"
+// @Router /links/{code} [get]
+func (h *Handler) GetLink(ctx *gin.Context) {
+	link, err := h.shortenerService.GetLink(ctx.Request.Context(), ctx.Param("code"), ctx.GetString(OwnerKey))
+	if err != nil {
+		h.linkError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, linkInfo(link))
+}
+
+// @Summary Изменить короткую ссылку
+// @Description Меняет длинную ссылку и/или срок действия (expires_at или ttl_seconds), короткий код сохраняется.
+// @Description "expires_at": null делает ссылку бессрочной.
+// @Description При включённой аутентификации доступны только ссылки, созданные тем же API-ключом.
+// @Tags Управление ссылками
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param code path string true "Короткий код"
+// @Param patch body model.LinkPatch true "Изменения"
+// @Success 200 {object} model.LinkInfo "Изменённая ссылка"
+// @Failure 400 {object} ErrorResponse "Неверный ввод"
+// @Failure 401 {object} ErrorResponse "Неверный API-ключ"
+// @Failure 403 {object} ErrorResponse "Ссылка принадлежит другому ключу"
+// @Failure 404 {object} ErrorResponse "Ссылка не найдена"
+// @Failure 409 {object} ErrorResponse "Длинная ссылка уже сохранена под другим кодом"
+// @Failure 422 {object} ValidationErrorResponse "Недопустимая длинная ссылка или слишком долгое время жизни"
+// @Failure 500 {object} ErrorResponse "Внутренняя ошибка сервера"
+// @Failure 503 {object} ErrorResponse "Запрос отменён"
+// @Failure 504 {object} ErrorResponse "Истекло время ожидания хранилища"
+// @Router /links/{code} [patch]
+func (h *Handler) UpdateLink(ctx *gin.Context) {
+	var patch model.LinkPatch
+	if err := ctx.ShouldBindJSON(&patch); err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Message: err.Error()})
+		ctx.Abort()
+		return
+	}
+	if patch.LongURL == "" && patch.ExpiresAt == nil && patch.TTLSeconds == 0 && !patch.ClearExpiry {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Message: "nothing to update"})
+		ctx.Abort()
+		return
+	}
+
+	var expiresAt *time.Time
+	switch {
+	case patch.ClearExpiry && patch.TTLSeconds != 0:
+		expiryError(ctx, fmt.Errorf("%w: expires_at and ttl_seconds are mutually exclusive", service.ErrInvalidExpiry))
+		return
+	case patch.ClearExpiry:
+		expiresAt = &time.Time{}
+	case patch.ExpiresAt != nil || patch.TTLSeconds != 0:
+		resolved, err := service.ResolveExpiry(patch.ExpiresAt, patch.TTLSeconds, time.Now())
+		if err != nil {
+			expiryError(ctx, err)
+			return
+		}
+		expiresAt = &resolved
+	}
+
+	link, err := h.shortenerService.UpdateLink(ctx.Request.Context(), ctx.Param("code"), ctx.GetString(OwnerKey), patch.LongURL, expiresAt)
+	if err != nil {
+		h.linkError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, linkInfo(link))
+}
+
+// @Summary Удалить короткую ссылку
+// @Description Удаляет ссылку, после чего код может быть занят заново.
+// @Description При включённой аутентификации доступны только ссылки, созданные тем же API-ключом.
+// @Tags Управление ссылками
+// @Security ApiKeyAuth
+// @Param code path string true "Короткий код"
+// @Success 204 "Ссылка удалена"
+// @Failure 401 {object} ErrorResponse "Неверный API-ключ"
+// @Failure 403 {object} ErrorResponse "Ссылка принадлежит другому ключу"
+// @Failure 404 {object} ErrorResponse "Ссылка не найдена"
+// @Failure 500 {object} ErrorResponse "Внутренняя ошибка сервера"
+// @Failure 503 {object} ErrorResponse "Запрос отменён"
+// @Failure 504 {object} ErrorResponse "Истекло время ожидания хранилища"
+// @Router /links/{code} [delete]
+func (h *Handler) DeleteLink(ctx *gin.Context) {
+	if err := h.shortenerService.DeleteLink(ctx.Request.Context(), ctx.Param("code"), ctx.GetString(OwnerKey)); err != nil {
+		h.linkError(ctx, err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+func (h *Handler) linkError(ctx *gin.Context, err error) {
+	var validationErr *service.URLValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		ctx.JSON(http.StatusUnprocessableEntity, ValidationErrorResponse{Message: err.Error(), Field: "long_url", Reason: validationErr.Reason})
+	case errors.Is(err, storage.ErrNotFound):
+		ctx.JSON(http.StatusNotFound, ErrorResponse{Message: err.Error()})
+	case errors.Is(err, service.ErrForbidden):
+		ctx.JSON(http.StatusForbidden, ErrorResponse{Message: err.Error()})
+	case errors.Is(err, storage.ErrAlreadyExists):
+		ctx.JSON(http.StatusConflict, ErrorResponse{Message: "long url is already shortened under another code"})
+	default:
+		h.logger.Errorf("Ошибка при управлении ссылкой: %v", err)
+		ctx.JSON(errorStatus(err), ErrorResponse{Message: err.Error()})
+	}
+	ctx.Abort()
+}
+
+func linkInfo(link model.Link) model.LinkInfo {
+	info := model.LinkInfo{
+		ShortURL:  link.ShortURL,
+		LongURL:   link.LongURL,
+		Owner:     link.Owner,
+		CreatedAt: link.CreatedAt,
+		UpdatedAt: link.UpdatedAt,
+	}
+	if !link.ExpiresAt.IsZero() {
+		info.ExpiresAt = &link.ExpiresAt
+	}
+	return info
+}
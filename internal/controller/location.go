@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+var errInvalidTarget = errors.New("invalid redirect target")
+
+// Собирает значение заголовка Location из сохранённой длинной ссылки:
+// хост переводится в punycode, путь, запрос и фрагмент экранируются,
+// чтобы в заголовок не попали пробелы, не-ASCII и управляющие символы
+func redirectLocation(longUrl string) (string, error) {
+	u, err := url.Parse(longUrl)
+	if err != nil {
+		return "", err
+	}
+	if !u.IsAbs() || u.Host == "" {
+		return "", errInvalidTarget
+	}
+
+	host := u.Hostname()
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]" // IPv6-литерал
+	} else if host, err = idna.Lookup.ToASCII(host); err != nil {
+		return "", err
+	}
+	if port := u.Port(); port != "" {
+		host += ":" + port
+	}
+
+	var b strings.Builder
+	b.WriteString(u.Scheme)
+	b.WriteString("://")
+	if u.User != nil {
+		b.WriteString(u.User.String())
+		b.WriteByte('@')
+	}
+	b.WriteString(host)
+	b.WriteString(u.EscapedPath())
+	if u.ForceQuery || u.RawQuery != "" {
+		b.WriteByte('?')
+		b.WriteString(escapeQuery(u.RawQuery))
+	}
+	if u.Fragment != "" {
+		b.WriteByte('#')
+		b.WriteString(u.EscapedFragment())
+	}
+	return b.String(), nil
+}
+
+// Экранирует в строке запроса только недопустимые байты, не трогая уже закодированные последовательности
+func escapeQuery(query string) string {
+	const hex = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		if c <= ' ' || c >= 0x7f || strings.IndexByte(`"<>\^`+"`{|}", c) >= 0 {
+			b.WriteByte('%')
+			b.WriteByte(hex[c>>4])
+			b.WriteByte(hex[c&15])
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
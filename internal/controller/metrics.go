@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"strconv"
+	"time"
+
+	"url-shortener/pkg/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+const metricsUrl = "/metrics"
+
+// Маршрут запросов, не совпавших ни с одним обработчиком. Путь запроса в метку
+// не попадает, чтобы не плодить серии на произвольных адресах
+const unmatchedRoute = "unmatched"
+
+type HTTPMetrics struct {
+	registry *metrics.Registry
+	requests *metrics.CounterVec
+	duration *metrics.HistogramVec
+}
+
+func NewHTTPMetrics(registry *metrics.Registry) *HTTPMetrics {
+	return &HTTPMetrics{
+		registry: registry,
+		requests: registry.NewCounterVec("http_requests_total",
+			"HTTP requests by method, route and status.", "method", "route", "status"),
+		duration: registry.NewHistogramVec("http_request_duration_seconds",
+			"HTTP request latency by method, route and status.", metrics.DefaultBuckets, "method", "route", "status"),
+	}
+}
+
+// Middleware, считающий запросы и их длительность по шаблону маршрута и коду ответа
+func (m *HTTPMetrics) Middleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+		ctx.Next()
+
+		route := ctx.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		status := strconv.Itoa(ctx.Writer.Status())
+		m.requests.With(ctx.Request.Method, route, status).Inc()
+		m.duration.With(ctx.Request.Method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
+
+// Регистрирует эндпоинт метрик в текстовом формате Prometheus
+func (m *HTTPMetrics) Register(router *gin.Engine) {
+	router.GET(metricsUrl, m.Metrics)
+}
+
+// @Summary Метрики сервиса
+// @Description Возвращает счётчики и гистограммы запросов, сервиса и хранилища в текстовом формате Prometheus.
+// @Tags Мониторинг
+// @Produce plain
+// @Success 200 {string} string "Метрики в текстовом формате Prometheus"
+// @Router /metrics [get]
+func (m *HTTPMetrics) Metrics(ctx *gin.Context) {
+	m.registry.ServeHTTP(ctx.Writer, ctx.Request)
+}
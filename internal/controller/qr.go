@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"url-shortener/pkg/qr"
+	"url-shortener/pkg/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+const qrUrl = "/links/:code/qr"
+
+// Ограничения параметров QR-кода
+const (
+	defaultQRSize   = 256
+	minQRSize       = 64
+	maxQRSize       = 2048
+	defaultQRMargin = 4
+	maxQRMargin     = 16
+)
+
+// @Summary QR-код короткой ссылки
+// @Description Возвращает QR-код с полной короткой ссылкой в формате PNG или SVG. Адрес собирается из PUBLIC_BASE_URL,
+// @Description а если он не задан - из схемы и хоста запроса. Переход при генерации кода в статистику не записывается.
+// @Tags Расширение URL
+// @Produce png,image/svg+xml
+// @Param code path string true "Короткий код"
+// @Param format query string false "Формат изображения" Enums(png, svg) default(png)
+// @Param size query int false "Сторона изображения в пикселях" default(256) minimum(64) maximum(2048)
+// @Param margin query int false "Ширина светлого поля в модулях" default(4) minimum(0) maximum(16)
+// @Param level query string false "Уровень коррекции ошибок" Enums(L, M, Q, H) default(M)
+// @Param fg query string false "Цвет модулей, RRGGBB или RGB" default(000000)
+// @Param bg query string false "Цвет фона, RRGGBB или RGB" default(ffffff)
+// @Success 200 {file} file "Изображение QR-кода"
+// @Failure 400 {object} ErrorResponse "Неверный ввод"
+// @Failure 404 {object} ErrorResponse "Ссылка не найдена"
+// @Failure 410 {object} ErrorResponse "Срок действия ссылки истёк"
+// @Failure 429 {object} ErrorResponse "Превышен лимит запросов"
+// @Failure 500 {object} ErrorResponse "Внутренняя ошибка сервера"
+// @Failure 503 {object} ErrorResponse "Запрос отменён"
+// @Failure 504 {object} ErrorResponse "Истекло время ожидания хранилища"
+// @Router /links/{code}/qr [get]
+func (h *Handler) QRCode(ctx *gin.Context) {
+	format, level, opts, err := qrParams(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Message: err.Error()})
+		ctx.Abort()
+		return
+	}
+
+	code := ctx.Param("code")
+	_, err = h.shortenerService.Expansion(ctx.Request.Context(), code)
+	if errors.Is(err, storage.ErrNotFound) {
+		ctx.JSON(http.StatusNotFound, ErrorResponse{Message: err.Error()})
+		ctx.Abort()
+		return
+	}
+	if errors.Is(err, storage.ErrExpired) {
+		ctx.JSON(http.StatusGone, ErrorResponse{Message: err.Error()})
+		ctx.Abort()
+		return
+	}
+	if err != nil {
+		h.logger.Errorf("Ошибка при создании QR-кода: %v", err)
+		ctx.JSON(errorStatus(err), ErrorResponse{Message: err.Error()})
+		ctx.Abort()
+		return
+	}
+
+	qrCode, err := qr.Encode([]byte(h.publicURL(ctx, code)), level)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Message: err.Error()})
+		ctx.Abort()
+		return
+	}
+	var buf bytes.Buffer
+	contentType := "image/png"
+	if format == "svg" {
+		contentType = "image/svg+xml"
+		err = qrCode.SVG(&buf, opts)
+	} else {
+		err = qrCode.PNG(&buf, opts)
+	}
+	if err != nil {
+		h.logger.Errorf("Ошибка при отрисовке QR-кода: %v", err)
+		ctx.JSON(http.StatusInternalServerError, ErrorResponse{Message: err.Error()})
+		ctx.Abort()
+		return
+	}
+	// Ссылку можно изменить или удалить, поэтому изображение кэшируется ненадолго. Без внешнего адреса
+	// в код записан хост запроса, и общий кэш не должен отдавать это изображение запросам с другим Host
+	if h.opts.BaseURL == "" {
+		ctx.Header("Cache-Control", "private, max-age=300")
+		ctx.Header("Vary", "Host")
+	} else {
+		ctx.Header("Cache-Control", "public, max-age=300")
+	}
+	ctx.Data(http.StatusOK, contentType, buf.Bytes())
+}
+
+func qrParams(ctx *gin.Context) (format string, level qr.Level, opts qr.Options, err error) {
+	format = strings.ToLower(ctx.DefaultQuery("format", "png"))
+	if format != "png" && format != "svg" {
+		return "", 0, opts, fmt.Errorf("format: unsupported value %q, specify png or svg", format)
+	}
+	if level, err = qr.ParseLevel(ctx.DefaultQuery("level", "M")); err != nil {
+		return "", 0, opts, err
+	}
+	opts = qr.Options{Foreground: qr.Black, Background: qr.White}
+	if opts.Size, err = intQuery(ctx, "size", defaultQRSize, minQRSize, maxQRSize); err != nil {
+		return "", 0, opts, err
+	}
+	if opts.Margin, err = intQuery(ctx, "margin", defaultQRMargin, 0, maxQRMargin); err != nil {
+		return "", 0, opts, err
+	}
+	if raw := ctx.Query("fg"); raw != "" {
+		if opts.Foreground, err = qr.ParseColor(raw); err != nil {
+			return "", 0, opts, err
+		}
+	}
+	if raw := ctx.Query("bg"); raw != "" {
+		if opts.Background, err = qr.ParseColor(raw); err != nil {
+			return "", 0, opts, err
+		}
+	}
+	return format, level, opts, nil
+}
+
+func intQuery(ctx *gin.Context, name string, def, min, max int) (int, error) {
+	raw := ctx.Query(name)
+	if raw == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < min || n > max {
+		return 0, fmt.Errorf("%s: %q is not an integer in range %d-%d", name, raw, min, max)
+	}
+	return n, nil
+}
+
+// Полная короткая ссылка: внешний адрес сервиса из конфигурации или схема и хост запроса
+func (h *Handler) publicURL(ctx *gin.Context, code string) string {
+	base := h.opts.BaseURL
+	if base == "" {
+		scheme := "http"
+		if ctx.Request.TLS != nil {
+			scheme = "https"
+		}
+		base = scheme + "://" + ctx.Request.Host
+	}
+	return strings.TrimSuffix(base, "/") + "/" + url.PathEscape(code)
+}
@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"url-shortener/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Поведение при недоступном хранилище лимитов: Timeout ограничивает время проверки (0 - без ограничения),
+// FailOpen пропускает запрос при ошибке хранилища, иначе сервер отвечает 503
+type RateLimiterOptions struct {
+	Timeout  time.Duration
+	FailOpen bool
+}
+
+type RateLimiter struct {
+	store  service.RateLimitStore
+	logger Logger
+	opts   RateLimiterOptions
+}
+
+func NewRateLimiter(store service.RateLimitStore, logger Logger, opts RateLimiterOptions) *RateLimiter {
+	return &RateLimiter{store: store, logger: logger, opts: opts}
+}
+
+// Ключ контекста gin с функцией списания лимита пакетного запроса, см. LimitBatch
+const rateLimitChargeKey = "rate_limit_charge"
+
+// Списывает cost запросов. false - ответ (429 или 503) уже отправлен и запрос прерван
+type rateLimitCharge func(ctx *gin.Context, cost int) bool
+
+// Возвращает middleware, ограничивающий частоту запросов к группе эндпоинтов name.
+// Клиент определяется по API-ключу, если запрос аутентифицирован, иначе по IP-адресу
+// (X-Forwarded-For учитывается только от доверенных прокси, см. gin.Engine.SetTrustedProxies)
+func (l *RateLimiter) Limit(name string, policy service.RateLimitPolicy) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if l.check(ctx, name, policy, 1) {
+			ctx.Next()
+		}
+	}
+}
+
+// Возвращает middleware для пакетных эндпоинтов: размер пакета известен только после разбора тела,
+// поэтому middleware лишь передаёт обработчику функцию списания (chargeRateLimit), и тот списывает
+// по одному запросу на каждый элемент пакета
+func (l *RateLimiter) LimitBatch(name string, policy service.RateLimitPolicy) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Set(rateLimitChargeKey, rateLimitCharge(func(ctx *gin.Context, cost int) bool {
+			return l.check(ctx, name, policy, cost)
+		}))
+		ctx.Next()
+	}
+}
+
+// Списывает cost запросов по лимиту, установленному LimitBatch. Без лимита всегда true
+func chargeRateLimit(ctx *gin.Context, cost int) bool {
+	value, _ := ctx.Get(rateLimitChargeKey)
+	charge, ok := value.(rateLimitCharge)
+	return !ok || charge(ctx, cost)
+}
+
+// Списывает cost запросов и передаёт заголовки RateLimit-*. При отказе прерывает запрос и возвращает false
+func (l *RateLimiter) check(ctx *gin.Context, name string, policy service.RateLimitPolicy, cost int) bool {
+	client := "ip:" + ctx.ClientIP()
+	if owner := ctx.GetString(OwnerKey); owner != "" {
+		client = "key:" + owner
+	}
+
+	res, err := l.take(ctx.Request.Context(), name+":"+client, policy, cost)
+	if err != nil {
+		l.logger.Errorf("Ошибка при проверке лимита запросов: %v", err)
+		if l.opts.FailOpen {
+			return true
+		}
+		ctx.AbortWithStatusJSON(http.StatusServiceUnavailable, ErrorResponse{Message: "rate limit unavailable"})
+		return false
+	}
+
+	ctx.Header("RateLimit-Limit", strconv.Itoa(res.Limit))
+	ctx.Header("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	ctx.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+	if !res.Allowed {
+		ctx.Header("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter)))
+		ctx.AbortWithStatusJSON(http.StatusTooManyRequests, ErrorResponse{Message: "rate limit exceeded"})
+		return false
+	}
+	return true
+}
+
+func (l *RateLimiter) take(ctx context.Context, key string, policy service.RateLimitPolicy, cost int) (service.RateLimitResult, error) {
+	if l.opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, l.opts.Timeout)
+		defer cancel()
+	}
+	return l.store.Take(ctx, key, policy, cost, time.Now())
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
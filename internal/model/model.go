@@ -1,9 +1,136 @@
 package model
 
+import (
+	"encoding/json"
+	"time"
+)
+
 type LongURL struct {
-	URL string `json:"long_url" binding:"required"`
+	URL        string     `json:"long_url" binding:"required"`
+	Alias      string     `json:"alias,omitempty"`       // Желаемый короткий код (необязательно)
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`  // Момент истечения ссылки в формате RFC 3339 (необязательно)
+	TTLSeconds int64      `json:"ttl_seconds,omitempty"` // Время жизни ссылки в секундах (необязательно, не больше 10 лет)
+}
+
+// Запись о короткой ссылке в хранилище
+type Link struct {
+	ShortURL  string
+	LongURL   string
+	ExpiresAt time.Time // Нулевое значение - ссылка бессрочная
+	Owner     string    // Идентификатор API-ключа, создавшего ссылку
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Результат занятия кода для одной ссылки пакета
+type Allocation struct {
+	ShortURL string // Код, под которым сохранена ссылка
+	Exists   bool   // Ссылка уже была сохранена, новая запись не создана
+	Err      error
+}
+
+// Срок действия в изменении ссылки (Storage.Update), после которого ссылка становится бессрочной:
+// нулевой срок в изменении оставляет прежнее значение
+var NoExpiry = time.Unix(0, 0).UTC()
+
+// @Description Сведения о короткой ссылке
+type LinkInfo struct {
+	ShortURL  string     `json:"short_url"`
+	LongURL   string     `json:"long_url"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // Момент истечения ссылки, отсутствует у бессрочных
+	Owner     string     `json:"owner,omitempty"`      // Идентификатор API-ключа, создавшего ссылку
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// @Description Изменение короткой ссылки, отсутствующие поля не меняются
+type LinkPatch struct {
+	LongURL     string     `json:"long_url,omitempty"`    // Новая длинная ссылка
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`  // Новый момент истечения в формате RFC 3339, null делает ссылку бессрочной
+	TTLSeconds  int64      `json:"ttl_seconds,omitempty"` // Новое время жизни в секундах от момента запроса, не больше 10 лет
+	ClearExpiry bool       `json:"-"`                     // В запросе передан "expires_at": null
+}
+
+// Отличает явный "expires_at": null от отсутствующего поля
+func (p *LinkPatch) UnmarshalJSON(data []byte) error {
+	type linkPatch LinkPatch
+	if err := json.Unmarshal(data, (*linkPatch)(p)); err != nil {
+		return err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	raw, ok := fields["expires_at"]
+	p.ClearExpiry = ok && string(raw) == "null"
+	return nil
+}
+
+// @Description Результат сокращения одной ссылки пакетного запроса
+type BatchItemResult struct {
+	ShortURL string `json:"short_url,omitempty"` // Сокращённая ссылка, если сокращение удалось
+	Status   int    `json:"status"`              // HTTP-код результата, как для одиночного запроса
+	Error    string `json:"error,omitempty"`     // Описание ошибки
+	Reason   string `json:"reason,omitempty"`    // Причина отклонения длинной ссылки или времени жизни для статуса 422
 }
 
 type ShortURL struct {
 	URL string `json:"short_url" binding:"required"`
 }
+
+// Событие перехода по короткой ссылке
+type Click struct {
+	ShortURL  string
+	Timestamp time.Time
+	Referrer  string
+	UserAgent string
+	ClientIP  string // Анонимизированный адрес клиента
+}
+
+// @Description Статистика переходов по короткой ссылке
+type LinkStats struct {
+	ShortURL string        `json:"short_url"`
+	Total    int64         `json:"total"`
+	Daily    []DailyClicks `json:"daily"`
+}
+
+type DailyClicks struct {
+	Date   string `json:"date"` // День в формате YYYY-MM-DD (UTC)
+	Clicks int64  `json:"clicks"`
+}
+
+// Ключ доступа к API. Сам ключ не хранится, сохраняется только его хэш
+type APIKey struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Hash      string     `json:"-"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// @Description Созданный ключ доступа, значение key показывается только один раз
+type NewAPIKey struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Key       string    `json:"key"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type APIKeyRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// @Description Готовность сервиса принимать запросы с результатами проверки каждой зависимости
+type Readiness struct {
+	Status       string                      `json:"status"`        // ok или unavailable
+	ShuttingDown bool                        `json:"shutting_down"` // Сервис завершает работу и не принимает новые запросы
+	Checks       map[string]DependencyStatus `json:"checks"`
+}
+
+// @Description Результат проверки одной зависимости
+type DependencyStatus struct {
+	Status     string `json:"status"`           // ok или unavailable
+	Detail     string `json:"detail,omitempty"` // Дополнительные сведения, например версия миграций
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
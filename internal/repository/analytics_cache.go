@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"url-shortener/internal/model"
+)
+
+type CacheAnalyticsStorage struct {
+	clicks map[string][]model.Click
+	sync.Mutex
+}
+
+func NewCacheAnalyticsStorage() *CacheAnalyticsStorage {
+	return &CacheAnalyticsStorage{clicks: make(map[string][]model.Click)}
+}
+
+func (s *CacheAnalyticsStorage) InsertClick(_ context.Context, click model.Click) error {
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+	s.clicks[click.ShortURL] = append(s.clicks[click.ShortURL], click)
+	return nil
+}
+
+func (s *CacheAnalyticsStorage) ClickStats(_ context.Context, shortURL string, since time.Time) (int64, map[string]int64, error) {
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+	clicks := s.clicks[shortURL]
+	daily := make(map[string]int64)
+	for _, click := range clicks {
+		if !click.Timestamp.Before(since) {
+			daily[click.Timestamp.UTC().Format("2006-01-02")]++
+		}
+	}
+	return int64(len(clicks)), daily, nil
+}
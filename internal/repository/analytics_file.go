@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"encoding/binary"
+	"time"
+
+	"url-shortener/internal/model"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Ключ общего числа переходов в бакете ссылки, остальные ключи - дни YYYY-MM-DD
+var fileClicksTotal = []byte("total")
+
+// Хранилище аналитики в файле FileStorage. Как и в RedisAnalyticsStorage, переходы складываются
+// в счётчики по дням во вложенном бакете кода, которые удаляются вместе со ссылкой
+type FileAnalyticsStorage struct {
+	db *bolt.DB
+}
+
+func NewFileAnalyticsStorage(s *FileStorage) *FileAnalyticsStorage {
+	return &FileAnalyticsStorage{db: s.db}
+}
+
+func (s *FileAnalyticsStorage) InsertClick(_ context.Context, click model.Click) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.Bucket(fileClicksBucket).CreateBucketIfNotExists([]byte(click.ShortURL))
+		if err != nil {
+			return err
+		}
+		for _, key := range [][]byte{fileClicksTotal, []byte(click.Timestamp.UTC().Format("2006-01-02"))} {
+			if err := bucket.Put(key, fileCounter(fileCounterValue(bucket.Get(key))+1)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *FileAnalyticsStorage) ClickStats(_ context.Context, shortURL string, since time.Time) (int64, map[string]int64, error) {
+	var total int64
+	daily := make(map[string]int64)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(fileClicksBucket).Bucket([]byte(shortURL))
+		if bucket == nil {
+			return nil
+		}
+		total = fileCounterValue(bucket.Get(fileClicksTotal))
+		// Дни в формате YYYY-MM-DD упорядочены как строки, поэтому обход начинается с since
+		c := bucket.Cursor()
+		for day, value := c.Seek([]byte(since.UTC().Format("2006-01-02"))); day != nil; day, value = c.Next() {
+			if string(day) != string(fileClicksTotal) {
+				daily[string(day)] = fileCounterValue(value)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	return total, daily, nil
+}
+
+func fileCounter(n int64) []byte {
+	return binary.BigEndian.AppendUint64(nil, uint64(n))
+}
+
+func fileCounterValue(value []byte) int64 {
+	if len(value) != 8 {
+		return 0
+	}
+	return int64(binary.BigEndian.Uint64(value))
+}
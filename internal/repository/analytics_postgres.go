@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"url-shortener/internal/model"
+	"url-shortener/pkg/storage/postgres"
+)
+
+type DataBaseAnalyticsStorage struct {
+	pool *postgres.Pool
+}
+
+func NewDataBaseAnalyticsStorage(pool *postgres.Pool) *DataBaseAnalyticsStorage {
+	return &DataBaseAnalyticsStorage{pool: pool}
+}
+
+func (s *DataBaseAnalyticsStorage) InsertClick(ctx context.Context, click model.Click) error {
+	query := "INSERT INTO clicks (short_url, clicked_at, referrer, user_agent, client_ip) VALUES ($1, $2, $3, $4, $5)"
+	_, err := s.pool.Exec(ctx, query, click.ShortURL, click.Timestamp, click.Referrer, click.UserAgent, click.ClientIP)
+	return err
+}
+
+func (s *DataBaseAnalyticsStorage) ClickStats(ctx context.Context, shortURL string, since time.Time) (int64, map[string]int64, error) {
+	var total int64
+	err := s.pool.QueryRow(ctx, "SELECT count(*) FROM clicks WHERE short_url = $1", shortURL).Scan(&total)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	query := `SELECT to_char(clicked_at AT TIME ZONE 'UTC', 'YYYY-MM-DD') AS day, count(*) FROM clicks
+		WHERE short_url = $1 AND clicked_at >= $2 GROUP BY day`
+	rows, err := s.pool.Query(ctx, query, shortURL, since)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer rows.Close()
+
+	daily := make(map[string]int64)
+	for rows.Next() {
+		var day string
+		var clicks int64
+		if err := rows.Scan(&day, &clicks); err != nil {
+			return 0, nil, err
+		}
+		daily[day] = clicks
+	}
+	return total, daily, rows.Err()
+}
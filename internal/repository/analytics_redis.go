@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"url-shortener/internal/model"
+	"url-shortener/pkg/storage/redis"
+)
+
+const (
+	redisClicksPrefix = "clicks:" // Счётчики переходов: clicks:<код> -> хэш total и YYYY-MM-DD -> число переходов
+	redisClicksTotal  = "total"
+)
+
+// Хранилище аналитики в Redis. Переходы не хранятся по отдельности, а складываются в счётчики по дням,
+// поэтому объём зависит от числа ссылок и дней, а не от числа переходов.
+// Счётчики удаляются вместе со ссылкой и при занятии кода новой ссылкой, а у истекающей ссылки
+// сервер удаляет их по тому же TTL, что и её запись
+type RedisAnalyticsStorage struct {
+	client *redis.Client
+}
+
+func NewRedisAnalyticsStorage(client *redis.Client) *RedisAnalyticsStorage {
+	return &RedisAnalyticsStorage{client: client}
+}
+
+func (s *RedisAnalyticsStorage) InsertClick(ctx context.Context, click model.Click) error {
+	key := redisClicksPrefix + click.ShortURL
+	replies, err := s.client.Pipeline(ctx, [][]string{
+		{"HINCRBY", key, redisClicksTotal, "1"},
+		{"HINCRBY", key, click.Timestamp.UTC().Format("2006-01-02"), "1"},
+		{"PEXPIRETIME", key},
+		{"PEXPIRETIME", redisKeyPrefix + click.ShortURL},
+	})
+	if err != nil {
+		return err
+	}
+	for _, reply := range replies {
+		if replyErr, ok := reply.(redis.Error); ok {
+			return replyErr
+		}
+	}
+	// Первый переход создаёт хэш без TTL: он получает TTL записи ссылки. Дальше TTL
+	// меняется вместе с записью в RedisStorage.Update, поэтому второй запрос нужен только здесь
+	clicksAt, _ := replies[2].(int64)
+	linkAt, _ := replies[3].(int64)
+	if clicksAt == -1 && linkAt > 0 {
+		_, err = s.client.Do(ctx, "PEXPIREAT", key, strconv.FormatInt(linkAt, 10))
+	}
+	return err
+}
+
+func (s *RedisAnalyticsStorage) ClickStats(ctx context.Context, shortURL string, since time.Time) (int64, map[string]int64, error) {
+	reply, err := s.client.Do(ctx, "HGETALL", redisClicksPrefix+shortURL)
+	if err != nil {
+		return 0, nil, err
+	}
+	items, _ := reply.([]interface{})
+	var total int64
+	daily := make(map[string]int64)
+	first := since.UTC().Format("2006-01-02")
+	for i := 0; i+1 < len(items); i += 2 {
+		field, _ := items[i].(string)
+		value, _ := items[i+1].(string)
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return 0, nil, fmt.Errorf("redis: clicks counter %s: %w", field, err)
+		}
+		switch {
+		case field == redisClicksTotal:
+			total = n
+		case field >= first:
+			daily[field] = n
+		}
+	}
+	return total, daily, nil
+}
@@ -1,37 +1,253 @@
 package repository
 
 import (
+	"context"
 	"sync"
+	"time"
 
+	"url-shortener/internal/model"
 	"url-shortener/pkg/storage"
 )
 
+// Без журнала (NewCacheStorage) данные хранятся только в памяти процесса,
+// с журналом (OpenCacheStorage) каждое изменение сохраняется на диск до применения.
+// Ограничения и повторное занятие кодов работают как в DataBaseStorage: и код, и длинная ссылка уникальны,
+// Insert перезаписывает истёкший код, InsertBatch - нет
 type CacheStorage struct {
-	data map[string]string
+	data  map[string]model.Link
+	codes map[string]string // Индекс длинная ссылка -> код, повторяющий ограничение UNIQUE(long_url)
+	log   *cacheLog
 	sync.Mutex
 }
 
 func NewCacheStorage() *CacheStorage {
-	return &CacheStorage{data: make(map[string]string)}
+	return &CacheStorage{data: make(map[string]model.Link), codes: make(map[string]string)}
 }
 
-func (c *CacheStorage) GetLongUrl(shortURL string) (string, error) {
+func (c *CacheStorage) GetLongUrl(_ context.Context, shortURL string) (string, error) {
 	c.Mutex.Lock()
 	defer c.Mutex.Unlock()
 	res, ok := c.data[shortURL]
 	if !ok {
 		return "", storage.ErrNotFound
 	}
+	if expired(res, time.Now()) {
+		return "", storage.ErrExpired
+	}
 
-	return res, nil
+	return res.LongURL, nil
 }
 
-func (s *CacheStorage) Insert(shortURL, longURL string) error {
+// Код с истёкшим сроком действия перезаписывается новой ссылкой. Ссылка, уже сохранённая
+// под другим кодом (в том числе истёкшим), - storage.ErrAlreadyExists, как нарушение UNIQUE(long_url)
+func (s *CacheStorage) Insert(_ context.Context, link model.Link) error {
 	s.Mutex.Lock()
 	defer s.Mutex.Unlock()
-	if _, ok := s.data[shortURL]; ok {
+	now := time.Now()
+	if res, ok := s.data[link.ShortURL]; ok && !expired(res, now) {
 		return storage.ErrAlreadyExists
 	}
-	s.data[shortURL] = longURL
+	if code, ok := s.codes[link.LongURL]; ok && code != link.ShortURL {
+		return storage.ErrAlreadyExists
+	}
+	link.CreatedAt, link.UpdatedAt = now, now
+	if err := s.persist(newFileRecord(link)); err != nil {
+		return err
+	}
+	s.put(link)
 	return nil
 }
+
+// Проверка и вставка выполняются под одной блокировкой. Как DataBaseStorage.Allocate: истёкшие записи
+// с тем же кодом или той же длинной ссылкой удаляются, для уже сохранённой ссылки возвращается её код,
+// даже если он отличается от link.ShortURL
+func (s *CacheStorage) Allocate(_ context.Context, link model.Link) (string, bool, error) {
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+	return s.allocate(link, time.Now())
+}
+
+// Весь пакет занимается под одной блокировкой
+func (s *CacheStorage) AllocateBatch(_ context.Context, links []model.Link) ([]model.Allocation, error) {
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+	now := time.Now()
+	res := make([]model.Allocation, len(links))
+	for i, link := range links {
+		shortURL, exists, err := s.allocate(link, now)
+		if err != nil && err != storage.ErrAlreadyExists {
+			return nil, err
+		}
+		res[i] = model.Allocation{ShortURL: shortURL, Exists: exists, Err: err}
+	}
+	return res, nil
+}
+
+func (s *CacheStorage) allocate(link model.Link, now time.Time) (string, bool, error) {
+	var recs []fileRecord
+	if res, ok := s.data[link.ShortURL]; ok && expired(res, now) {
+		recs = append(recs, fileRecord{Op: fileOpDelete, ShortURL: link.ShortURL})
+	}
+	if code, ok := s.codes[link.LongURL]; ok && code != link.ShortURL {
+		if !expired(s.data[code], now) {
+			return code, true, nil
+		}
+		recs = append(recs, fileRecord{Op: fileOpDelete, ShortURL: code})
+	} else if ok && len(recs) == 0 {
+		return code, true, nil
+	}
+	if res, ok := s.data[link.ShortURL]; ok && !expired(res, now) {
+		return "", false, storage.ErrAlreadyExists
+	}
+	link.CreatedAt, link.UpdatedAt = now, now
+	if err := s.persist(append(recs, newFileRecord(link))...); err != nil {
+		return "", false, err
+	}
+	for _, rec := range recs {
+		s.remove(rec.ShortURL)
+	}
+	s.put(link)
+	return link.ShortURL, false, nil
+}
+
+// Занятые коды и уже сохранённые ссылки (в том числе истёкшие) не перезаписываются и отмечаются
+// storage.ErrAlreadyExists
+func (s *CacheStorage) InsertBatch(_ context.Context, links []model.Link) ([]error, error) {
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+	now := time.Now()
+	errs := make([]error, len(links))
+	var added []model.Link
+	codes := make(map[string]bool)
+	longURLs := make(map[string]bool)
+	var recs []fileRecord
+	for i, link := range links {
+		_, taken := s.data[link.ShortURL]
+		_, found := s.codes[link.LongURL]
+		if taken || found || codes[link.ShortURL] || longURLs[link.LongURL] {
+			errs[i] = storage.ErrAlreadyExists
+			continue
+		}
+		link.CreatedAt, link.UpdatedAt = now, now
+		codes[link.ShortURL], longURLs[link.LongURL] = true, true
+		added = append(added, link)
+		recs = append(recs, newFileRecord(link))
+	}
+	if err := s.persist(recs...); err != nil {
+		return nil, err
+	}
+	for _, link := range added {
+		s.put(link)
+	}
+	return errs, nil
+}
+
+// Возвращает запись о ссылке, в том числе с истёкшим сроком действия
+func (s *CacheStorage) GetLink(_ context.Context, shortURL string) (model.Link, error) {
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+	res, ok := s.data[shortURL]
+	if !ok {
+		return model.Link{}, storage.ErrNotFound
+	}
+	return res, nil
+}
+
+// Новая длинная ссылка, уже сохранённая под другим кодом, - storage.ErrAlreadyExists
+func (s *CacheStorage) Update(_ context.Context, link model.Link) (model.Link, error) {
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+	res, ok := s.data[link.ShortURL]
+	if !ok {
+		return model.Link{}, storage.ErrNotFound
+	}
+	if !owns(res, link.Owner) {
+		return model.Link{}, storage.ErrForbidden
+	}
+	if code, found := s.codes[link.LongURL]; found && code != link.ShortURL {
+		return model.Link{}, storage.ErrAlreadyExists
+	}
+	res = updated(res, link)
+	if err := s.persist(newFileRecord(res)); err != nil {
+		return model.Link{}, err
+	}
+	s.put(res)
+	return res, nil
+}
+
+func (s *CacheStorage) Delete(_ context.Context, shortURL, owner string) error {
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+	link, ok := s.data[shortURL]
+	if !ok {
+		return storage.ErrNotFound
+	}
+	if !owns(link, owner) {
+		return storage.ErrForbidden
+	}
+	if err := s.persist(fileRecord{Op: fileOpDelete, ShortURL: shortURL}); err != nil {
+		return err
+	}
+	s.remove(shortURL)
+	return nil
+}
+
+func (s *CacheStorage) DeleteExpired(_ context.Context, now time.Time) (int64, error) {
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+	var recs []fileRecord
+	for shortURL, res := range s.data {
+		if expired(res, now) {
+			recs = append(recs, fileRecord{Op: fileOpDelete, ShortURL: shortURL})
+		}
+	}
+	if err := s.persist(recs...); err != nil {
+		return 0, err
+	}
+	for _, rec := range recs {
+		s.remove(rec.ShortURL)
+	}
+	return int64(len(recs)), nil
+}
+
+// Сохраняет запись, заменяя прежнюю запись с тем же кодом, и обновляет индекс длинных ссылок
+func (s *CacheStorage) put(link model.Link) {
+	s.remove(link.ShortURL)
+	s.data[link.ShortURL] = link
+	s.codes[link.LongURL] = link.ShortURL
+}
+
+func (s *CacheStorage) remove(shortURL string) {
+	old, ok := s.data[shortURL]
+	if !ok {
+		return
+	}
+	delete(s.data, shortURL)
+	if s.codes[old.LongURL] == shortURL {
+		delete(s.codes, old.LongURL)
+	}
+}
+
+func expired(link model.Link, now time.Time) bool {
+	return !link.ExpiresAt.IsZero() && !link.ExpiresAt.After(now)
+}
+
+// Пустой владелец - аутентификация отключена, и изменять можно любую ссылку
+func owns(link model.Link, owner string) bool {
+	return owner == "" || link.Owner == owner
+}
+
+// Применяет изменение Update к записи old: пустая длинная ссылка и нулевой срок оставляют прежние значения
+func updated(old, change model.Link) model.Link {
+	if change.LongURL != "" {
+		old.LongURL = change.LongURL
+	}
+	switch {
+	case change.ExpiresAt.Equal(model.NoExpiry):
+		old.ExpiresAt = time.Time{}
+	case !change.ExpiresAt.IsZero():
+		old.ExpiresAt = change.ExpiresAt
+	}
+	old.UpdatedAt = time.Now()
+	return old
+}
@@ -0,0 +1,356 @@
+package repository
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"url-shortener/internal/model"
+	"url-shortener/pkg/storage/journal"
+)
+
+// Файлы каталога: журналы wal-<поколение>.log и снимки snapshot-<поколение>.db.
+// Снимок поколения N содержит все изменения из журналов поколений до N включительно
+const (
+	cacheSnapshotPrefix = "snapshot-"
+	cacheSnapshotSuffix = ".db"
+	cacheLogPrefix      = "wal-"
+	cacheLogSuffix      = ".log"
+)
+
+// Журнал изменений CacheStorage: каждое изменение дописывается в журнал текущего поколения до изменения карты.
+// После snapshotRecords записей начинается новое поколение журнала, а копия карты записывается
+// в снимок в фоне, не задерживая запись, после чего журналы, вошедшие в снимок, удаляются
+type cacheLog struct {
+	dir             string
+	wal             *os.File
+	gen             uint64 // Поколение текущего журнала
+	size            int64
+	records         int // Записей в журналах, не вошедших в снимок
+	snapshotRecords int
+	snapshotting    bool
+	err             error // Ошибка последнего фонового снимка, возвращается из Close
+	wg              sync.WaitGroup
+}
+
+// Восстанавливает CacheStorage из последнего снимка и более новых журналов в каталоге dir и включает запись изменений.
+// Оборванная при сбое последняя запись журнала отбрасывается, повреждённая запись в середине журнала
+// или повреждённый снимок - ошибка: последующие записи нельзя потерять молча.
+// Снимок пишется после каждых snapshotRecords записей журнала
+func OpenCacheStorage(dir string, snapshotRecords int) (*CacheStorage, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	snapshots, logs, err := listCacheFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	s := NewCacheStorage()
+	var base, last uint64
+	if len(snapshots) > 0 {
+		base = snapshots[len(snapshots)-1]
+		if err := s.loadSnapshot(filepath.Join(dir, cacheFileName(cacheSnapshotPrefix, base, cacheSnapshotSuffix))); err != nil {
+			return nil, err
+		}
+	}
+	last = base
+	records := 0
+	for _, gen := range logs {
+		if gen <= base {
+			continue
+		}
+		n, err := s.replayLog(filepath.Join(dir, cacheFileName(cacheLogPrefix, gen, cacheLogSuffix)))
+		if err != nil {
+			return nil, err
+		}
+		records += n
+		last = gen
+	}
+
+	s.log = &cacheLog{dir: dir, records: records, snapshotRecords: snapshotRecords}
+	if err := s.log.rotate(last + 1); err != nil {
+		return nil, err
+	}
+	s.log.removeObsolete(base)
+	return s, nil
+}
+
+// Снимок пишется целиком во временный файл и подменяет его после fsync, поэтому неполным быть не может
+func (s *CacheStorage) loadSnapshot(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	valid, err := journal.Replay(f, s.replay)
+	if err == nil && valid != info.Size() {
+		err = journal.ErrCorrupted
+	}
+	if err != nil {
+		return fmt.Errorf("snapshot %s: %w", path, err)
+	}
+	return nil
+}
+
+// Применяет журнал и обрезает его оборванный хвост. Возвращает количество применённых записей
+func (s *CacheStorage) replayLog(path string) (int, error) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0o644)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	records := 0
+	valid, err := journal.Replay(f, func(payload []byte, offset int64) error {
+		records++
+		return s.replay(payload, offset)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("log %s: %w", path, err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	if valid < info.Size() {
+		if err := f.Truncate(valid); err != nil {
+			return 0, err
+		}
+		if err := f.Sync(); err != nil {
+			return 0, err
+		}
+	}
+	return records, nil
+}
+
+func (s *CacheStorage) replay(payload []byte, _ int64) error {
+	var rec fileRecord
+	if err := json.Unmarshal(payload, &rec); err != nil {
+		return err
+	}
+	if rec.Op == fileOpDelete {
+		s.remove(rec.ShortURL)
+	} else {
+		s.put(rec.link())
+	}
+	return nil
+}
+
+// Дописывает изменения в журнал и сбрасывает их на диск, при накоплении snapshotRecords записей
+// запускает фоновый снимок. Без журнала ничего не делает. Вызывается под блокировкой
+func (s *CacheStorage) persist(recs ...fileRecord) error {
+	if s.log == nil || len(recs) == 0 {
+		return nil
+	}
+	var buf bytes.Buffer
+	for _, rec := range recs {
+		payload, err := json.Marshal(rec)
+		if err != nil {
+			return err
+		}
+		if _, err := journal.Append(&buf, payload); err != nil {
+			return err
+		}
+	}
+	if _, err := s.log.wal.WriteAt(buf.Bytes(), s.log.size); err != nil {
+		return err
+	}
+	if err := s.log.wal.Sync(); err != nil {
+		return err
+	}
+	s.log.size += int64(buf.Len())
+	s.log.records += len(recs)
+	if s.log.records >= s.log.snapshotRecords && !s.log.snapshotting {
+		s.log.snapshotting = true
+		s.log.wg.Add(1)
+		go s.backgroundSnapshot()
+	}
+	return nil
+}
+
+func (s *CacheStorage) backgroundSnapshot() {
+	defer s.log.wg.Done()
+	s.Mutex.Lock()
+	log := s.log
+	gen, links, err := s.beginSnapshot()
+	s.Mutex.Unlock()
+	if err == nil {
+		err = log.writeSnapshot(gen, links)
+	}
+	s.Mutex.Lock()
+	log.err = err
+	log.snapshotting = false
+	s.Mutex.Unlock()
+}
+
+// Под блокировкой начинает новое поколение журнала и копирует карту. Возвращает поколение,
+// которое покроет снимок из этой копии
+func (s *CacheStorage) beginSnapshot() (uint64, []model.Link, error) {
+	gen := s.log.gen
+	if err := s.log.rotate(gen + 1); err != nil {
+		return 0, nil, err
+	}
+	s.log.records = 0
+	links := make([]model.Link, 0, len(s.data))
+	for _, link := range s.data {
+		links = append(links, link)
+	}
+	return gen, links, nil
+}
+
+// Закрывает текущий журнал и начинает журнал поколения gen
+func (l *cacheLog) rotate(gen uint64) error {
+	wal, err := os.OpenFile(filepath.Join(l.dir, cacheFileName(cacheLogPrefix, gen, cacheLogSuffix)), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if err := syncDir(l.dir); err != nil {
+		wal.Close()
+		return err
+	}
+	if l.wal != nil {
+		l.wal.Close()
+	}
+	l.wal, l.gen, l.size = wal, gen, 0
+	return nil
+}
+
+// Записывает снимок поколения gen во временный файл и атомарно публикует его.
+// Журналы, вошедшие в снимок, и старые снимки удаляются только после fsync каталога
+func (l *cacheLog) writeSnapshot(gen uint64, links []model.Link) error {
+	var buf bytes.Buffer
+	for _, link := range links {
+		payload, err := json.Marshal(newFileRecord(link))
+		if err != nil {
+			return err
+		}
+		if _, err := journal.Append(&buf, payload); err != nil {
+			return err
+		}
+	}
+
+	path := filepath.Join(l.dir, cacheFileName(cacheSnapshotPrefix, gen, cacheSnapshotSuffix))
+	tmpPath := path + ".tmp"
+	if err := writeFileSync(tmpPath, buf.Bytes()); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := syncDir(l.dir); err != nil {
+		return err
+	}
+	l.removeObsolete(gen)
+	return nil
+}
+
+// Удаляет снимки старше gen и журналы, вошедшие в снимок gen. Ошибки удаления не мешают работе:
+// лишние файлы будут пропущены при восстановлении и удалены следующим снимком
+func (l *cacheLog) removeObsolete(gen uint64) {
+	snapshots, logs, err := listCacheFiles(l.dir)
+	if err != nil {
+		return
+	}
+	for _, g := range snapshots {
+		if g < gen {
+			os.Remove(filepath.Join(l.dir, cacheFileName(cacheSnapshotPrefix, g, cacheSnapshotSuffix)))
+		}
+	}
+	for _, g := range logs {
+		if g <= gen && g != l.gen {
+			os.Remove(filepath.Join(l.dir, cacheFileName(cacheLogPrefix, g, cacheLogSuffix)))
+		}
+	}
+	syncDir(l.dir)
+}
+
+// Дожидается фонового снимка, записывает итоговый снимок и закрывает журнал
+func (s *CacheStorage) Close() error {
+	s.Mutex.Lock()
+	if s.log == nil {
+		s.Mutex.Unlock()
+		return nil
+	}
+	log := s.log
+	s.Mutex.Unlock()
+	log.wg.Wait()
+
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+	gen, links, err := s.beginSnapshot()
+	if err == nil {
+		err = log.writeSnapshot(gen, links)
+	}
+	if closeErr := log.wal.Close(); err == nil {
+		err = closeErr
+	}
+	s.log = nil
+	return errors.Join(log.err, err)
+}
+
+func cacheFileName(prefix string, gen uint64, suffix string) string {
+	return fmt.Sprintf("%s%020d%s", prefix, gen, suffix)
+}
+
+// Поколения снимков и журналов в каталоге по возрастанию
+func listCacheFiles(dir string) (snapshots, logs []uint64, err error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	parse := func(name, prefix, suffix string) (uint64, bool) {
+		if !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, suffix) {
+			return 0, false
+		}
+		gen, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(name, prefix), suffix), 10, 64)
+		return gen, err == nil
+	}
+	for _, entry := range entries {
+		if gen, ok := parse(entry.Name(), cacheSnapshotPrefix, cacheSnapshotSuffix); ok {
+			snapshots = append(snapshots, gen)
+		} else if gen, ok := parse(entry.Name(), cacheLogPrefix, cacheLogSuffix); ok {
+			logs = append(logs, gen)
+		}
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i] < snapshots[j] })
+	sort.Slice(logs, func(i, j int) bool { return logs[i] < logs[j] })
+	return snapshots, logs, nil
+}
+
+func writeFileSync(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Сбрасывает на диск содержимое каталога, чтобы созданные, переименованные и удалённые файлы пережили сбой
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"context"
+
+	"url-shortener/pkg/storage/postgres"
+)
+
+// Счётчик стратегии counter на последовательности short_code_seq, общей для всех экземпляров сервиса
+type DataBaseCounter struct {
+	pool *postgres.Pool
+}
+
+func NewDataBaseCounter(pool *postgres.Pool) *DataBaseCounter {
+	return &DataBaseCounter{pool: pool}
+}
+
+func (c *DataBaseCounter) Next(ctx context.Context) (uint64, error) {
+	var n int64
+	if err := c.pool.QueryRow(ctx, "SELECT nextval('short_code_seq')").Scan(&n); err != nil {
+		return 0, err
+	}
+	return uint64(n), nil
+}
@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"url-shortener/pkg/storage/redis"
+)
+
+const redisCounterKey = "counter:short_code"
+
+// Счётчик стратегии counter на ключе Redis, INCR увеличивает его атомарно
+type RedisCounter struct {
+	client *redis.Client
+}
+
+func NewRedisCounter(client *redis.Client) *RedisCounter {
+	return &RedisCounter{client: client}
+}
+
+func (c *RedisCounter) Next(ctx context.Context) (uint64, error) {
+	reply, err := c.client.Do(ctx, "INCR", redisCounterKey)
+	if err != nil {
+		return 0, err
+	}
+	n, ok := reply.(int64)
+	if !ok {
+		return 0, fmt.Errorf("redis: unexpected INCR reply %v", reply)
+	}
+	return uint64(n), nil
+}
@@ -0,0 +1,413 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"url-shortener/internal/model"
+	"url-shortener/pkg/storage"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	fileOpPut    = "put"
+	fileOpDelete = "del"
+)
+
+// Бакеты BoltDB: записи ссылок по коду и индекс SHA-256 длинной ссылки -> код,
+// повторяющий ограничение UNIQUE(long_url) таблицы urls, API-ключи FileKeyStorage
+// и счётчики переходов FileAnalyticsStorage
+var (
+	fileLinksBucket     = []byte("links")
+	fileLongURLBucket   = []byte("long_urls")
+	fileKeysBucket      = []byte("api_keys")
+	fileKeyHashesBucket = []byte("api_key_hashes")
+	fileClicksBucket    = []byte("clicks")
+)
+
+// Сколько ждать, пока файл освободит другой процесс, прежде чем вернуть ошибку
+const fileOpenTimeout = time.Second
+
+// Хранилище ссылок во встроенной базе BoltDB. Каждая операция - транзакция, которая
+// сбрасывается на диск до возврата из метода, поэтому сбой не оставляет базу в промежуточном состоянии.
+// Ограничения и повторное занятие кодов работают как в DataBaseStorage: и код, и длинная ссылка уникальны,
+// Insert перезаписывает истёкший код, InsertBatch - нет
+type FileStorage struct {
+	db *bolt.DB
+}
+
+// Запись о ссылке, в таком же виде она пишется в журнал CacheStorage
+type fileRecord struct {
+	Op        string     `json:"op"`
+	ShortURL  string     `json:"short_url"`
+	LongURL   string     `json:"long_url,omitempty"`
+	Owner     string     `json:"owner,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at,omitempty"`
+	UpdatedAt time.Time  `json:"updated_at,omitempty"`
+}
+
+// Открывает файл хранилища, создавая его и бакеты при первом запуске
+func NewFileStorage(path string) (*FileStorage, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	db, err := bolt.Open(path, 0o644, &bolt.Options{Timeout: fileOpenTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{fileLinksBucket, fileLongURLBucket, fileKeysBucket, fileKeyHashesBucket, fileClicksBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &FileStorage{db: db}, nil
+}
+
+// Бакеты одной транзакции
+type fileTx struct {
+	links, longURLs, clicks *bolt.Bucket
+}
+
+func newFileTx(tx *bolt.Tx) fileTx {
+	return fileTx{links: tx.Bucket(fileLinksBucket), longURLs: tx.Bucket(fileLongURLBucket), clicks: tx.Bucket(fileClicksBucket)}
+}
+
+func (t fileTx) get(shortURL string) (model.Link, bool, error) {
+	value := t.links.Get([]byte(shortURL))
+	if value == nil {
+		return model.Link{}, false, nil
+	}
+	var rec fileRecord
+	if err := json.Unmarshal(value, &rec); err != nil {
+		return model.Link{}, false, err
+	}
+	return rec.link(), true, nil
+}
+
+// Код, под которым сохранена длинная ссылка
+func (t fileTx) codeOf(longURL string) (string, bool) {
+	code := t.longURLs.Get(longURLKey(longURL))
+	return string(code), code != nil
+}
+
+// Записывает новую ссылку: счётчики переходов прежней ссылки с тем же кодом ей не достаются
+func (t fileTx) create(link model.Link) error {
+	if err := t.deleteClicks(link.ShortURL); err != nil {
+		return err
+	}
+	return t.put(link)
+}
+
+func (t fileTx) deleteClicks(shortURL string) error {
+	if err := t.clicks.DeleteBucket([]byte(shortURL)); err != nil && err != bolt.ErrBucketNotFound {
+		return err
+	}
+	return nil
+}
+
+func (t fileTx) put(link model.Link) error {
+	value, err := json.Marshal(newFileRecord(link))
+	if err != nil {
+		return err
+	}
+	if err := t.links.Put([]byte(link.ShortURL), value); err != nil {
+		return err
+	}
+	return t.longURLs.Put(longURLKey(link.LongURL), []byte(link.ShortURL))
+}
+
+func (t fileTx) delete(link model.Link) error {
+	if err := t.links.Delete([]byte(link.ShortURL)); err != nil {
+		return err
+	}
+	if code, ok := t.codeOf(link.LongURL); ok && code == link.ShortURL {
+		return t.longURLs.Delete(longURLKey(link.LongURL))
+	}
+	return nil
+}
+
+// Удаляет ссылку вместе со счётчиками её переходов
+func (t fileTx) remove(link model.Link) error {
+	if err := t.delete(link); err != nil {
+		return err
+	}
+	return t.deleteClicks(link.ShortURL)
+}
+
+// Удаляет запись с кодом shortURL, если её срок действия истёк
+func (t fileTx) deleteExpired(shortURL string, now time.Time) error {
+	link, ok, err := t.get(shortURL)
+	if err != nil || !ok || !expired(link, now) {
+		return err
+	}
+	return t.remove(link)
+}
+
+// Занимает код link.ShortURL, как FileStorage.Allocate
+func (t fileTx) allocate(link model.Link, now time.Time) (string, bool, error) {
+	if err := t.deleteExpired(link.ShortURL, now); err != nil {
+		return "", false, err
+	}
+	if code, ok := t.codeOf(link.LongURL); ok {
+		if err := t.deleteExpired(code, now); err != nil {
+			return "", false, err
+		}
+	}
+	if code, ok := t.codeOf(link.LongURL); ok {
+		return code, true, nil
+	}
+	if t.links.Get([]byte(link.ShortURL)) != nil {
+		return "", false, storage.ErrAlreadyExists
+	}
+	link.CreatedAt, link.UpdatedAt = now, now
+	return link.ShortURL, false, t.create(link)
+}
+
+// Длина ключа BoltDB ограничена, поэтому индекс строится по хэшу ссылки
+func longURLKey(longURL string) []byte {
+	sum := sha256.Sum256([]byte(longURL))
+	return sum[:]
+}
+
+func (s *FileStorage) GetLongUrl(_ context.Context, shortURL string) (string, error) {
+	var link model.Link
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var ok bool
+		var err error
+		if link, ok, err = newFileTx(tx).get(shortURL); err == nil && !ok {
+			return storage.ErrNotFound
+		}
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	if expired(link, time.Now()) {
+		return "", storage.ErrExpired
+	}
+	return link.LongURL, nil
+}
+
+// Возвращает запись о ссылке, в том числе с истёкшим сроком действия
+func (s *FileStorage) GetLink(_ context.Context, shortURL string) (model.Link, error) {
+	var link model.Link
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var ok bool
+		var err error
+		if link, ok, err = newFileTx(tx).get(shortURL); err == nil && !ok {
+			return storage.ErrNotFound
+		}
+		return err
+	})
+	return link, err
+}
+
+// Код с истёкшим сроком действия перезаписывается новой ссылкой. Ссылка, уже сохранённая
+// под другим кодом (в том числе истёкшим), - storage.ErrAlreadyExists, как нарушение UNIQUE(long_url)
+func (s *FileStorage) Insert(_ context.Context, link model.Link) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		t := newFileTx(tx)
+		now := time.Now()
+		old, ok, err := t.get(link.ShortURL)
+		if err != nil {
+			return err
+		}
+		if ok && !expired(old, now) {
+			return storage.ErrAlreadyExists
+		}
+		if code, found := t.codeOf(link.LongURL); found && code != link.ShortURL {
+			return storage.ErrAlreadyExists
+		}
+		if ok {
+			if err := t.delete(old); err != nil {
+				return err
+			}
+		}
+		link.CreatedAt, link.UpdatedAt = now, now
+		return t.create(link)
+	})
+}
+
+// Как DataBaseStorage.Allocate: истёкшие записи с тем же кодом или той же длинной ссылкой удаляются,
+// для уже сохранённой ссылки возвращается её код, даже если он отличается от link.ShortURL
+func (s *FileStorage) Allocate(_ context.Context, link model.Link) (string, bool, error) {
+	var shortURL string
+	var exists bool
+	err := s.db.Update(func(tx *bolt.Tx) (err error) {
+		shortURL, exists, err = newFileTx(tx).allocate(link, time.Now())
+		return err
+	})
+	if err != nil {
+		return "", false, err
+	}
+	return shortURL, exists, nil
+}
+
+// Весь пакет занимается одной транзакцией
+func (s *FileStorage) AllocateBatch(_ context.Context, links []model.Link) ([]model.Allocation, error) {
+	res := make([]model.Allocation, len(links))
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		t := newFileTx(tx)
+		now := time.Now()
+		for i, link := range links {
+			shortURL, exists, err := t.allocate(link, now)
+			if err != nil && err != storage.ErrAlreadyExists {
+				return err
+			}
+			res[i] = model.Allocation{ShortURL: shortURL, Exists: exists, Err: err}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Занятые коды и уже сохранённые ссылки (в том числе истёкшие) не перезаписываются и отмечаются
+// storage.ErrAlreadyExists, все новые записи сохраняются одной транзакцией
+func (s *FileStorage) InsertBatch(_ context.Context, links []model.Link) ([]error, error) {
+	errs := make([]error, len(links))
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		t := newFileTx(tx)
+		now := time.Now()
+		for i, link := range links {
+			_, taken := t.codeOf(link.LongURL)
+			if taken || t.links.Get([]byte(link.ShortURL)) != nil {
+				errs[i] = storage.ErrAlreadyExists
+				continue
+			}
+			link.CreatedAt, link.UpdatedAt = now, now
+			if err := t.create(link); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return errs, nil
+}
+
+// Новая длинная ссылка, уже сохранённая под другим кодом, - storage.ErrAlreadyExists
+func (s *FileStorage) Update(_ context.Context, link model.Link) (model.Link, error) {
+	var res model.Link
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		t := newFileTx(tx)
+		old, ok, err := t.get(link.ShortURL)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return storage.ErrNotFound
+		}
+		if !owns(old, link.Owner) {
+			return storage.ErrForbidden
+		}
+		if code, found := t.codeOf(link.LongURL); found && code != link.ShortURL {
+			return storage.ErrAlreadyExists
+		}
+		if err := t.delete(old); err != nil {
+			return err
+		}
+		res = updated(old, link)
+		return t.put(res)
+	})
+	if err != nil {
+		return model.Link{}, err
+	}
+	return res, nil
+}
+
+func (s *FileStorage) Delete(_ context.Context, shortURL, owner string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		t := newFileTx(tx)
+		link, ok, err := t.get(shortURL)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return storage.ErrNotFound
+		}
+		if !owns(link, owner) {
+			return storage.ErrForbidden
+		}
+		return t.remove(link)
+	})
+}
+
+// Освобождённые страницы файла BoltDB переиспользуются следующими записями
+func (s *FileStorage) DeleteExpired(_ context.Context, now time.Time) (int64, error) {
+	var deleted int64
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		t := newFileTx(tx)
+		var links []model.Link
+		err := t.links.ForEach(func(_, value []byte) error {
+			var rec fileRecord
+			if err := json.Unmarshal(value, &rec); err != nil {
+				return err
+			}
+			if link := rec.link(); expired(link, now) {
+				links = append(links, link)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		// Изменять бакет во время ForEach нельзя, поэтому записи удаляются после обхода
+		for _, link := range links {
+			if err := t.remove(link); err != nil {
+				return err
+			}
+		}
+		deleted = int64(len(links))
+		return nil
+	})
+	return deleted, err
+}
+
+func (s *FileStorage) Close() error {
+	return s.db.Close()
+}
+
+func newFileRecord(link model.Link) fileRecord {
+	return fileRecord{
+		Op:        fileOpPut,
+		ShortURL:  link.ShortURL,
+		LongURL:   link.LongURL,
+		Owner:     link.Owner,
+		ExpiresAt: nullTime(link.ExpiresAt),
+		CreatedAt: link.CreatedAt,
+		UpdatedAt: link.UpdatedAt,
+	}
+}
+
+func (rec fileRecord) link() model.Link {
+	link := model.Link{
+		ShortURL:  rec.ShortURL,
+		LongURL:   rec.LongURL,
+		Owner:     rec.Owner,
+		CreatedAt: rec.CreatedAt,
+		UpdatedAt: rec.UpdatedAt,
+	}
+	if rec.ExpiresAt != nil {
+		link.ExpiresAt = *rec.ExpiresAt
+	}
+	return link
+}
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"url-shortener/internal/model"
+	"url-shortener/pkg/storage"
+)
+
+type CacheKeyStorage struct {
+	keys map[string]model.APIKey // Ключи по идентификатору
+	sync.Mutex
+}
+
+func NewCacheKeyStorage() *CacheKeyStorage {
+	return &CacheKeyStorage{keys: make(map[string]model.APIKey)}
+}
+
+func (s *CacheKeyStorage) InsertKey(_ context.Context, key model.APIKey) error {
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+	if _, ok := s.keys[key.ID]; ok {
+		return storage.ErrAlreadyExists
+	}
+	s.keys[key.ID] = key
+	return nil
+}
+
+func (s *CacheKeyStorage) GetKeyByHash(_ context.Context, hash string) (model.APIKey, error) {
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+	for _, key := range s.keys {
+		if key.Hash == hash {
+			return key, nil
+		}
+	}
+	return model.APIKey{}, storage.ErrKeyNotFound
+}
+
+func (s *CacheKeyStorage) ListKeys(_ context.Context) ([]model.APIKey, error) {
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+	keys := make([]model.APIKey, 0, len(s.keys))
+	for _, key := range s.keys {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].CreatedAt.Before(keys[j].CreatedAt) })
+	return keys, nil
+}
+
+func (s *CacheKeyStorage) RevokeKey(_ context.Context, id string, revokedAt time.Time) error {
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+	key, ok := s.keys[id]
+	if !ok {
+		return storage.ErrKeyNotFound
+	}
+	if key.RevokedAt == nil {
+		key.RevokedAt = &revokedAt
+		s.keys[id] = key
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"url-shortener/internal/model"
+	"url-shortener/pkg/storage"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Хранилище API-ключей в файле FileStorage: записи по идентификатору и индекс хэш ключа -> идентификатор
+type FileKeyStorage struct {
+	db *bolt.DB
+}
+
+type fileAPIKey struct {
+	Name      string     `json:"name"`
+	Hash      string     `json:"hash"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+func NewFileKeyStorage(s *FileStorage) *FileKeyStorage {
+	return &FileKeyStorage{db: s.db}
+}
+
+func (s *FileKeyStorage) InsertKey(_ context.Context, key model.APIKey) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		keys, hashes := tx.Bucket(fileKeysBucket), tx.Bucket(fileKeyHashesBucket)
+		if keys.Get([]byte(key.ID)) != nil || hashes.Get([]byte(key.Hash)) != nil {
+			return storage.ErrAlreadyExists
+		}
+		if err := putFileKey(keys, key); err != nil {
+			return err
+		}
+		return hashes.Put([]byte(key.Hash), []byte(key.ID))
+	})
+}
+
+func (s *FileKeyStorage) GetKeyByHash(_ context.Context, hash string) (model.APIKey, error) {
+	var key model.APIKey
+	err := s.db.View(func(tx *bolt.Tx) error {
+		id := tx.Bucket(fileKeyHashesBucket).Get([]byte(hash))
+		if id == nil {
+			return storage.ErrKeyNotFound
+		}
+		var err error
+		key, err = getFileKey(tx.Bucket(fileKeysBucket), string(id))
+		return err
+	})
+	return key, err
+}
+
+func (s *FileKeyStorage) ListKeys(_ context.Context) ([]model.APIKey, error) {
+	keys := make([]model.APIKey, 0)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(fileKeysBucket).ForEach(func(id, value []byte) error {
+			key, err := decodeFileKey(string(id), value)
+			if err != nil {
+				return err
+			}
+			keys = append(keys, key)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].CreatedAt.Before(keys[j].CreatedAt) })
+	return keys, nil
+}
+
+// Повторный отзыв не меняет время первого
+func (s *FileKeyStorage) RevokeKey(_ context.Context, id string, revokedAt time.Time) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		keys := tx.Bucket(fileKeysBucket)
+		key, err := getFileKey(keys, id)
+		if err != nil || key.RevokedAt != nil {
+			return err
+		}
+		key.RevokedAt = &revokedAt
+		return putFileKey(keys, key)
+	})
+}
+
+func getFileKey(keys *bolt.Bucket, id string) (model.APIKey, error) {
+	value := keys.Get([]byte(id))
+	if value == nil {
+		return model.APIKey{}, storage.ErrKeyNotFound
+	}
+	return decodeFileKey(id, value)
+}
+
+func putFileKey(keys *bolt.Bucket, key model.APIKey) error {
+	value, err := json.Marshal(fileAPIKey{Name: key.Name, Hash: key.Hash, CreatedAt: key.CreatedAt, RevokedAt: key.RevokedAt})
+	if err != nil {
+		return err
+	}
+	return keys.Put([]byte(key.ID), value)
+}
+
+func decodeFileKey(id string, value []byte) (model.APIKey, error) {
+	var rec fileAPIKey
+	if err := json.Unmarshal(value, &rec); err != nil {
+		return model.APIKey{}, err
+	}
+	return model.APIKey{ID: id, Name: rec.Name, Hash: rec.Hash, CreatedAt: rec.CreatedAt, RevokedAt: rec.RevokedAt}, nil
+}
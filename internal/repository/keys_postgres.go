@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"url-shortener/internal/model"
+	"url-shortener/pkg/storage"
+	"url-shortener/pkg/storage/postgres"
+)
+
+type DataBaseKeyStorage struct {
+	pool *postgres.Pool
+}
+
+func NewDataBaseKeyStorage(pool *postgres.Pool) *DataBaseKeyStorage {
+	return &DataBaseKeyStorage{pool: pool}
+}
+
+func (s *DataBaseKeyStorage) InsertKey(ctx context.Context, key model.APIKey) error {
+	query := "INSERT INTO api_keys (id, name, key_hash, created_at) VALUES ($1, $2, $3, $4)"
+	_, err := s.pool.Exec(ctx, query, key.ID, key.Name, key.Hash, key.CreatedAt)
+	if postgres.IsDuplicateError(err) {
+		return storage.ErrAlreadyExists
+	}
+	return err
+}
+
+func (s *DataBaseKeyStorage) GetKeyByHash(ctx context.Context, hash string) (model.APIKey, error) {
+	var key model.APIKey
+	query := "SELECT id, name, key_hash, created_at, revoked_at FROM api_keys WHERE key_hash = $1"
+	err := s.pool.QueryRow(ctx, query, hash).Scan(&key.ID, &key.Name, &key.Hash, &key.CreatedAt, &key.RevokedAt)
+	if err == postgres.ErrNotFound {
+		return model.APIKey{}, storage.ErrKeyNotFound
+	}
+	return key, err
+}
+
+func (s *DataBaseKeyStorage) ListKeys(ctx context.Context) ([]model.APIKey, error) {
+	rows, err := s.pool.Query(ctx, "SELECT id, name, key_hash, created_at, revoked_at FROM api_keys ORDER BY created_at")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make([]model.APIKey, 0)
+	for rows.Next() {
+		var key model.APIKey
+		if err := rows.Scan(&key.ID, &key.Name, &key.Hash, &key.CreatedAt, &key.RevokedAt); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+func (s *DataBaseKeyStorage) RevokeKey(ctx context.Context, id string, revokedAt time.Time) error {
+	query := "UPDATE api_keys SET revoked_at = COALESCE(revoked_at, $2) WHERE id = $1"
+	tag, err := s.pool.Exec(ctx, query, id, revokedAt)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return storage.ErrKeyNotFound
+	}
+	return nil
+}
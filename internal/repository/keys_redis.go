@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"url-shortener/internal/model"
+	"url-shortener/pkg/storage"
+	"url-shortener/pkg/storage/redis"
+)
+
+// Хэши Redis с API-ключами: идентификатор -> JSON-запись и хэш ключа -> идентификатор
+const (
+	redisKeysKey      = "api_keys"
+	redisKeyHashesKey = "api_key_hashes"
+)
+
+// Хранилище API-ключей в Redis рядом со ссылками RedisStorage
+type RedisKeyStorage struct {
+	client *redis.Client
+}
+
+type redisAPIKey struct {
+	Name      string     `json:"name"`
+	Hash      string     `json:"hash"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+func NewRedisKeyStorage(client *redis.Client) *RedisKeyStorage {
+	return &RedisKeyStorage{client: client}
+}
+
+// Запись и индекс по хэшу добавляются одной транзакцией
+func (s *RedisKeyStorage) InsertKey(ctx context.Context, key model.APIKey) error {
+	_, err := s.transaction(ctx, func(tx *redis.Tx) ([][]string, error) {
+		if _, err := tx.Do("WATCH", redisKeysKey, redisKeyHashesKey); err != nil {
+			return nil, err
+		}
+		for _, exists := range [][]string{{"HEXISTS", redisKeysKey, key.ID}, {"HEXISTS", redisKeyHashesKey, key.Hash}} {
+			reply, err := tx.Do(exists...)
+			if err != nil {
+				return nil, err
+			}
+			if reply == int64(1) {
+				return nil, storage.ErrAlreadyExists
+			}
+		}
+		return [][]string{
+			{"HSET", redisKeysKey, key.ID, encodeRedisKey(key)},
+			{"HSET", redisKeyHashesKey, key.Hash, key.ID},
+		}, nil
+	})
+	return err
+}
+
+func (s *RedisKeyStorage) GetKeyByHash(ctx context.Context, hash string) (model.APIKey, error) {
+	reply, err := s.client.Do(ctx, "HGET", redisKeyHashesKey, hash)
+	if err != nil {
+		return model.APIKey{}, err
+	}
+	id, ok := reply.(string)
+	if !ok {
+		return model.APIKey{}, storage.ErrKeyNotFound
+	}
+	if reply, err = s.client.Do(ctx, "HGET", redisKeysKey, id); err != nil {
+		return model.APIKey{}, err
+	}
+	if reply == nil {
+		return model.APIKey{}, storage.ErrKeyNotFound
+	}
+	return decodeRedisKey(id, reply)
+}
+
+func (s *RedisKeyStorage) ListKeys(ctx context.Context) ([]model.APIKey, error) {
+	reply, err := s.client.Do(ctx, "HGETALL", redisKeysKey)
+	if err != nil {
+		return nil, err
+	}
+	items, _ := reply.([]interface{})
+	keys := make([]model.APIKey, 0, len(items)/2)
+	for i := 0; i+1 < len(items); i += 2 {
+		id, _ := items[i].(string)
+		key, err := decodeRedisKey(id, items[i+1])
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].CreatedAt.Before(keys[j].CreatedAt) })
+	return keys, nil
+}
+
+// Повторный отзыв не меняет время первого
+func (s *RedisKeyStorage) RevokeKey(ctx context.Context, id string, revokedAt time.Time) error {
+	_, err := s.transaction(ctx, func(tx *redis.Tx) ([][]string, error) {
+		if _, err := tx.Do("WATCH", redisKeysKey); err != nil {
+			return nil, err
+		}
+		reply, err := tx.Do("HGET", redisKeysKey, id)
+		if err != nil {
+			return nil, err
+		}
+		if reply == nil {
+			return nil, storage.ErrKeyNotFound
+		}
+		key, err := decodeRedisKey(id, reply)
+		if err != nil || key.RevokedAt != nil {
+			return nil, err
+		}
+		key.RevokedAt = &revokedAt
+		return [][]string{{"HSET", redisKeysKey, id, encodeRedisKey(key)}}, nil
+	})
+	return err
+}
+
+func (s *RedisKeyStorage) transaction(ctx context.Context, fn func(tx *redis.Tx) ([][]string, error)) ([]interface{}, error) {
+	return redisTransaction(ctx, s.client, fn)
+}
+
+func encodeRedisKey(key model.APIKey) string {
+	data, _ := json.Marshal(redisAPIKey{Name: key.Name, Hash: key.Hash, CreatedAt: key.CreatedAt, RevokedAt: key.RevokedAt})
+	return string(data)
+}
+
+func decodeRedisKey(id string, reply interface{}) (model.APIKey, error) {
+	value, _ := reply.(string)
+	var rec redisAPIKey
+	if err := json.Unmarshal([]byte(value), &rec); err != nil {
+		return model.APIKey{}, err
+	}
+	return model.APIKey{ID: id, Name: rec.Name, Hash: rec.Hash, CreatedAt: rec.CreatedAt, RevokedAt: rec.RevokedAt}, nil
+}
@@ -2,9 +2,14 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"time"
 
+	"url-shortener/internal/model"
 	"url-shortener/pkg/storage"
 	"url-shortener/pkg/storage/postgres"
+
+	"github.com/jackc/pgx/v5"
 )
 
 type DataBaseStorage struct {
@@ -15,17 +20,287 @@ func NewDataBaseStorage(pool *postgres.Pool) *DataBaseStorage {
 	return &DataBaseStorage{pool: pool}
 }
 
-func (s *DataBaseStorage) Insert(shortURL, longURL string) error {
-	query := "INSERT INTO urls (short_url, long_url) VALUES ($1, $2) ON CONFLICT (short_url) DO NOTHING"
-	_, err := s.pool.Exec(context.Background(), query, shortURL, longURL)
-	return err
+// Код с истёкшим сроком действия занимается новой ссылкой: истёкшая запись удаляется в той же транзакции,
+// поэтому её переходы удаляются внешним ключом clicks ON DELETE CASCADE и не достаются новой ссылке
+func (s *DataBaseStorage) Insert(ctx context.Context, link model.Link) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "DELETE FROM urls WHERE short_url = $1 AND expires_at <= now()", link.ShortURL); err != nil {
+		return err
+	}
+	query := "INSERT INTO urls (short_url, long_url, expires_at, owner) VALUES ($1, $2, $3, $4) ON CONFLICT (short_url) DO NOTHING"
+	tag, err := tx.Exec(ctx, query, link.ShortURL, link.LongURL, nullTime(link.ExpiresAt), nullString(link.Owner))
+	if postgres.IsDuplicateError(err) {
+		return storage.ErrAlreadyExists
+	}
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return storage.ErrAlreadyExists
+	}
+	return tx.Commit(ctx)
+}
+
+// Сколько раз Allocate повторяет вставку, если конфликтующая запись удалена до её чтения
+const allocateAttempts = 3
+
+// Занимает код в транзакции: истёкшие записи с тем же кодом или той же длинной ссылкой удаляются,
+// затем INSERT ... ON CONFLICT DO NOTHING RETURNING. Вставка, конфликтующая с незавершённой транзакцией,
+// ждёт её окончания, поэтому после конфликта запись-победитель уже видна. Длинная ссылка уникальна,
+// поэтому для уже сохранённой ссылки возвращается её код, даже если он отличается от link.ShortURL
+func (s *DataBaseStorage) Allocate(ctx context.Context, link model.Link) (string, bool, error) {
+	for attempt := 0; attempt < allocateAttempts; attempt++ {
+		shortURL, exists, err := s.allocate(ctx, link)
+		if err != errAllocateRetry {
+			return shortURL, exists, err
+		}
+	}
+	return "", false, storage.ErrAlreadyExists
 }
 
-func (s *DataBaseStorage) GetLongUrl(shortURL string) (string, error) {
+var errAllocateRetry = errors.New("conflicting row disappeared")
+
+func (s *DataBaseStorage) allocate(ctx context.Context, link model.Link) (string, bool, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return "", false, err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, "DELETE FROM urls WHERE (short_url = $1 OR long_url = $2) AND expires_at <= now()", link.ShortURL, link.LongURL)
+	if err != nil {
+		return "", false, err
+	}
+	query := `INSERT INTO urls (short_url, long_url, expires_at, owner) VALUES ($1, $2, $3, $4)
+		ON CONFLICT DO NOTHING RETURNING short_url`
+	var shortURL string
+	err = tx.QueryRow(ctx, query, link.ShortURL, link.LongURL, nullTime(link.ExpiresAt), nullString(link.Owner)).Scan(&shortURL)
+	if err == nil {
+		return shortURL, false, tx.Commit(ctx)
+	}
+	if err != postgres.ErrNotFound {
+		return "", false, err
+	}
+
+	// Конфликт по коду или по длинной ссылке: запись с той же ссылкой важнее
 	var longURL string
-	err := s.pool.QueryRow(context.Background(), "SELECT long_url FROM urls WHERE short_url = $1", shortURL).Scan(&longURL)
+	query = `SELECT short_url, long_url FROM urls WHERE short_url = $1 OR long_url = $2
+		ORDER BY long_url = $2 DESC LIMIT 1`
+	err = tx.QueryRow(ctx, query, link.ShortURL, link.LongURL).Scan(&shortURL, &longURL)
+	if err == postgres.ErrNotFound {
+		return "", false, errAllocateRetry
+	}
+	if err != nil {
+		return "", false, err
+	}
+	if longURL != link.LongURL {
+		return "", false, storage.ErrAlreadyExists
+	}
+	return shortURL, true, tx.Commit(ctx)
+}
+
+// Занимает коды всего пакета за один сетевой обмен: пакет выполняется в неявной транзакции,
+// в которой удаляются истёкшие записи с кодами или ссылками пакета, каждая ссылка вставляется
+// INSERT ... ON CONFLICT DO NOTHING RETURNING, а последний запрос читает записи ссылок, вставка которых
+// не удалась. Для уже сохранённой ссылки возвращается её код, код, занятый другой ссылкой, -
+// storage.ErrAlreadyExists
+func (s *DataBaseStorage) AllocateBatch(ctx context.Context, links []model.Link) ([]model.Allocation, error) {
+	codes := make([]string, len(links))
+	longURLs := make([]string, len(links))
+	for i, link := range links {
+		codes[i], longURLs[i] = link.ShortURL, link.LongURL
+	}
+	batch := &pgx.Batch{}
+	batch.Queue("DELETE FROM urls WHERE (short_url = ANY($1) OR long_url = ANY($2)) AND expires_at <= now()", codes, longURLs)
+	query := `INSERT INTO urls (short_url, long_url, expires_at, owner) VALUES ($1, $2, $3, $4)
+		ON CONFLICT DO NOTHING RETURNING short_url`
+	for _, link := range links {
+		batch.Queue(query, link.ShortURL, link.LongURL, nullTime(link.ExpiresAt), nullString(link.Owner))
+	}
+	batch.Queue("SELECT short_url, long_url FROM urls WHERE long_url = ANY($1)", longURLs)
+
+	results := s.pool.SendBatch(ctx, batch)
+	defer results.Close()
+
+	if _, err := results.Exec(); err != nil {
+		return nil, err
+	}
+	res := make([]model.Allocation, len(links))
+	inserted := make([]bool, len(links))
+	for i := range links {
+		var shortURL string
+		err := results.QueryRow().Scan(&shortURL)
+		if err != nil && err != postgres.ErrNotFound {
+			return nil, err
+		}
+		inserted[i] = err == nil
+	}
+	rows, err := results.Query()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	stored := make(map[string]string)
+	for rows.Next() {
+		var shortURL, longURL string
+		if err := rows.Scan(&shortURL, &longURL); err != nil {
+			return nil, err
+		}
+		stored[longURL] = shortURL
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Повтор ссылки, вставленной раньше в том же пакете, получает её код с Exists
+	for i, link := range links {
+		switch code, ok := stored[link.LongURL]; {
+		case inserted[i]:
+			res[i] = model.Allocation{ShortURL: link.ShortURL}
+		case ok:
+			res[i] = model.Allocation{ShortURL: code, Exists: true}
+		default:
+			res[i] = model.Allocation{Err: storage.ErrAlreadyExists}
+		}
+	}
+	return res, results.Close()
+}
+
+// Отправляет все вставки за один сетевой обмен. Пакет выполняется в неявной транзакции,
+// поэтому любая ошибка, кроме занятого кода, отменяет весь пакет и возвращается общей ошибкой.
+// Занятые коды (в том числе истёкшие) не перезаписываются и отмечаются storage.ErrAlreadyExists
+func (s *DataBaseStorage) InsertBatch(ctx context.Context, links []model.Link) ([]error, error) {
+	query := "INSERT INTO urls (short_url, long_url, expires_at, owner) VALUES ($1, $2, $3, $4) ON CONFLICT DO NOTHING"
+	batch := &pgx.Batch{}
+	for _, link := range links {
+		batch.Queue(query, link.ShortURL, link.LongURL, nullTime(link.ExpiresAt), nullString(link.Owner))
+	}
+
+	results := s.pool.SendBatch(ctx, batch)
+	defer results.Close()
+
+	errs := make([]error, len(links))
+	for i := range links {
+		tag, err := results.Exec()
+		if err != nil {
+			return nil, err
+		}
+		if tag.RowsAffected() == 0 {
+			errs[i] = storage.ErrAlreadyExists
+		}
+	}
+	return errs, results.Close()
+}
+
+func (s *DataBaseStorage) GetLongUrl(ctx context.Context, shortURL string) (string, error) {
+	var longURL string
+	var expiresAt *time.Time
+	err := s.pool.QueryRow(ctx, "SELECT long_url, expires_at FROM urls WHERE short_url = $1", shortURL).Scan(&longURL, &expiresAt)
 	if err == postgres.ErrNotFound {
 		return "", storage.ErrNotFound
 	}
+	if err == nil && expiresAt != nil && !expiresAt.After(time.Now()) {
+		return "", storage.ErrExpired
+	}
 	return longURL, err
 }
+
+// Возвращает запись о ссылке, в том числе с истёкшим сроком действия
+func (s *DataBaseStorage) GetLink(ctx context.Context, shortURL string) (model.Link, error) {
+	query := "SELECT short_url, long_url, expires_at, owner, created_at, updated_at FROM urls WHERE short_url = $1"
+	link, err := scanLink(s.pool.QueryRow(ctx, query, shortURL))
+	if err == postgres.ErrNotFound {
+		return model.Link{}, storage.ErrNotFound
+	}
+	return link, err
+}
+
+// Владелец проверяется условием того же UPDATE. Новая длинная ссылка, уже сохранённая под другим кодом,
+// нарушает UNIQUE(long_url) и возвращается как storage.ErrAlreadyExists. Срок model.NoExpiry сбрасывает expires_at в NULL
+func (s *DataBaseStorage) Update(ctx context.Context, link model.Link) (model.Link, error) {
+	query := `UPDATE urls SET long_url = COALESCE(NULLIF($2, ''), long_url),
+			expires_at = CASE WHEN $5 THEN NULL ELSE COALESCE($3, expires_at) END, updated_at = now()
+		WHERE short_url = $1 AND ($4::text = '' OR owner = $4)
+		RETURNING short_url, long_url, expires_at, owner, created_at, updated_at`
+	clear := link.ExpiresAt.Equal(model.NoExpiry)
+	if clear {
+		link.ExpiresAt = time.Time{}
+	}
+	res, err := scanLink(s.pool.QueryRow(ctx, query, link.ShortURL, link.LongURL, nullTime(link.ExpiresAt), link.Owner, clear))
+	if postgres.IsDuplicateError(err) {
+		return model.Link{}, storage.ErrAlreadyExists
+	}
+	if err == postgres.ErrNotFound {
+		return model.Link{}, s.missing(ctx, link.ShortURL)
+	}
+	return res, err
+}
+
+// Переходы удаляются вместе со ссылкой внешним ключом clicks ON DELETE CASCADE
+func (s *DataBaseStorage) Delete(ctx context.Context, shortURL, owner string) error {
+	tag, err := s.pool.Exec(ctx, "DELETE FROM urls WHERE short_url = $1 AND ($2::text = '' OR owner = $2)", shortURL, owner)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return s.missing(ctx, shortURL)
+	}
+	return nil
+}
+
+// Причина, по которой изменение с условием владельца не затронуло ни одной строки:
+// storage.ErrForbidden, если ссылка есть, и storage.ErrNotFound, если нет
+func (s *DataBaseStorage) missing(ctx context.Context, shortURL string) error {
+	var exists bool
+	if err := s.pool.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM urls WHERE short_url = $1)", shortURL).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		return storage.ErrForbidden
+	}
+	return storage.ErrNotFound
+}
+
+func (s *DataBaseStorage) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	tag, err := s.pool.Exec(ctx, "DELETE FROM urls WHERE expires_at <= $1", now)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+func scanLink(row pgx.Row) (model.Link, error) {
+	var link model.Link
+	var expiresAt *time.Time
+	var owner *string
+	err := row.Scan(&link.ShortURL, &link.LongURL, &expiresAt, &owner, &link.CreatedAt, &link.UpdatedAt)
+	if err != nil {
+		return model.Link{}, err
+	}
+	if expiresAt != nil {
+		link.ExpiresAt = *expiresAt
+	}
+	if owner != nil {
+		link.Owner = *owner
+	}
+	return link, nil
+}
+
+func nullTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func nullString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"url-shortener/internal/service"
+)
+
+const rateLimitCleanupInterval = time.Minute
+
+type CacheRateLimitStore struct {
+	tats        map[string]time.Time
+	lastCleanup time.Time
+	sync.Mutex
+}
+
+func NewCacheRateLimitStore() *CacheRateLimitStore {
+	return &CacheRateLimitStore{tats: make(map[string]time.Time)}
+}
+
+func (s *CacheRateLimitStore) Take(_ context.Context, key string, policy service.RateLimitPolicy, cost int, now time.Time) (service.RateLimitResult, error) {
+	s.Mutex.Lock()
+	defer s.Mutex.Unlock()
+	s.cleanup(now)
+
+	tat, res := service.ApplyGCRA(s.tats[key], now, policy, cost)
+	s.tats[key] = tat
+	return res, nil
+}
+
+// Удаляет ключи, лимит которых уже восстановился полностью
+func (s *CacheRateLimitStore) cleanup(now time.Time) {
+	if now.Sub(s.lastCleanup) < rateLimitCleanupInterval {
+		return
+	}
+	s.lastCleanup = now
+	for key, tat := range s.tats {
+		if tat.Before(now) {
+			delete(s.tats, key)
+		}
+	}
+}
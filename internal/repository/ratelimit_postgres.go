@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"url-shortener/internal/service"
+	"url-shortener/pkg/storage/postgres"
+)
+
+// Хранит состояние лимитов в Postgres, чтобы несколько экземпляров сервиса делили общие квоты
+type DataBaseRateLimitStore struct {
+	pool        *postgres.Pool
+	lastCleanup time.Time
+	mu          sync.Mutex
+}
+
+func NewDataBaseRateLimitStore(pool *postgres.Pool) *DataBaseRateLimitStore {
+	return &DataBaseRateLimitStore{pool: pool}
+}
+
+// Запрос списывается одним атомарным UPSERT без явной транзакции: tat сдвигается на cost интервалов политики,
+// только если результат укладывается в допустимый запас. Для отклонённого запроса текущее tat
+// читается отдельно и используется лишь для заголовков ответа
+func (s *DataBaseRateLimitStore) Take(ctx context.Context, key string, policy service.RateLimitPolicy, cost int, now time.Time) (service.RateLimitResult, error) {
+	s.cleanup(ctx, now)
+
+	interval := policy.Interval()
+	step := time.Duration(cost) * interval
+	tolerance := time.Duration(policy.Burst) * interval
+	query := `INSERT INTO rate_limits (key, tat) VALUES ($1, $2::timestamptz + $3 * interval '1 microsecond')
+		ON CONFLICT (key) DO UPDATE SET tat = greatest(rate_limits.tat, $2) + $3 * interval '1 microsecond'
+		WHERE greatest(rate_limits.tat, $2) + $3 * interval '1 microsecond' <= $2::timestamptz + $4 * interval '1 microsecond'
+		RETURNING tat`
+	var tat time.Time
+	err := s.pool.QueryRow(ctx, query, key, now, step.Microseconds(), tolerance.Microseconds()).Scan(&tat)
+	if err == nil {
+		// Разрешённый запрос: повторяем расчёт от состояния до списания
+		_, res := service.ApplyGCRA(tat.Add(-step), now, policy, cost)
+		return res, nil
+	}
+	if err != postgres.ErrNotFound {
+		return service.RateLimitResult{}, err
+	}
+	if err := s.pool.QueryRow(ctx, "SELECT tat FROM rate_limits WHERE key = $1", key).Scan(&tat); err != nil {
+		return service.RateLimitResult{}, err
+	}
+	_, res := service.ApplyGCRA(tat, now, policy, cost)
+	res.Allowed = false
+	return res, nil
+}
+
+// Удаляет строки, лимит которых уже восстановился полностью
+func (s *DataBaseRateLimitStore) cleanup(ctx context.Context, now time.Time) {
+	s.mu.Lock()
+	if now.Sub(s.lastCleanup) < rateLimitCleanupInterval {
+		s.mu.Unlock()
+		return
+	}
+	s.lastCleanup = now
+	s.mu.Unlock()
+	s.pool.Exec(ctx, "DELETE FROM rate_limits WHERE tat < $1", now)
+}
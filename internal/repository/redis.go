@@ -0,0 +1,506 @@
+package repository
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"url-shortener/internal/model"
+	"url-shortener/pkg/storage"
+	"url-shortener/pkg/storage/redis"
+)
+
+const (
+	redisKeyPrefix     = "url:"
+	redisLongURLPrefix = "long:" // Индекс длинной ссылки: long:<SHA-256 ссылки> -> код
+)
+
+// Сколько раз повторяется транзакция, наблюдаемые ключи которой изменил параллельный запрос
+const redisTxAttempts = 32
+
+// Сколько истёкшая ссылка хранится после срока действия, отвечая storage.ErrExpired, прежде чем её удалит сервер
+const redisExpiredRetention = 24 * time.Hour
+
+// Ссылки хранятся строковыми ключами url:<код> с JSON-записью в значении, а индекс long:<хэш ссылки> -> код
+// повторяет ограничение UNIQUE(long_url) таблицы urls. Запись и индекс меняются вместе в транзакции
+// WATCH/MULTI/EXEC, которая повторяется, если ключи изменил параллельный запрос. SET NX здесь недостаточно:
+// он занимает только один ключ, а запись и индекс должны появиться вместе, и не позволяет перезаписать истёкший код.
+// Истёкшие ссылки удаляет сам сервер по TTL обоих ключей, но только через redisExpiredRetention после
+// срока действия: до этого GetLongUrl возвращает для них storage.ErrExpired, как другие хранилища до очистки.
+// Как и в FileStorage, Insert и Allocate перезаписывают истёкший код, InsertBatch - нет
+type RedisStorage struct {
+	client *redis.Client
+}
+
+type redisLink struct {
+	LongURL   string     `json:"long_url"`
+	Owner     string     `json:"owner,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+func NewRedisStorage(client *redis.Client) *RedisStorage {
+	return &RedisStorage{client: client}
+}
+
+func (s *RedisStorage) GetLongUrl(ctx context.Context, shortURL string) (string, error) {
+	link, err := s.GetLink(ctx, shortURL)
+	if err != nil {
+		return "", err
+	}
+	if expired(link, time.Now()) {
+		return "", storage.ErrExpired
+	}
+	return link.LongURL, nil
+}
+
+// Возвращает запись о ссылке, в том числе с истёкшим сроком действия
+func (s *RedisStorage) GetLink(ctx context.Context, shortURL string) (model.Link, error) {
+	reply, err := s.client.Do(ctx, "GET", redisKeyPrefix+shortURL)
+	if err != nil {
+		return model.Link{}, err
+	}
+	if reply == nil {
+		return model.Link{}, storage.ErrNotFound
+	}
+	return decodeRedisLink(shortURL, reply)
+}
+
+// Код с истёкшим сроком действия перезаписывается новой ссылкой. Занятый код или ссылка,
+// уже сохранённая под другим кодом (в том числе истёкшим), - storage.ErrAlreadyExists
+func (s *RedisStorage) Insert(ctx context.Context, link model.Link) error {
+	now := time.Now()
+	_, err := s.transaction(ctx, func(tx *redis.Tx) ([][]string, error) {
+		old, taken, err := watchLink(tx, link.ShortURL)
+		if err != nil {
+			return nil, err
+		}
+		code, found, err := watchCode(tx, link.LongURL)
+		if err != nil {
+			return nil, err
+		}
+		if (taken && !expired(old, now)) || (found && code != link.ShortURL) {
+			return nil, storage.ErrAlreadyExists
+		}
+		link.CreatedAt, link.UpdatedAt = now, now
+		cmds := createCommands(link)
+		if taken && old.LongURL != link.LongURL {
+			unindex, err := unindexCommand(tx, old)
+			if err != nil {
+				return nil, err
+			}
+			cmds = append(cmds, unindex...)
+		}
+		return cmds, nil
+	})
+	return err
+}
+
+// Как FileStorage.Allocate: истёкшие записи с тем же кодом или той же длинной ссылкой перезаписываются,
+// для уже сохранённой ссылки возвращается её код, даже если он отличается от link.ShortURL.
+// Код, занятый другой ссылкой, - storage.ErrAlreadyExists
+func (s *RedisStorage) Allocate(ctx context.Context, link model.Link) (string, bool, error) {
+	now := time.Now()
+	var shortURL string
+	var exists bool
+	_, err := s.transaction(ctx, func(tx *redis.Tx) ([][]string, error) {
+		var cmds [][]string
+		code, found, err := watchCode(tx, link.LongURL)
+		if err != nil {
+			return nil, err
+		}
+		if found && code != link.ShortURL {
+			current, ok, err := watchLink(tx, code)
+			if err != nil {
+				return nil, err
+			}
+			if ok && !expired(current, now) {
+				shortURL, exists = code, true
+				return nil, nil
+			}
+			// Истёкшая запись удаляется, индекс перезапишет новая
+			cmds = append(cmds, []string{"DEL", redisKeyPrefix + code, redisClicksPrefix + code})
+		}
+		old, taken, err := watchLink(tx, link.ShortURL)
+		if err != nil {
+			return nil, err
+		}
+		if taken && !expired(old, now) {
+			// Параллельный запрос мог занять код той же ссылкой после чтения индекса
+			if old.LongURL == link.LongURL {
+				shortURL, exists = link.ShortURL, true
+				return nil, nil
+			}
+			return nil, storage.ErrAlreadyExists
+		}
+		if taken && old.LongURL != link.LongURL {
+			unindex, err := unindexCommand(tx, old)
+			if err != nil {
+				return nil, err
+			}
+			cmds = append(cmds, unindex...)
+		}
+		shortURL, exists = link.ShortURL, false
+		link.CreatedAt, link.UpdatedAt = now, now
+		return append(cmds, createCommands(link)...), nil
+	})
+	if err != nil {
+		return "", false, err
+	}
+	return shortURL, exists, nil
+}
+
+// Как Allocate для каждой ссылки пакета: записи кодов и индексы ссылок пакета читаются за один сетевой обмен,
+// второй нужен, только если ссылка сохранена под другим кодом или код занят истёкшей записью.
+// Все изменения сохраняются одной транзакцией
+func (s *RedisStorage) AllocateBatch(ctx context.Context, links []model.Link) ([]model.Allocation, error) {
+	now := time.Now()
+	res := make([]model.Allocation, len(links))
+	_, err := s.transaction(ctx, func(tx *redis.Tx) ([][]string, error) {
+		st := redisBatchState{tx: tx, links: make(map[string]*model.Link), codes: make(map[string]string)}
+		codes := make([]string, len(links))
+		longURLs := make([]string, len(links))
+		for i, link := range links {
+			codes[i], longURLs[i] = link.ShortURL, link.LongURL
+		}
+		if err := st.load(codes, longURLs); err != nil {
+			return nil, err
+		}
+		codes, longURLs = nil, nil
+		for _, link := range links {
+			if code := st.codes[link.LongURL]; code != "" {
+				codes = append(codes, code)
+			}
+			if old := st.links[link.ShortURL]; old != nil && expired(*old, now) {
+				longURLs = append(longURLs, old.LongURL)
+			}
+		}
+		if err := st.load(codes, longURLs); err != nil {
+			return nil, err
+		}
+
+		var cmds [][]string
+		for i, link := range links {
+			var stale string
+			if code := st.codes[link.LongURL]; code != "" && code != link.ShortURL {
+				if current := st.links[code]; current != nil && !expired(*current, now) {
+					res[i] = model.Allocation{ShortURL: code, Exists: true}
+					continue
+				}
+				stale = code
+			}
+			old := st.links[link.ShortURL]
+			if old != nil && !expired(*old, now) {
+				if old.LongURL == link.LongURL {
+					res[i] = model.Allocation{ShortURL: link.ShortURL, Exists: true}
+				} else {
+					res[i] = model.Allocation{Err: storage.ErrAlreadyExists}
+				}
+				continue
+			}
+			// Истёкшая запись той же ссылки удаляется, только если код занимается: её индекс перезапишет новая
+			if stale != "" {
+				cmds = append(cmds, []string{"DEL", redisKeyPrefix + stale, redisClicksPrefix + stale})
+				st.links[stale] = nil
+			}
+			if old != nil && old.LongURL != link.LongURL && st.codes[old.LongURL] == link.ShortURL {
+				cmds = append(cmds, []string{"DEL", redisLongURLKey(old.LongURL)})
+				st.codes[old.LongURL] = ""
+			}
+			link.CreatedAt, link.UpdatedAt = now, now
+			cmds = append(cmds, createCommands(link)...)
+			st.links[link.ShortURL], st.codes[link.LongURL] = &link, link.ShortURL
+			res[i] = model.Allocation{ShortURL: link.ShortURL}
+		}
+		return cmds, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Записи и индексы, прочитанные в транзакции AllocateBatch и изменённые её командами.
+// Отсутствующая запись - nil, отсутствующий индекс - пустой код
+type redisBatchState struct {
+	tx    *redis.Tx
+	links map[string]*model.Link
+	codes map[string]string
+}
+
+// Наблюдает и читает ещё не прочитанные записи кодов и индексы ссылок за один сетевой обмен
+func (st redisBatchState) load(codes, longURLs []string) error {
+	watch := []string{"WATCH"}
+	var reads [][]string
+	var newCodes, newLongURLs []string
+	for _, code := range codes {
+		if _, ok := st.links[code]; !ok {
+			st.links[code] = nil
+			newCodes = append(newCodes, code)
+			watch = append(watch, redisKeyPrefix+code)
+			reads = append(reads, []string{"GET", redisKeyPrefix + code})
+		}
+	}
+	for _, longURL := range longURLs {
+		if _, ok := st.codes[longURL]; !ok {
+			st.codes[longURL] = ""
+			newLongURLs = append(newLongURLs, longURL)
+			watch = append(watch, redisLongURLKey(longURL))
+			reads = append(reads, []string{"GET", redisLongURLKey(longURL)})
+		}
+	}
+	if len(reads) == 0 {
+		return nil
+	}
+	replies, err := st.tx.Pipeline(append([][]string{watch}, reads...))
+	if err != nil {
+		return err
+	}
+	for _, reply := range replies {
+		if replyErr, ok := reply.(redis.Error); ok {
+			return replyErr
+		}
+	}
+	replies = replies[1:]
+	for i, code := range newCodes {
+		if replies[i] == nil {
+			continue
+		}
+		link, err := decodeRedisLink(code, replies[i])
+		if err != nil {
+			return err
+		}
+		st.links[code] = &link
+	}
+	for i, longURL := range newLongURLs {
+		code, _ := replies[len(newCodes)+i].(string)
+		st.codes[longURL] = code
+	}
+	return nil
+}
+
+// Ключи всех ссылок пакета читаются за один сетевой обмен, новые записи сохраняются одной транзакцией.
+// Занятые коды и уже сохранённые ссылки отмечаются storage.ErrAlreadyExists
+func (s *RedisStorage) InsertBatch(ctx context.Context, links []model.Link) ([]error, error) {
+	now := time.Now()
+	errs := make([]error, len(links))
+	_, err := s.transaction(ctx, func(tx *redis.Tx) ([][]string, error) {
+		watch := []string{"WATCH"}
+		reads := make([][]string, 0, 2*len(links)+1)
+		for _, link := range links {
+			watch = append(watch, redisKeyPrefix+link.ShortURL, redisLongURLKey(link.LongURL))
+			reads = append(reads, []string{"GET", redisKeyPrefix + link.ShortURL}, []string{"GET", redisLongURLKey(link.LongURL)})
+		}
+		replies, err := tx.Pipeline(append([][]string{watch}, reads...))
+		if err != nil {
+			return nil, err
+		}
+		for _, reply := range replies {
+			if replyErr, ok := reply.(redis.Error); ok {
+				return nil, replyErr
+			}
+		}
+
+		var cmds [][]string
+		codes := make(map[string]bool)
+		longURLs := make(map[string]bool)
+		for i, link := range links {
+			errs[i] = nil
+			taken := replies[1+2*i] != nil || codes[link.ShortURL]
+			found := replies[2+2*i] != nil || longURLs[link.LongURL]
+			if taken || found {
+				errs[i] = storage.ErrAlreadyExists
+				continue
+			}
+			codes[link.ShortURL], longURLs[link.LongURL] = true, true
+			link.CreatedAt, link.UpdatedAt = now, now
+			cmds = append(cmds, createCommands(link)...)
+		}
+		return cmds, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return errs, nil
+}
+
+// Новая длинная ссылка, уже сохранённая под другим кодом, - storage.ErrAlreadyExists
+func (s *RedisStorage) Update(ctx context.Context, link model.Link) (model.Link, error) {
+	var res model.Link
+	_, err := s.transaction(ctx, func(tx *redis.Tx) ([][]string, error) {
+		old, ok, err := watchLink(tx, link.ShortURL)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, storage.ErrNotFound
+		}
+		if !owns(old, link.Owner) {
+			return nil, storage.ErrForbidden
+		}
+		res = updated(old, link)
+		code, found, err := watchCode(tx, res.LongURL)
+		if err != nil {
+			return nil, err
+		}
+		if found && code != link.ShortURL {
+			return nil, storage.ErrAlreadyExists
+		}
+		cmds := append(putCommands(res), clicksExpiryCommand(res))
+		if old.LongURL != res.LongURL {
+			unindex, err := unindexCommand(tx, old)
+			if err != nil {
+				return nil, err
+			}
+			cmds = append(cmds, unindex...)
+		}
+		return cmds, nil
+	})
+	if err != nil {
+		return model.Link{}, err
+	}
+	return res, nil
+}
+
+func (s *RedisStorage) Delete(ctx context.Context, shortURL, owner string) error {
+	_, err := s.transaction(ctx, func(tx *redis.Tx) ([][]string, error) {
+		link, ok, err := watchLink(tx, shortURL)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, storage.ErrNotFound
+		}
+		if !owns(link, owner) {
+			return nil, storage.ErrForbidden
+		}
+		unindex, err := unindexCommand(tx, link)
+		if err != nil {
+			return nil, err
+		}
+		return append([][]string{{"DEL", redisKeyPrefix + shortURL, redisClicksPrefix + shortURL}}, unindex...), nil
+	})
+	return err
+}
+
+// Истёкшие ключи удаляет сервер через redisExpiredRetention после срока действия
+func (s *RedisStorage) DeleteExpired(_ context.Context, now time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (s *RedisStorage) transaction(ctx context.Context, fn func(tx *redis.Tx) ([][]string, error)) ([]interface{}, error) {
+	return redisTransaction(ctx, s.client, fn)
+}
+
+// Выполняет транзакцию, повторяя её, пока наблюдаемые ключи меняются параллельными запросами
+func redisTransaction(ctx context.Context, client *redis.Client, fn func(tx *redis.Tx) ([][]string, error)) ([]interface{}, error) {
+	for attempt := 1; ; attempt++ {
+		replies, err := client.Transaction(ctx, fn)
+		if err != redis.ErrTxConflict || attempt == redisTxAttempts {
+			return replies, err
+		}
+	}
+}
+
+// Наблюдает ключ записи и читает её
+func watchLink(tx *redis.Tx, shortURL string) (model.Link, bool, error) {
+	reply, err := watchGet(tx, redisKeyPrefix+shortURL)
+	if err != nil || reply == nil {
+		return model.Link{}, false, err
+	}
+	link, err := decodeRedisLink(shortURL, reply)
+	return link, err == nil, err
+}
+
+// Наблюдает индекс длинной ссылки и возвращает код, под которым она сохранена
+func watchCode(tx *redis.Tx, longURL string) (string, bool, error) {
+	reply, err := watchGet(tx, redisLongURLKey(longURL))
+	code, _ := reply.(string)
+	return code, reply != nil, err
+}
+
+func watchGet(tx *redis.Tx, key string) (interface{}, error) {
+	replies, err := tx.Pipeline([][]string{{"WATCH", key}, {"GET", key}})
+	if err != nil {
+		return nil, err
+	}
+	for _, reply := range replies {
+		if replyErr, ok := reply.(redis.Error); ok {
+			return nil, replyErr
+		}
+	}
+	return replies[1], nil
+}
+
+// Удаление индекса длинной ссылки link, если он указывает на её код
+func unindexCommand(tx *redis.Tx, link model.Link) ([][]string, error) {
+	code, found, err := watchCode(tx, link.LongURL)
+	if err != nil || !found || code != link.ShortURL {
+		return nil, err
+	}
+	return [][]string{{"DEL", redisLongURLKey(link.LongURL)}}, nil
+}
+
+// Запись ссылки и индекса её длинной ссылки с общим TTL, который истекает через redisExpiredRetention
+// после срока действия ссылки
+func putCommands(link model.Link) [][]string {
+	record := []string{"SET", redisKeyPrefix + link.ShortURL, encodeRedisLink(link)}
+	index := []string{"SET", redisLongURLKey(link.LongURL), link.ShortURL}
+	if !link.ExpiresAt.IsZero() {
+		pxat := strconv.FormatInt(link.ExpiresAt.Add(redisExpiredRetention).UnixMilli(), 10)
+		record = append(record, "PXAT", pxat)
+		index = append(index, "PXAT", pxat)
+	}
+	return [][]string{record, index}
+}
+
+// TTL счётчиков переходов после изменения срока действия ссылки совпадает с TTL её записи
+func clicksExpiryCommand(link model.Link) []string {
+	if link.ExpiresAt.IsZero() {
+		return []string{"PERSIST", redisClicksPrefix + link.ShortURL}
+	}
+	return []string{"PEXPIREAT", redisClicksPrefix + link.ShortURL, strconv.FormatInt(link.ExpiresAt.Add(redisExpiredRetention).UnixMilli(), 10)}
+}
+
+// Запись новой ссылки: счётчики переходов прежней ссылки с тем же кодом ей не достаются
+func createCommands(link model.Link) [][]string {
+	return append(putCommands(link), []string{"DEL", redisClicksPrefix + link.ShortURL})
+}
+
+// Длина ключа не зависит от длины ссылки
+func redisLongURLKey(longURL string) string {
+	return redisLongURLPrefix + hex.EncodeToString(longURLKey(longURL))
+}
+
+func encodeRedisLink(link model.Link) string {
+	data, _ := json.Marshal(redisLink{
+		LongURL:   link.LongURL,
+		Owner:     link.Owner,
+		ExpiresAt: nullTime(link.ExpiresAt),
+		CreatedAt: link.CreatedAt,
+		UpdatedAt: link.UpdatedAt,
+	})
+	return string(data)
+}
+
+func decodeRedisLink(shortURL string, reply interface{}) (model.Link, error) {
+	value, _ := reply.(string)
+	var rec redisLink
+	if err := json.Unmarshal([]byte(value), &rec); err != nil {
+		return model.Link{}, err
+	}
+	link := model.Link{
+		ShortURL:  shortURL,
+		LongURL:   rec.LongURL,
+		Owner:     rec.Owner,
+		CreatedAt: rec.CreatedAt,
+		UpdatedAt: rec.UpdatedAt,
+	}
+	if rec.ExpiresAt != nil {
+		link.ExpiresAt = *rec.ExpiresAt
+	}
+	return link, nil
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	minAliasLength = 3
+	maxAliasLength = 32 // Ограничено длиной колонки urls.short_url
+	aliasSeparator = '-'
+)
+
+var (
+	ErrInvalidAlias = errors.New("invalid alias")
+	ErrAliasTaken   = errors.New("alias already taken")
+)
+
+// Зарезервированные пути сервиса, которые нельзя занять пользовательским кодом
+var reservedAliases = map[string]struct{}{
+	"admin":   {},
+	"api":     {},
+	"docs":    {},
+	"expand":  {},
+	"healthz": {},
+	"links":   {},
+	"metrics": {},
+	"readyz":  {},
+	"shorten": {},
+	"static":  {},
+	"swagger": {},
+}
+
+// Проверяет пользовательский код: символы из chars (алфавит кодов) и разделитель "-"
+// (не в начале и не в конце), допустимая длина и отсутствие в списке зарезервированных слов
+func ValidateAlias(alias, chars string) error {
+	if len(alias) < minAliasLength || len(alias) > maxAliasLength {
+		return fmt.Errorf("%w: length must be between %d and %d", ErrInvalidAlias, minAliasLength, maxAliasLength)
+	}
+	for i := 0; i < len(alias); i++ {
+		if alias[i] == aliasSeparator && i != 0 && i != len(alias)-1 {
+			continue
+		}
+		if strings.IndexByte(chars, alias[i]) < 0 {
+			return fmt.Errorf("%w: unexpected character %q", ErrInvalidAlias, alias[i])
+		}
+	}
+	if _, ok := reservedAliases[strings.ToLower(alias)]; ok {
+		return fmt.Errorf("%w: %q is reserved", ErrInvalidAlias, alias)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"url-shortener/internal/model"
+)
+
+const (
+	dateLayout   = "2006-01-02"
+	maxStatsDays = 365
+	ipv4KeepBits = 24 // Последний октет IPv4 обнуляется
+	ipv6KeepBits = 48 // В IPv6 сохраняется только префикс сети
+	// Сколько ждать записи одного перехода, чтобы зависшее хранилище не останавливало очередь
+	clickInsertTimeout = 5 * time.Second
+)
+
+var ErrInvalidPeriod = errors.New("invalid stats period")
+
+// since - начало первого дня, за который нужна разбивка по дням.
+// daily содержит количество переходов по дням в формате YYYY-MM-DD (UTC)
+type AnalyticsStorage interface {
+	InsertClick(ctx context.Context, click model.Click) error
+	ClickStats(ctx context.Context, shortUrl string, since time.Time) (total int64, daily map[string]int64, err error)
+}
+
+// Принимает события переходов без блокировки обработчика запроса
+// и записывает их в хранилище аналитики в фоне
+type AnalyticsService struct {
+	Storage   Storage
+	Analytics AnalyticsStorage
+	clicks    chan model.Click
+	logger    Logger
+}
+
+func NewAnalyticsService(storage Storage, analytics AnalyticsStorage, bufferSize int, logger Logger) *AnalyticsService {
+	return &AnalyticsService{
+		Storage:   storage,
+		Analytics: analytics,
+		clicks:    make(chan model.Click, bufferSize),
+		logger:    logger,
+	}
+}
+
+// При переполненной очереди событие отбрасывается, чтобы не задерживать переход
+func (s *AnalyticsService) RecordClick(click model.Click) {
+	click.ClientIP = AnonymizeIP(click.ClientIP)
+	select {
+	case s.clicks <- click:
+	default:
+		s.logger.Errorf("Очередь событий переполнена, переход по %s не записан", click.ShortURL)
+	}
+}
+
+// Блокируется до отмены контекста, после чего дописывает оставшиеся в очереди события
+func (s *AnalyticsService) Run(ctx context.Context) {
+	for {
+		select {
+		case click := <-s.clicks:
+			s.insert(click)
+		case <-ctx.Done():
+			for {
+				select {
+				case click := <-s.clicks:
+					s.insert(click)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (s *AnalyticsService) insert(click model.Click) {
+	ctx, cancel := context.WithTimeout(context.Background(), clickInsertTimeout)
+	defer cancel()
+	if err := s.Analytics.InsertClick(ctx, click); err != nil {
+		s.logger.Errorf("Ошибка при записи перехода по %s: %v", click.ShortURL, err)
+	}
+}
+
+// Возвращает общее количество переходов и разбивку по дням за последние days дней, включая текущий.
+// Если owner не пуст (аутентификация включена), ссылка должна принадлежать этому ключу, иначе возвращается ErrForbidden
+func (s *AnalyticsService) Stats(ctx context.Context, shortUrl, owner string, days int) (model.LinkStats, error) {
+	if days < 1 || days > maxStatsDays {
+		return model.LinkStats{}, ErrInvalidPeriod
+	}
+	link, err := s.Storage.GetLink(ctx, shortUrl)
+	if err != nil {
+		return model.LinkStats{}, err
+	}
+	if owner != "" && link.Owner != owner {
+		return model.LinkStats{}, ErrForbidden
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	since := today.AddDate(0, 0, 1-days)
+	// Переходы хранятся под кодом в том написании, в котором сохранена ссылка
+	total, daily, err := s.Analytics.ClickStats(ctx, link.ShortURL, since)
+	if err != nil {
+		return model.LinkStats{}, err
+	}
+
+	stats := model.LinkStats{ShortURL: shortUrl, Total: total, Daily: make([]model.DailyClicks, 0, days)}
+	for day := since; !day.After(today); day = day.AddDate(0, 0, 1) {
+		date := day.Format(dateLayout)
+		stats.Daily = append(stats.Daily, model.DailyClicks{Date: date, Clicks: daily[date]})
+	}
+	return stats, nil
+}
+
+// Обнуляет младшие биты адреса клиента, чтобы по статистике нельзя было установить конкретного пользователя
+func AnonymizeIP(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ""
+	}
+	if v4 := parsed.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(ipv4KeepBits, 32)).String()
+	}
+	return parsed.Mask(net.CIDRMask(ipv6KeepBits, 128)).String()
+}
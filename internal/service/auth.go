@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"url-shortener/internal/model"
+	"url-shortener/pkg/storage"
+)
+
+const (
+	keyIDBytes     = 8
+	keySecretBytes = 32
+)
+
+var ErrUnauthorized = errors.New("invalid or revoked api key")
+
+type KeyStorage interface {
+	InsertKey(ctx context.Context, key model.APIKey) error
+	GetKeyByHash(ctx context.Context, hash string) (model.APIKey, error)
+	ListKeys(ctx context.Context) ([]model.APIKey, error)
+	RevokeKey(ctx context.Context, id string, revokedAt time.Time) error
+}
+
+type AuthService struct {
+	Keys KeyStorage
+}
+
+func NewAuthService(keys KeyStorage) *AuthService {
+	return &AuthService{Keys: keys}
+}
+
+// Возвращает владельца ключа, если ключ существует и не отозван
+func (s AuthService) Authenticate(ctx context.Context, rawKey string) (model.APIKey, error) {
+	key, err := s.Keys.GetKeyByHash(ctx, HashKey(rawKey))
+	if err == storage.ErrKeyNotFound {
+		return model.APIKey{}, ErrUnauthorized
+	}
+	if err != nil {
+		return model.APIKey{}, err
+	}
+	if key.RevokedAt != nil {
+		return model.APIKey{}, ErrUnauthorized
+	}
+	return key, nil
+}
+
+// Создаёт новый ключ. Значение ключа возвращается только здесь, в хранилище попадает его хэш
+func (s AuthService) CreateKey(ctx context.Context, name string) (model.NewAPIKey, error) {
+	id, err := randomHex(keyIDBytes)
+	if err != nil {
+		return model.NewAPIKey{}, err
+	}
+	secret, err := randomHex(keySecretBytes)
+	if err != nil {
+		return model.NewAPIKey{}, err
+	}
+
+	key := model.APIKey{ID: id, Name: name, Hash: HashKey(secret), CreatedAt: time.Now().UTC()}
+	if err := s.Keys.InsertKey(ctx, key); err != nil {
+		return model.NewAPIKey{}, err
+	}
+	return model.NewAPIKey{ID: key.ID, Name: key.Name, Key: secret, CreatedAt: key.CreatedAt}, nil
+}
+
+func (s AuthService) ListKeys(ctx context.Context) ([]model.APIKey, error) {
+	return s.Keys.ListKeys(ctx)
+}
+
+func (s AuthService) RevokeKey(ctx context.Context, id string) error {
+	return s.Keys.RevokeKey(ctx, id, time.Now().UTC())
+}
+
+func HashKey(rawKey string) string {
+	sum := sha256.Sum256([]byte(rawKey))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
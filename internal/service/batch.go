@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+
+	"url-shortener/internal/model"
+	"url-shortener/pkg/storage"
+)
+
+// Результат сокращения одной ссылки пакета: короткий код или ошибка
+type BatchResult struct {
+	ShortURL string
+	Err      error
+}
+
+type batchItem struct {
+	link      model.Link
+	exists    bool // Ссылка уже сохранена под этим кодом, вставка не нужна
+	generated bool // Код вычисляется и занимается через AllocateBatch, а не пакетной вставкой
+	err       error
+}
+
+// Сокращает пакет ссылок, сохраняя порядок результатов. Ссылки со значением ShortURL
+// сохраняются под пользовательским кодом, остальные под вычисленным.
+// Ссылки проверяются параллельно не более чем в workers горутинах. Вычисленные коды занимаются
+// через Storage.AllocateBatch с той же атомарностью, что и при сокращении одной ссылки: одно обращение
+// к хранилищу на попытку генератора, следующая попытка только для ссылок, чей код оказался занят.
+// Ссылки с пользовательским кодом сохраняются одной пакетной вставкой. Ссылки, для которых
+// пакетное обращение не удалось, сохраняются по одной
+func (s ShortenerService) BatchShortening(ctx context.Context, links []model.Link, workers int) []BatchResult {
+	items := make([]batchItem, len(links))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(workers, len(links)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				items[i] = s.prepare(ctx, links[i])
+			}
+		}()
+	}
+	for i := range links {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	retry := s.allocate(ctx, items)
+
+	// Одинаковые ссылки внутри пакета вставляются один раз,
+	// ссылки с совпавшим кодом, но разным адресом сохраняются по одной
+	var batch []model.Link
+	var batchIndexes []int
+	first := make(map[string]int)
+	duplicateOf := make(map[int]int)
+	for i, item := range items {
+		if item.err != nil || item.exists || item.generated {
+			continue
+		}
+		if j, ok := first[item.link.ShortURL]; ok {
+			if items[j].link.LongURL == item.link.LongURL {
+				duplicateOf[i] = j
+			} else {
+				retry = append(retry, i)
+			}
+			continue
+		}
+		first[item.link.ShortURL] = i
+		batch = append(batch, item.link)
+		batchIndexes = append(batchIndexes, i)
+	}
+
+	if len(batch) > 0 {
+		errs, err := s.Storage.InsertBatch(ctx, batch)
+		for n, i := range batchIndexes {
+			if err != nil || errors.Is(errs[n], storage.ErrAlreadyExists) {
+				retry = append(retry, i)
+			} else {
+				items[i].err = errs[n]
+			}
+		}
+	}
+
+	results := make([]BatchResult, len(items))
+	exists := make([]bool, len(items))
+	for i, item := range items {
+		exists[i] = item.exists
+		if item.err != nil {
+			results[i] = BatchResult{Err: item.err}
+		} else {
+			results[i] = BatchResult{ShortURL: item.link.ShortURL}
+		}
+	}
+	sort.Ints(retry)
+	for _, i := range retry {
+		var res BatchResult
+		if links[i].ShortURL != "" {
+			res.ShortURL, exists[i], res.Err = s.customShorten(ctx, links[i])
+		} else {
+			res.ShortURL, exists[i], res.Err = s.shorten(ctx, links[i])
+		}
+		results[i] = res
+	}
+	for i, j := range duplicateOf {
+		results[i] = results[j]
+		exists[i] = true
+	}
+	for i, res := range results {
+		s.Metrics.observeShorten("batch", shortenOutcome(res.Err, exists[i]))
+	}
+	return results
+}
+
+// Вычисляет код первой попытки либо проверяет пользовательский код. Сохранение откладывается
+// до пакетных обращений к хранилищу
+func (s ShortenerService) prepare(ctx context.Context, link model.Link) batchItem {
+	var err error
+	if link.ShortURL == "" {
+		if link.LongURL, err = s.Normalizer.Normalize(link.LongURL); err != nil {
+			return batchItem{err: err}
+		}
+		link.ShortURL, err = s.Generator.Code(ctx, link.LongURL, 0)
+		return batchItem{link: link, generated: true, err: err}
+	}
+	if err := s.validateAlias(link.ShortURL); err != nil {
+		return batchItem{err: err}
+	}
+	if link.LongURL, err = s.Normalizer.Normalize(link.LongURL); err != nil {
+		return batchItem{err: err}
+	}
+	exists, err := s.checkAlias(ctx, link)
+	return batchItem{link: link, exists: exists, err: err}
+}
+
+// Занимает вычисленные коды пакета через Storage.AllocateBatch, по одному обращению на попытку генератора.
+// Возвращает ссылки, которые нужно сократить по одной, потому что пакетное обращение не удалось
+func (s ShortenerService) allocate(ctx context.Context, items []batchItem) []int {
+	var pending []int
+	for i, item := range items {
+		if item.generated && item.err == nil {
+			pending = append(pending, i)
+		}
+	}
+	for attempt := 0; len(pending) > 0; attempt++ {
+		if attempt == s.Generator.MaxAttempts() {
+			for _, i := range pending {
+				items[i].err = errNoFreeCode
+			}
+			return nil
+		}
+		links := make([]model.Link, 0, len(pending))
+		indexes := make([]int, 0, len(pending))
+		for _, i := range pending {
+			if attempt > 0 {
+				code, err := s.Generator.Code(ctx, items[i].link.LongURL, attempt)
+				if err != nil {
+					items[i].err = err
+					continue
+				}
+				items[i].link.ShortURL = code
+			}
+			links = append(links, items[i].link)
+			indexes = append(indexes, i)
+		}
+		if len(links) == 0 {
+			return nil
+		}
+		allocations, err := s.Storage.AllocateBatch(ctx, links)
+		if err != nil {
+			return indexes
+		}
+		pending = pending[:0]
+		for n, i := range indexes {
+			switch res := allocations[n]; {
+			case errors.Is(res.Err, storage.ErrAlreadyExists):
+				pending = append(pending, i)
+			case res.Err != nil:
+				items[i].err = res.Err
+			default:
+				items[i].link.ShortURL, items[i].exists = res.ShortURL, res.Exists
+				s.Metrics.observeCollisionDepth(attempt)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"url-shortener/internal/model"
+	"url-shortener/pkg/storage"
+)
+
+// Хранилище, приводящее коды к одному регистру перед обращением к вложенному хранилищу,
+// чтобы код находился при любом регистре букв в запросе. Используется с алфавитом одного регистра,
+// в котором коды, различающиеся только регистром, не выдаются.
+// Новые коды сохраняются в регистре алфавита. Коды, сохранённые до включения режима в смешанном регистре,
+// ищутся сначала как есть, поэтому остаются доступны по исходному написанию
+type CaseFoldStorage struct {
+	Storage
+	fold func(string) string
+}
+
+func NewCaseFoldStorage(storage Storage, fold func(string) string) *CaseFoldStorage {
+	return &CaseFoldStorage{Storage: storage, fold: fold}
+}
+
+func (s *CaseFoldStorage) GetLongUrl(ctx context.Context, shortUrl string) (longUrl string, err error) {
+	err = s.lookup(shortUrl, func(code string) (err error) {
+		longUrl, err = s.Storage.GetLongUrl(ctx, code)
+		return err
+	})
+	return longUrl, err
+}
+
+func (s *CaseFoldStorage) GetLink(ctx context.Context, shortUrl string) (link model.Link, err error) {
+	err = s.lookup(shortUrl, func(code string) (err error) {
+		link, err = s.Storage.GetLink(ctx, code)
+		return err
+	})
+	return link, err
+}
+
+func (s *CaseFoldStorage) Insert(ctx context.Context, link model.Link) error {
+	link.ShortURL = s.fold(link.ShortURL)
+	return s.Storage.Insert(ctx, link)
+}
+
+func (s *CaseFoldStorage) Allocate(ctx context.Context, link model.Link) (string, bool, error) {
+	link.ShortURL = s.fold(link.ShortURL)
+	return s.Storage.Allocate(ctx, link)
+}
+
+func (s *CaseFoldStorage) AllocateBatch(ctx context.Context, links []model.Link) ([]model.Allocation, error) {
+	folded := make([]model.Link, len(links))
+	for i, link := range links {
+		link.ShortURL = s.fold(link.ShortURL)
+		folded[i] = link
+	}
+	return s.Storage.AllocateBatch(ctx, folded)
+}
+
+func (s *CaseFoldStorage) InsertBatch(ctx context.Context, links []model.Link) ([]error, error) {
+	folded := make([]model.Link, len(links))
+	for i, link := range links {
+		link.ShortURL = s.fold(link.ShortURL)
+		folded[i] = link
+	}
+	return s.Storage.InsertBatch(ctx, folded)
+}
+
+func (s *CaseFoldStorage) Update(ctx context.Context, link model.Link) (updated model.Link, err error) {
+	err = s.lookup(link.ShortURL, func(code string) (err error) {
+		link.ShortURL = code
+		updated, err = s.Storage.Update(ctx, link)
+		return err
+	})
+	return updated, err
+}
+
+func (s *CaseFoldStorage) Delete(ctx context.Context, shortUrl, owner string) error {
+	return s.lookup(shortUrl, func(code string) error {
+		return s.Storage.Delete(ctx, code, owner)
+	})
+}
+
+// Выполняет fn для кода как есть и, если такого кода нет, для кода в регистре алфавита
+func (s *CaseFoldStorage) lookup(shortUrl string, fn func(code string) error) error {
+	err := fn(shortUrl)
+	if folded := s.fold(shortUrl); folded != shortUrl && errors.Is(err, storage.ErrNotFound) {
+		return fn(folded)
+	}
+	return err
+}
+
+// Хранилище аналитики, относящее переходы к тому же коду, что и CaseFoldStorage,
+// чтобы переходы по коду в любом регистре учитывались вместе
+type CaseFoldAnalyticsStorage struct {
+	AnalyticsStorage
+	links Storage // Хранилище ссылок без приведения регистра, в нём проверяются коды в исходном написании
+	fold  func(string) string
+}
+
+func NewCaseFoldAnalyticsStorage(analytics AnalyticsStorage, links Storage, fold func(string) string) *CaseFoldAnalyticsStorage {
+	return &CaseFoldAnalyticsStorage{AnalyticsStorage: analytics, links: links, fold: fold}
+}
+
+func (s *CaseFoldAnalyticsStorage) InsertClick(ctx context.Context, click model.Click) (err error) {
+	if click.ShortURL, err = s.canonical(ctx, click.ShortURL); err != nil {
+		return err
+	}
+	return s.AnalyticsStorage.InsertClick(ctx, click)
+}
+
+func (s *CaseFoldAnalyticsStorage) ClickStats(ctx context.Context, shortUrl string, since time.Time) (int64, map[string]int64, error) {
+	shortUrl, err := s.canonical(ctx, shortUrl)
+	if err != nil {
+		return 0, nil, err
+	}
+	return s.AnalyticsStorage.ClickStats(ctx, shortUrl, since)
+}
+
+// Код, под которым хранится ссылка: исходное написание, если ссылка сохранена под ним, иначе код в регистре алфавита
+func (s *CaseFoldAnalyticsStorage) canonical(ctx context.Context, shortUrl string) (string, error) {
+	folded := s.fold(shortUrl)
+	if folded == shortUrl {
+		return shortUrl, nil
+	}
+	_, err := s.links.GetLink(ctx, shortUrl)
+	switch {
+	case err == nil:
+		return shortUrl, nil
+	case errors.Is(err, storage.ErrNotFound):
+		return folded, nil
+	}
+	return "", err
+}
@@ -0,0 +1,303 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/bits"
+	"sync/atomic"
+
+	"url-shortener/pkg/alphabet"
+)
+
+// Стратегии генерации коротких кодов
+const (
+	StrategyHash    = "hash"    // SHA-256 от ссылки и номер коллизии, одна ссылка - один код
+	StrategyRandom  = "random"  // Криптографически случайный код
+	StrategyCounter = "counter" // Номер из счётчика, переставленный по секретному ключу
+	StrategyHMAC    = "hmac"    // Как hash, но HMAC-SHA256 с секретным ключом: коды нельзя вычислить заранее
+)
+
+const AlphabetBase62 = alphabet.Base62
+
+const (
+	suffixLength  = 2  // Длина номера коллизии в кодах hash и hmac
+	retryAttempts = 10 // Попыток для стратегий random и counter, каждая даёт новый код
+)
+
+// CodeGenerator подбирает короткий код для длинной ссылки. Code возвращает кандидата для попытки attempt
+// (начиная с нуля), следующая попытка запрашивается, если код занят другой ссылкой.
+// Детерминированные стратегии дают для одной ссылки одну и ту же последовательность кодов,
+// поэтому повторное сокращение находит уже сохранённый код. Name - стратегия генератора (Strategy*),
+// Alphabet - символы кодов, из них же составляются пользовательские коды
+type CodeGenerator interface {
+	Code(ctx context.Context, longUrl string, attempt int) (string, error)
+	MaxAttempts() int
+	Name() string
+	Alphabet() string
+}
+
+// Источник монотонно растущих номеров для стратегии counter
+type Counter interface {
+	Next(ctx context.Context) (uint64, error)
+}
+
+// Параметры кодов: символы и длина
+type CodeOptions struct {
+	Alphabet string
+	Length   int
+}
+
+// Параметры по умолчанию повторяют исходный формат кодов: 8 символов хэша и 2 символа номера коллизии
+var DefaultCodeOptions = CodeOptions{Alphabet: Alphabet, Length: hashLength + suffixLength}
+
+// Создаёт генератор выбранной стратегии. Алфавит задаётся символами или именем набора из alphabet.Presets,
+// пустой алфавит - StrategyAlphabet. secret обязателен для counter и hmac, counter - для counter
+func NewCodeGenerator(strategy string, opts CodeOptions, secret []byte, counter Counter) (CodeGenerator, error) {
+	opts.Alphabet = alphabet.Resolve(opts.Alphabet)
+	if opts.Alphabet == "" {
+		opts.Alphabet = StrategyAlphabet(strategy)
+	}
+	if err := alphabet.Validate(opts.Alphabet); err != nil {
+		return nil, fmt.Errorf("code alphabet: %w", err)
+	}
+	if (strategy == StrategyCounter || strategy == StrategyHMAC) && len(secret) == 0 {
+		return nil, fmt.Errorf("code strategy %s requires a secret", strategy)
+	}
+	switch strategy {
+	case StrategyHash:
+		return NewHashGenerator(opts), nil
+	case StrategyHMAC:
+		return NewHMACGenerator(opts, secret), nil
+	case StrategyRandom:
+		return NewRandomGenerator(opts), nil
+	case StrategyCounter:
+		if counter == nil {
+			return nil, errors.New("code strategy counter requires a counter")
+		}
+		return NewCounterGenerator(opts, secret, counter), nil
+	}
+	return nil, fmt.Errorf("unknown code strategy %q", strategy)
+}
+
+// Алфавит стратегии по умолчанию: Alphabet для hash и hmac (исходный формат кодов), AlphabetBase62 для остальных
+func StrategyAlphabet(strategy string) string {
+	if strategy == StrategyHash || strategy == StrategyHMAC {
+		return Alphabet
+	}
+	return AlphabetBase62
+}
+
+// Код из хэша ссылки: Length-2 символа дайджеста и 2 символа номера коллизии (attempt)
+type HashGenerator struct {
+	opts CodeOptions
+	key  []byte // Ключ HMAC, пустой для SHA-256
+}
+
+func NewHashGenerator(opts CodeOptions) *HashGenerator {
+	return &HashGenerator{opts: opts}
+}
+
+func NewHMACGenerator(opts CodeOptions, secret []byte) *HashGenerator {
+	return &HashGenerator{opts: opts, key: secret}
+}
+
+func (g *HashGenerator) Code(_ context.Context, longUrl string, attempt int) (string, error) {
+	var digest []byte
+	if len(g.key) > 0 {
+		mac := hmac.New(sha256.New, g.key)
+		mac.Write([]byte(longUrl))
+		digest = mac.Sum(nil)
+	} else {
+		sum := sha256.Sum256([]byte(longUrl))
+		digest = sum[:]
+	}
+	return encodeDigest(digest, g.opts.Alphabet, g.opts.Length-suffixLength) + encodeIndex(attempt, g.opts.Alphabet, suffixLength), nil
+}
+
+func (g *HashGenerator) MaxAttempts() int {
+	return len(g.opts.Alphabet)*len(g.opts.Alphabet) - 1
+}
+
+func (g *HashGenerator) Name() string {
+	if len(g.key) > 0 {
+		return StrategyHMAC
+	}
+	return StrategyHash
+}
+
+func (g *HashGenerator) Alphabet() string {
+	return g.opts.Alphabet
+}
+
+// Случайный код из crypto/rand, символы выбираются равновероятно
+type RandomGenerator struct {
+	opts CodeOptions
+}
+
+func NewRandomGenerator(opts CodeOptions) *RandomGenerator {
+	return &RandomGenerator{opts: opts}
+}
+
+func (g *RandomGenerator) Code(context.Context, string, int) (string, error) {
+	// Байты не меньше limit отбрасываются, чтобы остаток от деления был равномерным
+	limit := 256 - 256%len(g.opts.Alphabet)
+	code := make([]byte, 0, g.opts.Length)
+	buf := make([]byte, g.opts.Length)
+	for len(code) < g.opts.Length {
+		if _, err := rand.Read(buf); err != nil {
+			return "", err
+		}
+		for _, b := range buf {
+			if int(b) < limit && len(code) < g.opts.Length {
+				code = append(code, g.opts.Alphabet[int(b)%len(g.opts.Alphabet)])
+			}
+		}
+	}
+	return string(code), nil
+}
+
+func (g *RandomGenerator) MaxAttempts() int {
+	return retryAttempts
+}
+
+func (g *RandomGenerator) Name() string {
+	return StrategyRandom
+}
+
+func (g *RandomGenerator) Alphabet() string {
+	return g.opts.Alphabet
+}
+
+// Код из номера счётчика: номер переставляется обратимой перестановкой с секретным ключом
+// и записывается в алфавите кода. Разные номера дают разные коды, но соседние номера
+// дают несвязанные коды, поэтому перебрать выданные ссылки по порядку нельзя
+type CounterGenerator struct {
+	opts    CodeOptions
+	counter Counter
+	perm    permutation
+}
+
+func NewCounterGenerator(opts CodeOptions, secret []byte, counter Counter) *CounterGenerator {
+	return &CounterGenerator{opts: opts, counter: counter, perm: newPermutation(codeSpace(opts), secret)}
+}
+
+func (g *CounterGenerator) Code(ctx context.Context, _ string, _ int) (string, error) {
+	n, err := g.counter.Next(ctx)
+	if err != nil {
+		return "", err
+	}
+	return encodeInt(g.perm.apply(n%g.perm.domain), g.opts.Alphabet, g.opts.Length), nil
+}
+
+func (g *CounterGenerator) MaxAttempts() int {
+	return retryAttempts
+}
+
+func (g *CounterGenerator) Name() string {
+	return StrategyCounter
+}
+
+func (g *CounterGenerator) Alphabet() string {
+	return g.opts.Alphabet
+}
+
+// Счётчик в памяти процесса для хранилищ без собственного счётчика
+type MemoryCounter struct {
+	n atomic.Uint64
+}
+
+// Первым будет выдан номер start
+func NewMemoryCounter(start uint64) *MemoryCounter {
+	c := &MemoryCounter{}
+	c.n.Store(start)
+	return c
+}
+
+func (c *MemoryCounter) Next(context.Context) (uint64, error) {
+	return c.n.Add(1) - 1, nil
+}
+
+// Количество различных кодов, ограниченное 2^62
+func codeSpace(opts CodeOptions) uint64 {
+	const limit = uint64(1) << 62
+	space := uint64(1)
+	for i := 0; i < opts.Length; i++ {
+		if space > limit/uint64(len(opts.Alphabet)) {
+			return limit
+		}
+		space *= uint64(len(opts.Alphabet))
+	}
+	return space
+}
+
+// Перестановка чисел [0, domain): сеть Фейстеля на чётном числе бит с раундовой функцией HMAC-SHA256.
+// Значения за пределами domain проходят через сеть повторно, пока не попадут в диапазон
+type permutation struct {
+	domain   uint64
+	halfBits int
+	key      []byte
+}
+
+const feistelRounds = 4
+
+func newPermutation(domain uint64, key []byte) permutation {
+	width := bits.Len64(domain - 1)
+	width += width % 2
+	if width < 2 {
+		width = 2
+	}
+	return permutation{domain: domain, halfBits: width / 2, key: key}
+}
+
+func (p permutation) apply(x uint64) uint64 {
+	for {
+		x = p.feistel(x)
+		if x < p.domain {
+			return x
+		}
+	}
+}
+
+func (p permutation) feistel(x uint64) uint64 {
+	mask := uint64(1)<<p.halfBits - 1
+	left, right := x>>p.halfBits, x&mask
+	var block [9]byte
+	for round := 0; round < feistelRounds; round++ {
+		block[0] = byte(round)
+		binary.BigEndian.PutUint64(block[1:], right)
+		mac := hmac.New(sha256.New, p.key)
+		mac.Write(block[:])
+		f := binary.BigEndian.Uint64(mac.Sum(nil))
+		left, right = right, left^(f&mask)
+	}
+	return left<<p.halfBits | right
+}
+
+// Первые n символов из байтов дайджеста
+func encodeDigest(digest []byte, alphabet string, n int) string {
+	code := make([]byte, n)
+	for i := range code {
+		code[i] = alphabet[int(digest[i%len(digest)])%len(alphabet)]
+	}
+	return string(code)
+}
+
+// Номер в системе счисления по основанию len(alphabet), ровно width разрядов
+func encodeIndex(id int, alphabet string, width int) string {
+	return encodeInt(uint64(id), alphabet, width)
+}
+
+func encodeInt(v uint64, alphabet string, width int) string {
+	code := make([]byte, width)
+	base := uint64(len(alphabet))
+	for i := width - 1; i >= 0; i-- {
+		code[i] = alphabet[v%base]
+		v /= base
+	}
+	return string(code)
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Максимальное время жизни ссылки, ttl_seconds больше него не переводится в time.Duration без переполнения
+const MaxTTL = 10 * 365 * 24 * time.Hour
+
+// Причина отклонения слишком долгого времени жизни в ответе 422
+const ReasonTTLTooLong = "ttl_too_long"
+
+var (
+	ErrInvalidExpiry = errors.New("invalid expiry")
+	ErrTTLTooLong    = fmt.Errorf("%w: ttl_seconds must not exceed %d", ErrInvalidExpiry, int64(MaxTTL/time.Second))
+)
+
+type Logger interface {
+	Infof(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+// Вычисляет момент истечения ссылки по абсолютному времени expires_at или времени жизни ttl_seconds.
+// Одновременно можно указать только одно из значений, нулевой результат означает бессрочную ссылку.
+// Время жизни больше MaxTTL - ErrTTLTooLong
+func ResolveExpiry(expiresAt *time.Time, ttlSeconds int64, now time.Time) (time.Time, error) {
+	if expiresAt != nil && ttlSeconds != 0 {
+		return time.Time{}, fmt.Errorf("%w: expires_at and ttl_seconds are mutually exclusive", ErrInvalidExpiry)
+	}
+	if ttlSeconds < 0 {
+		return time.Time{}, fmt.Errorf("%w: ttl_seconds must be positive", ErrInvalidExpiry)
+	}
+	if ttlSeconds > int64(MaxTTL/time.Second) {
+		return time.Time{}, ErrTTLTooLong
+	}
+	if ttlSeconds > 0 {
+		return now.Add(time.Duration(ttlSeconds) * time.Second).UTC(), nil
+	}
+	if expiresAt != nil {
+		if !expiresAt.After(now) {
+			return time.Time{}, fmt.Errorf("%w: expires_at must be in the future", ErrInvalidExpiry)
+		}
+		return expiresAt.UTC(), nil
+	}
+	return time.Time{}, nil
+}
+
+// Периодически удаляет из хранилища ссылки с истёкшим сроком действия
+type Sweeper struct {
+	storage  Storage
+	interval time.Duration
+	logger   Logger
+}
+
+func NewSweeper(storage Storage, interval time.Duration, logger Logger) *Sweeper {
+	return &Sweeper{storage: storage, interval: interval, logger: logger}
+}
+
+// Блокируется до отмены контекста
+func (s *Sweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			n, err := s.storage.DeleteExpired(ctx, now)
+			if err != nil {
+				s.logger.Errorf("Ошибка при удалении истёкших ссылок: %v", err)
+				continue
+			}
+			if n > 0 {
+				s.logger.Infof("Удалено истёкших ссылок: %d", n)
+			}
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"url-shortener/internal/model"
+)
+
+const (
+	HealthOK          = "ok"
+	HealthUnavailable = "unavailable"
+)
+
+// Проверка зависимости сервиса. Возвращает необязательные подробности (например, версию миграций)
+// или ошибку, если зависимость недоступна
+type HealthCheck func(ctx context.Context) (string, error)
+
+type namedCheck struct {
+	name  string
+	check HealthCheck
+}
+
+// Проверяет готовность сервиса: все зависимости доступны и сервис не завершает работу.
+// Проверки выполняются параллельно, каждая ограничена timeout
+type HealthService struct {
+	checks       []namedCheck
+	timeout      time.Duration
+	shuttingDown atomic.Bool
+}
+
+func NewHealthService(timeout time.Duration) *HealthService {
+	return &HealthService{timeout: timeout}
+}
+
+// Добавляет проверку зависимости, вызывается до начала обработки запросов
+func (s *HealthService) AddCheck(name string, check HealthCheck) {
+	s.checks = append(s.checks, namedCheck{name: name, check: check})
+}
+
+// Отмечает начало завершения работы, после чего сервис перестаёт считаться готовым
+func (s *HealthService) StartShutdown() {
+	s.shuttingDown.Store(true)
+}
+
+func (s *HealthService) ShuttingDown() bool {
+	return s.shuttingDown.Load()
+}
+
+func (s *HealthService) Ready(ctx context.Context) model.Readiness {
+	res := model.Readiness{
+		Status:       HealthOK,
+		ShuttingDown: s.ShuttingDown(),
+		Checks:       make(map[string]model.DependencyStatus, len(s.checks)),
+	}
+	if res.ShuttingDown {
+		res.Status = HealthUnavailable
+	}
+
+	statuses := make([]model.DependencyStatus, len(s.checks))
+	var wg sync.WaitGroup
+	for i, c := range s.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			statuses[i] = s.run(ctx, c.check)
+		}()
+	}
+	wg.Wait()
+
+	for i, c := range s.checks {
+		res.Checks[c.name] = statuses[i]
+		if statuses[i].Status != HealthOK {
+			res.Status = HealthUnavailable
+		}
+	}
+	return res
+}
+
+func (s *HealthService) run(ctx context.Context, check HealthCheck) model.DependencyStatus {
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+	start := time.Now()
+	detail, err := check(ctx)
+	status := model.DependencyStatus{Status: HealthOK, Detail: detail, DurationMs: time.Since(start).Milliseconds()}
+	if err != nil {
+		status.Status = HealthUnavailable
+		status.Error = err.Error()
+	}
+	return status
+}
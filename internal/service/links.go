@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"url-shortener/internal/model"
+	"url-shortener/pkg/storage"
+)
+
+var ErrForbidden = storage.ErrForbidden
+
+// Возвращает запись о ссылке. Если owner не пуст (аутентификация включена),
+// ссылка должна принадлежать этому ключу, иначе возвращается ErrForbidden
+func (s ShortenerService) GetLink(ctx context.Context, shortUrl, owner string) (model.Link, error) {
+	link, err := s.Storage.GetLink(ctx, shortUrl)
+	if err != nil {
+		return model.Link{}, err
+	}
+	if owner != "" && link.Owner != owner {
+		return model.Link{}, ErrForbidden
+	}
+	return link, nil
+}
+
+// Меняет длинную ссылку и срок действия. Пустой longUrl и nil expiresAt оставляют прежние значения,
+// нулевой *expiresAt делает ссылку бессрочной. Владелец проверяется хранилищем в том же шаге, что и изменение
+func (s ShortenerService) UpdateLink(ctx context.Context, shortUrl, owner, longUrl string, expiresAt *time.Time) (model.Link, error) {
+	link := model.Link{ShortURL: shortUrl, Owner: owner}
+	if longUrl != "" {
+		var err error
+		if link.LongURL, err = s.Normalizer.Normalize(longUrl); err != nil {
+			return model.Link{}, err
+		}
+	}
+	if expiresAt != nil {
+		link.ExpiresAt = *expiresAt
+		if link.ExpiresAt.IsZero() {
+			link.ExpiresAt = model.NoExpiry
+		}
+	}
+	return s.Storage.Update(ctx, link)
+}
+
+func (s ShortenerService) DeleteLink(ctx context.Context, shortUrl, owner string) error {
+	return s.Storage.Delete(ctx, shortUrl, owner)
+}
@@ -0,0 +1,199 @@
+package service
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"url-shortener/internal/model"
+	"url-shortener/pkg/storage"
+)
+
+// Параметры кэша: Size - максимальное количество кодов, TTL - время жизни найденной ссылки,
+// NegativeTTL - время жизни отметки об отсутствии кода (0 отключает кэширование отсутствия)
+type LRUOptions struct {
+	Size        int
+	TTL         time.Duration
+	NegativeTTL time.Duration
+}
+
+// Хранилище с ограниченным LRU-кэшем результатов GetLongUrl перед вложенным хранилищем.
+// Промах читает запись целиком через GetLink, поэтому срок действия ссылки проверяется по кэшу.
+// Запись через кэш сбрасывает закэшированное значение кода. Изменения, сделанные в обход
+// этого экземпляра (другими экземплярами сервиса), становятся видны не позже TTL
+type LRUStorage struct {
+	Storage
+	opts LRUOptions
+
+	mu      sync.Mutex
+	items   map[string]*list.Element
+	order   *list.List // В начале - недавно использованные
+	version uint64     // Увеличивается при каждой записи, чтобы не кэшировать прочитанное до неё
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+type lruEntry struct {
+	shortUrl  string
+	longUrl   string
+	expiresAt time.Time // Срок действия ссылки
+	notFound  bool
+	cachedTil time.Time
+}
+
+func NewLRUStorage(storage Storage, opts LRUOptions) *LRUStorage {
+	return &LRUStorage{Storage: storage, opts: opts, items: make(map[string]*list.Element), order: list.New()}
+}
+
+// Количество запросов, обслуженных из кэша
+func (s *LRUStorage) Hits() uint64 {
+	return s.hits.Load()
+}
+
+// Количество запросов, переданных во вложенное хранилище
+func (s *LRUStorage) Misses() uint64 {
+	return s.misses.Load()
+}
+
+func (s *LRUStorage) GetLongUrl(ctx context.Context, shortUrl string) (string, error) {
+	now := time.Now()
+	if entry, ok := s.lookup(shortUrl, now); ok {
+		s.hits.Add(1)
+		return entry.result(now)
+	}
+	s.misses.Add(1)
+
+	s.mu.Lock()
+	version := s.version
+	s.mu.Unlock()
+
+	link, err := s.Storage.GetLink(ctx, shortUrl)
+	switch {
+	case err == storage.ErrNotFound:
+		if s.opts.NegativeTTL > 0 {
+			s.store(version, lruEntry{shortUrl: shortUrl, notFound: true, cachedTil: now.Add(s.opts.NegativeTTL)})
+		}
+		return "", err
+	case err != nil:
+		return "", err
+	}
+	entry := lruEntry{shortUrl: shortUrl, longUrl: link.LongURL, expiresAt: link.ExpiresAt, cachedTil: now.Add(s.opts.TTL)}
+	s.store(version, entry)
+	return entry.result(now)
+}
+
+func (s *LRUStorage) Insert(ctx context.Context, link model.Link) error {
+	defer s.invalidate(link.ShortURL)
+	return s.Storage.Insert(ctx, link)
+}
+
+func (s *LRUStorage) Allocate(ctx context.Context, link model.Link) (string, bool, error) {
+	defer s.invalidate(link.ShortURL)
+	return s.Storage.Allocate(ctx, link)
+}
+
+func (s *LRUStorage) AllocateBatch(ctx context.Context, links []model.Link) ([]model.Allocation, error) {
+	codes := make([]string, len(links))
+	for i, link := range links {
+		codes[i] = link.ShortURL
+	}
+	defer s.invalidate(codes...)
+	return s.Storage.AllocateBatch(ctx, links)
+}
+
+func (s *LRUStorage) InsertBatch(ctx context.Context, links []model.Link) ([]error, error) {
+	codes := make([]string, len(links))
+	for i, link := range links {
+		codes[i] = link.ShortURL
+	}
+	defer s.invalidate(codes...)
+	return s.Storage.InsertBatch(ctx, links)
+}
+
+func (s *LRUStorage) Update(ctx context.Context, link model.Link) (model.Link, error) {
+	defer s.invalidate(link.ShortURL)
+	return s.Storage.Update(ctx, link)
+}
+
+func (s *LRUStorage) Delete(ctx context.Context, shortUrl, owner string) error {
+	defer s.invalidate(shortUrl)
+	return s.Storage.Delete(ctx, shortUrl, owner)
+}
+
+// Истёкшие ссылки в кэше уже отвечают storage.ErrExpired, после удаления кэш полностью сбрасывается
+func (s *LRUStorage) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	n, err := s.Storage.DeleteExpired(ctx, now)
+	if n > 0 {
+		s.mu.Lock()
+		s.version++
+		s.items = make(map[string]*list.Element)
+		s.order.Init()
+		s.mu.Unlock()
+	}
+	return n, err
+}
+
+func (s *LRUStorage) lookup(shortUrl string, now time.Time) (lruEntry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	elem, ok := s.items[shortUrl]
+	if !ok {
+		return lruEntry{}, false
+	}
+	entry := elem.Value.(lruEntry)
+	if !now.Before(entry.cachedTil) {
+		s.order.Remove(elem)
+		delete(s.items, shortUrl)
+		return lruEntry{}, false
+	}
+	s.order.MoveToFront(elem)
+	return entry, true
+}
+
+// Сохраняет результат чтения, если с его начала через кэш не было записей
+func (s *LRUStorage) store(version uint64, entry lruEntry) {
+	if s.opts.Size <= 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if version != s.version {
+		return
+	}
+	if elem, ok := s.items[entry.shortUrl]; ok {
+		elem.Value = entry
+		s.order.MoveToFront(elem)
+		return
+	}
+	s.items[entry.shortUrl] = s.order.PushFront(entry)
+	for s.order.Len() > s.opts.Size {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.items, oldest.Value.(lruEntry).shortUrl)
+	}
+}
+
+func (s *LRUStorage) invalidate(codes ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.version++
+	for _, shortUrl := range codes {
+		if elem, ok := s.items[shortUrl]; ok {
+			s.order.Remove(elem)
+			delete(s.items, shortUrl)
+		}
+	}
+}
+
+func (e lruEntry) result(now time.Time) (string, error) {
+	if e.notFound {
+		return "", storage.ErrNotFound
+	}
+	if !e.expiresAt.IsZero() && !e.expiresAt.After(now) {
+		return "", storage.ErrExpired
+	}
+	return e.longUrl, nil
+}
@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"url-shortener/internal/model"
+	"url-shortener/pkg/metrics"
+	"url-shortener/pkg/storage"
+)
+
+// Исходы сокращения и раскрытия ссылок
+const (
+	OutcomeCreated  = "created"   // Сохранена новая ссылка
+	OutcomeExisting = "existing"  // Ссылка уже была сохранена под этим кодом
+	OutcomeInvalid  = "invalid"   // Некорректная ссылка, код или срок действия
+	OutcomeTaken    = "taken"     // Пользовательский код занят другой ссылкой
+	OutcomeFound    = "found"     // Ссылка найдена
+	OutcomeNotFound = "not_found" // Код не найден
+	OutcomeExpired  = "expired"   // Срок действия ссылки истёк
+	OutcomeError    = "error"     // Ошибка хранилища
+)
+
+// Границы корзин глубины перебора коллизий (номер попытки CodeGenerator, на которой найден код)
+// до последней попытки генератора
+func collisionBuckets(maxAttempts int) []float64 {
+	last := float64(maxAttempts - 1)
+	var buckets []float64
+	for _, bound := range []float64{0, 1, 2, 3, 5, 10, 25, 50, 100, 500, 1000} {
+		if bound >= last {
+			break
+		}
+		buckets = append(buckets, bound)
+	}
+	return append(buckets, last)
+}
+
+// Метрики сервиса сокращения ссылок. Методы безопасно вызывать у nil,
+// тогда сервис работает без сбора метрик
+type Metrics struct {
+	shorten         *metrics.CounterVec
+	expand          *metrics.CounterVec
+	collisionDepth  *metrics.HistogramVec
+	storageDuration *metrics.HistogramVec
+}
+
+// maxAttempts - CodeGenerator.MaxAttempts генератора сервиса, ограничивает корзины глубины перебора коллизий
+func NewMetrics(registry *metrics.Registry, maxAttempts int) *Metrics {
+	return &Metrics{
+		shorten: registry.NewCounterVec("shortener_shorten_total",
+			"Shortening requests by mode (code strategy, custom, batch) and outcome.", "mode", "outcome"),
+		expand: registry.NewCounterVec("shortener_expand_total",
+			"Short code lookups by outcome.", "outcome"),
+		collisionDepth: registry.NewHistogramVec("shortener_collision_depth",
+			"Number of occupied codes probed before a free or matching code was found.", collisionBuckets(maxAttempts)),
+		storageDuration: registry.NewHistogramVec("shortener_storage_operation_duration_seconds",
+			"Storage operation latency by backend, operation and result.", metrics.DefaultBuckets,
+			"backend", "operation", "result"),
+	}
+}
+
+func (m *Metrics) observeShorten(mode, outcome string) {
+	if m != nil {
+		m.shorten.With(mode, outcome).Inc()
+	}
+}
+
+func (m *Metrics) observeExpand(err error) {
+	if m == nil {
+		return
+	}
+	outcome := OutcomeFound
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		outcome = OutcomeNotFound
+	case errors.Is(err, storage.ErrExpired):
+		outcome = OutcomeExpired
+	case err != nil:
+		outcome = OutcomeError
+	}
+	m.expand.With(outcome).Inc()
+}
+
+func (m *Metrics) observeCollisionDepth(id int) {
+	if m != nil {
+		m.collisionDepth.With().Observe(float64(id))
+	}
+}
+
+// Исход сокращения по ошибке сервиса и признаку уже сохранённой ссылки
+func shortenOutcome(err error, exists bool) string {
+	switch {
+	case err == nil && exists:
+		return OutcomeExisting
+	case err == nil:
+		return OutcomeCreated
+	case errors.Is(err, ErrAliasTaken), errors.Is(err, storage.ErrAlreadyExists):
+		return OutcomeTaken
+	case errors.Is(err, ErrInvalidURL), errors.Is(err, ErrInvalidAlias), errors.Is(err, ErrInvalidExpiry):
+		return OutcomeInvalid
+	}
+	return OutcomeError
+}
+
+// Хранилище, измеряющее длительность операций вложенного хранилища backend
+type MetricsStorage struct {
+	storage Storage
+	backend string
+	metrics *Metrics
+}
+
+func NewMetricsStorage(storage Storage, backend string, metrics *Metrics) *MetricsStorage {
+	return &MetricsStorage{storage: storage, backend: backend, metrics: metrics}
+}
+
+// ErrNotFound, ErrExpired и ErrAlreadyExists - штатные ответы хранилища, а не сбои
+func (s *MetricsStorage) observe(operation string, start time.Time, err error) {
+	if s.metrics == nil {
+		return
+	}
+	result := "ok"
+	if err != nil && !errors.Is(err, storage.ErrNotFound) && !errors.Is(err, storage.ErrExpired) &&
+		!errors.Is(err, storage.ErrAlreadyExists) {
+		result = "error"
+	}
+	s.metrics.storageDuration.With(s.backend, operation, result).Observe(time.Since(start).Seconds())
+}
+
+func (s *MetricsStorage) GetLongUrl(ctx context.Context, shortUrl string) (string, error) {
+	start := time.Now()
+	res, err := s.storage.GetLongUrl(ctx, shortUrl)
+	s.observe("get_long_url", start, err)
+	return res, err
+}
+
+func (s *MetricsStorage) GetLink(ctx context.Context, shortUrl string) (model.Link, error) {
+	start := time.Now()
+	res, err := s.storage.GetLink(ctx, shortUrl)
+	s.observe("get_link", start, err)
+	return res, err
+}
+
+func (s *MetricsStorage) Insert(ctx context.Context, link model.Link) error {
+	start := time.Now()
+	err := s.storage.Insert(ctx, link)
+	s.observe("insert", start, err)
+	return err
+}
+
+func (s *MetricsStorage) Allocate(ctx context.Context, link model.Link) (string, bool, error) {
+	start := time.Now()
+	shortUrl, exists, err := s.storage.Allocate(ctx, link)
+	s.observe("allocate", start, err)
+	return shortUrl, exists, err
+}
+
+func (s *MetricsStorage) AllocateBatch(ctx context.Context, links []model.Link) ([]model.Allocation, error) {
+	start := time.Now()
+	res, err := s.storage.AllocateBatch(ctx, links)
+	s.observe("allocate_batch", start, err)
+	return res, err
+}
+
+func (s *MetricsStorage) InsertBatch(ctx context.Context, links []model.Link) ([]error, error) {
+	start := time.Now()
+	errs, err := s.storage.InsertBatch(ctx, links)
+	s.observe("insert_batch", start, err)
+	return errs, err
+}
+
+func (s *MetricsStorage) Update(ctx context.Context, link model.Link) (model.Link, error) {
+	start := time.Now()
+	res, err := s.storage.Update(ctx, link)
+	s.observe("update", start, err)
+	return res, err
+}
+
+func (s *MetricsStorage) Delete(ctx context.Context, shortUrl, owner string) error {
+	start := time.Now()
+	err := s.storage.Delete(ctx, shortUrl, owner)
+	s.observe("delete", start, err)
+	return err
+}
+
+func (s *MetricsStorage) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	start := time.Now()
+	n, err := s.storage.DeleteExpired(ctx, now)
+	s.observe("delete_expired", start, err)
+	return n, err
+}
+
+// Экспортирует счётчики попаданий и промахов LRU-кэша
+func (s *LRUStorage) RegisterMetrics(registry *metrics.Registry) {
+	registry.NewCounterFunc("shortener_lru_hits_total", "Lookups served from the LRU cache.",
+		func() float64 { return float64(s.Hits()) })
+	registry.NewCounterFunc("shortener_lru_misses_total", "Lookups passed to the underlying storage.",
+		func() float64 { return float64(s.Misses()) })
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+var ErrInvalidURL = errors.New("invalid url")
+
+// Причины отклонения длинной ссылки
+const (
+	ReasonMalformed        = "malformed"
+	ReasonMissingScheme    = "missing_scheme"
+	ReasonSchemeNotAllowed = "scheme_not_allowed"
+	ReasonMissingHost      = "missing_host"
+	ReasonInvalidHost      = "invalid_host"
+	ReasonTooLong          = "too_long"
+)
+
+// Максимальная длина нормализованной ссылки в байтах. Запас до предела строки индекса
+// уникальности urls.long_url в PostgreSQL (около 2700 байт)
+const MaxURLLength = 2048
+
+var defaultSchemes = []string{"http", "https"}
+
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+type URLValidationError struct {
+	Reason string
+	Detail string
+}
+
+func (e *URLValidationError) Error() string {
+	return ErrInvalidURL.Error() + ": " + e.Detail
+}
+
+func (e *URLValidationError) Unwrap() error {
+	return ErrInvalidURL
+}
+
+// Правила приведения длинной ссылки к каноническому виду перед вычислением хэша.
+// Нулевое значение разрешает схемы http и https и не меняет запрос и фрагмент
+type URLNormalizer struct {
+	AllowedSchemes []string
+	SortQuery      bool // Сортировать параметры запроса по имени
+	StripFragment  bool // Отбрасывать фрагмент (#...)
+}
+
+// Проверяет ссылку и приводит её к каноническому виду: схема и хост в нижнем регистре,
+// IDN-хост в punycode, без порта по умолчанию, пустой путь заменяется на "/".
+// Ссылка длиннее MaxURLLength отклоняется
+func (n URLNormalizer) Normalize(rawUrl string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(rawUrl))
+	if err != nil {
+		return "", &URLValidationError{Reason: ReasonMalformed, Detail: err.Error()}
+	}
+	if u.Scheme == "" {
+		return "", &URLValidationError{Reason: ReasonMissingScheme, Detail: "scheme is required"}
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	if !n.schemeAllowed(u.Scheme) {
+		return "", &URLValidationError{Reason: ReasonSchemeNotAllowed, Detail: "scheme " + u.Scheme + " is not allowed"}
+	}
+	if u.Opaque != "" || u.Hostname() == "" {
+		return "", &URLValidationError{Reason: ReasonMissingHost, Detail: "host is required"}
+	}
+
+	host := u.Hostname()
+	if strings.Contains(host, ":") {
+		host = "[" + strings.ToLower(host) + "]" // IPv6-литерал
+	} else if host, err = idna.Lookup.ToASCII(strings.TrimSuffix(host, ".")); err != nil || host == "" {
+		return "", &URLValidationError{Reason: ReasonInvalidHost, Detail: "invalid host " + u.Hostname()}
+	}
+	if port := u.Port(); port != "" && port != defaultPorts[u.Scheme] {
+		host += ":" + port
+	}
+	u.Host = host
+
+	if u.Path == "" {
+		u.Path = "/"
+		u.RawPath = ""
+	}
+	if n.SortQuery && u.RawQuery != "" {
+		u.RawQuery = u.Query().Encode() // Encode сортирует параметры по имени
+	}
+	if n.StripFragment {
+		u.Fragment = ""
+		u.RawFragment = ""
+	}
+	normalized := u.String()
+	if len(normalized) > MaxURLLength {
+		return "", &URLValidationError{Reason: ReasonTooLong, Detail: fmt.Sprintf("url must not exceed %d bytes", MaxURLLength)}
+	}
+	return normalized, nil
+}
+
+func (n URLNormalizer) schemeAllowed(scheme string) bool {
+	schemes := n.AllowedSchemes
+	if len(schemes) == 0 {
+		schemes = defaultSchemes
+	}
+	for _, allowed := range schemes {
+		if strings.EqualFold(allowed, scheme) {
+			return true
+		}
+	}
+	return false
+}
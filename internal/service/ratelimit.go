@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Политика ограничения частоты запросов: Rate запросов в секунду в среднем
+// и не более Burst запросов подряд
+type RateLimitPolicy struct {
+	Rate  float64
+	Burst int
+}
+
+func (p RateLimitPolicy) Enabled() bool {
+	return p.Rate > 0 && p.Burst > 0
+}
+
+// Интервал между запросами при средней частоте Rate
+func (p RateLimitPolicy) Interval() time.Duration {
+	return time.Duration(float64(time.Second) / p.Rate)
+}
+
+type RateLimitResult struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration // Через сколько можно повторить отклонённый запрос
+	Reset      time.Duration // Через сколько лимит восстановится полностью
+}
+
+// Хранилище состояния лимитов. Take атомарно списывает cost запросов для ключа: все или ни одного
+type RateLimitStore interface {
+	Take(ctx context.Context, key string, policy RateLimitPolicy, cost int, now time.Time) (RateLimitResult, error)
+}
+
+// Реализация token bucket через GCRA: вместо количества токенов хранится tat -
+// теоретическое время, к которому корзина восстановится полностью.
+// Запрос стоимостью cost сдвигает tat на cost интервалов, поэтому cost больше Burst не проходит никогда.
+// Возвращает новое значение tat (если запрос разрешён, иначе прежнее) и результат проверки
+func ApplyGCRA(tat, now time.Time, policy RateLimitPolicy, cost int) (time.Time, RateLimitResult) {
+	interval := policy.Interval()
+	tolerance := time.Duration(policy.Burst) * interval
+	if tat.Before(now) {
+		tat = now
+	}
+	newTat := tat.Add(time.Duration(cost) * interval)
+	res := RateLimitResult{Limit: policy.Burst}
+
+	if newTat.Sub(now) > tolerance {
+		res.RetryAfter = newTat.Sub(now) - tolerance
+		res.Reset = tat.Sub(now)
+		return tat, res
+	}
+
+	res.Allowed = true
+	res.Remaining = int(math.Floor(float64(tolerance-newTat.Sub(now)) / float64(interval)))
+	res.Reset = newTat.Sub(now)
+	return newTat, res
+}
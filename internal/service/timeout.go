@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"url-shortener/internal/model"
+)
+
+// Ограничения времени на одну операцию хранилища, нулевое значение отключает ограничение
+type Timeouts struct {
+	Read  time.Duration // GetLongUrl, GetLink
+	Write time.Duration // Insert, Update, Delete, DeleteExpired
+	Batch time.Duration // InsertBatch
+}
+
+// Хранилище, ограничивающее время каждой операции вложенного хранилища.
+// Ограничение добавляется к контексту вызова, более ранний срок контекста сохраняется
+type TimeoutStorage struct {
+	storage  Storage
+	timeouts Timeouts
+}
+
+func NewTimeoutStorage(storage Storage, timeouts Timeouts) *TimeoutStorage {
+	return &TimeoutStorage{storage: storage, timeouts: timeouts}
+}
+
+func (s *TimeoutStorage) GetLongUrl(ctx context.Context, shortUrl string) (string, error) {
+	ctx, cancel := withTimeout(ctx, s.timeouts.Read)
+	defer cancel()
+	return s.storage.GetLongUrl(ctx, shortUrl)
+}
+
+func (s *TimeoutStorage) GetLink(ctx context.Context, shortUrl string) (model.Link, error) {
+	ctx, cancel := withTimeout(ctx, s.timeouts.Read)
+	defer cancel()
+	return s.storage.GetLink(ctx, shortUrl)
+}
+
+func (s *TimeoutStorage) Insert(ctx context.Context, link model.Link) error {
+	ctx, cancel := withTimeout(ctx, s.timeouts.Write)
+	defer cancel()
+	return s.storage.Insert(ctx, link)
+}
+
+func (s *TimeoutStorage) Allocate(ctx context.Context, link model.Link) (string, bool, error) {
+	ctx, cancel := withTimeout(ctx, s.timeouts.Write)
+	defer cancel()
+	return s.storage.Allocate(ctx, link)
+}
+
+func (s *TimeoutStorage) AllocateBatch(ctx context.Context, links []model.Link) ([]model.Allocation, error) {
+	ctx, cancel := withTimeout(ctx, s.timeouts.Batch)
+	defer cancel()
+	return s.storage.AllocateBatch(ctx, links)
+}
+
+func (s *TimeoutStorage) InsertBatch(ctx context.Context, links []model.Link) ([]error, error) {
+	ctx, cancel := withTimeout(ctx, s.timeouts.Batch)
+	defer cancel()
+	return s.storage.InsertBatch(ctx, links)
+}
+
+func (s *TimeoutStorage) Update(ctx context.Context, link model.Link) (model.Link, error) {
+	ctx, cancel := withTimeout(ctx, s.timeouts.Write)
+	defer cancel()
+	return s.storage.Update(ctx, link)
+}
+
+func (s *TimeoutStorage) Delete(ctx context.Context, shortUrl, owner string) error {
+	ctx, cancel := withTimeout(ctx, s.timeouts.Write)
+	defer cancel()
+	return s.storage.Delete(ctx, shortUrl, owner)
+}
+
+func (s *TimeoutStorage) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	ctx, cancel := withTimeout(ctx, s.timeouts.Write)
+	defer cancel()
+	return s.storage.DeleteExpired(ctx, now)
+}
+
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
@@ -1,47 +1,154 @@
 package service
 
 import (
+	"context"
 	"crypto/sha256"
 	"errors"
+	"time"
+	"url-shortener/internal/model"
+	"url-shortener/pkg/alphabet"
 	"url-shortener/pkg/storage"
 )
 
-const Alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz_"
-const hashLength = 8                   // Длина желаемого хэша
-const maxIndex = len(Alphabet) * len(Alphabet) - 1 // Максимальное кол-во коллизий для одного хэша
+const Alphabet = alphabet.Legacy
+const hashLength = 8 // Длина желаемого хэша
 
+// Код и длинная ссылка уникальны: ссылка хранится не более чем под одним кодом.
+// GetLongUrl для истёкшей ссылки возвращает storage.ErrExpired,
+// Insert может занять код, срок действия которого истёк.
+// InsertBatch сохраняет пакет ссылок и возвращает ошибку для каждой из них
+// (storage.ErrAlreadyExists, если код занят или ссылка уже сохранена), либо общую ошибку, если не сохранена ни одна.
+// Update меняет длинную ссылку и срок действия существующей записи и возвращает её новое состояние,
+// пустая длинная ссылка и нулевой срок оставляют прежние значения, срок model.NoExpiry делает ссылку бессрочной. Update и Delete с непустым владельцем
+// одним атомарным шагом проверяют, что запись принадлежит ему, и для чужой записи возвращают storage.ErrForbidden.
+// Длинная ссылка, уже сохранённая под другим кодом, - storage.ErrAlreadyExists.
+// Allocate одним атомарным шагом занимает код link.ShortURL (свободный или истёкший) либо, если длинная ссылка
+// уже сохранена под любым кодом, возвращает этот код с exists = true.
+// Код, занятый другой ссылкой, - storage.ErrAlreadyExists.
+// AllocateBatch делает то же для каждой ссылки пакета за одно обращение к хранилищу и возвращает
+// результат для каждой из них, либо общую ошибку, если пакет не обработан
 type Storage interface {
-	GetLongUrl(shortUrl string) (string, error)
-	Insert(shortUrl, longUrl string) error
+	GetLongUrl(ctx context.Context, shortUrl string) (string, error)
+	GetLink(ctx context.Context, shortUrl string) (model.Link, error)
+	Insert(ctx context.Context, link model.Link) error
+	Allocate(ctx context.Context, link model.Link) (shortUrl string, exists bool, err error)
+	AllocateBatch(ctx context.Context, links []model.Link) ([]model.Allocation, error)
+	InsertBatch(ctx context.Context, links []model.Link) ([]error, error)
+	Update(ctx context.Context, link model.Link) (model.Link, error)
+	Delete(ctx context.Context, shortUrl, owner string) error
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
 }
 
 type ShortenerService struct {
-	Storage Storage
+	Storage    Storage
+	Normalizer URLNormalizer
+	Generator  CodeGenerator       // Стратегия генерации кодов, по умолчанию хэш с параметрами DefaultCodeOptions
+	Metrics    *Metrics            // Необязательные метрики исходов и глубины перебора коллизий
+	Fold       func(string) string // Приведение регистра регистронезависимых кодов, nil - коды различаются регистром
 }
 
-func NewShortenerService(Storage Storage) *ShortenerService {
-	return &ShortenerService{Storage: Storage}
+func NewShortenerService(Storage Storage, Normalizer URLNormalizer) *ShortenerService {
+	return &ShortenerService{Storage: Storage, Normalizer: Normalizer, Generator: NewHashGenerator(DefaultCodeOptions)}
 }
 
-func (s ShortenerService) Shortening(longUrl string) (string, error) {
-	id := 0
-	hash := EncodeHash(longUrl)
-	for id < maxIndex {
-		shortUrl := hash + IntToIndex63(id)
-		longCheck, err := s.Storage.GetLongUrl(shortUrl)
-		if err == storage.ErrNotFound {
-			err = s.Storage.Insert(shortUrl, longUrl)
-			return shortUrl, err
-		} else if longCheck == longUrl {
-			return shortUrl, err
+var errNoFreeCode = errors.New("no free short code found")
+
+// Короткий код подбирает Generator по link.LongURL, значение link.ShortURL игнорируется
+func (s ShortenerService) Shortening(ctx context.Context, link model.Link) (string, error) {
+	shortUrl, exists, err := s.shorten(ctx, link)
+	s.Metrics.observeShorten(s.Generator.Name(), shortenOutcome(err, exists))
+	return shortUrl, err
+}
+
+// Проверка и занятие кода выполняются хранилищем за один шаг, поэтому параллельные запросы
+// не получат один код для разных ссылок. Код, занятый другой ссылкой, - коллизия, запрашивается следующая попытка
+func (s ShortenerService) shorten(ctx context.Context, link model.Link) (shortUrl string, exists bool, err error) {
+	if link.LongURL, err = s.Normalizer.Normalize(link.LongURL); err != nil {
+		return "", false, err
+	}
+	for attempt := 0; attempt < s.Generator.MaxAttempts(); attempt++ {
+		if link.ShortURL, err = s.Generator.Code(ctx, link.LongURL, attempt); err != nil {
+			return "", false, err
+		}
+		shortUrl, exists, err = s.Storage.Allocate(ctx, link)
+		if errors.Is(err, storage.ErrAlreadyExists) {
+			continue
+		}
+		if err == nil {
+			s.Metrics.observeCollisionDepth(attempt)
+		}
+		return shortUrl, exists, err
+	}
+	return "", false, errNoFreeCode
+}
+
+// Сохраняет длинную ссылку под выбранным пользователем кодом link.ShortURL.
+// Повторный запрос с тем же кодом и той же ссылкой возвращает этот код
+func (s ShortenerService) CustomShortening(ctx context.Context, link model.Link) (string, error) {
+	shortUrl, exists, err := s.customShorten(ctx, link)
+	s.Metrics.observeShorten("custom", shortenOutcome(err, exists))
+	return shortUrl, err
+}
+
+func (s ShortenerService) customShorten(ctx context.Context, link model.Link) (shortUrl string, exists bool, err error) {
+	if err := s.validateAlias(link.ShortURL); err != nil {
+		return "", false, err
+	}
+	if link.LongURL, err = s.Normalizer.Normalize(link.LongURL); err != nil {
+		return "", false, err
+	}
+	exists, err = s.checkAlias(ctx, link)
+	if err != nil {
+		return "", false, err
+	}
+	if exists {
+		return link.ShortURL, true, nil
+	}
+
+	if err := s.Storage.Insert(ctx, link); err != nil {
+		if !errors.Is(err, storage.ErrAlreadyExists) {
+			return "", false, err
+		}
+		// Параллельный запрос мог успеть сохранить этот же код с этой же ссылкой.
+		// Свободный код означает, что ссылка уже сохранена под другим
+		exists, err := s.checkAlias(ctx, link)
+		if err != nil {
+			return "", false, err
+		}
+		if !exists {
+			return "", false, ErrAliasTaken
 		}
-		id++
+		return link.ShortURL, true, nil
 	}
-	return "", errors.New("index out of range")
+	return link.ShortURL, false, nil
 }
 
-func (s ShortenerService) Expansion(shortUrl string) (string, error) {
-	res, err := s.Storage.GetLongUrl(shortUrl)
+// Пользовательский код составляется из символов алфавита генератора. Регистронезависимый код
+// проверяется в регистре алфавита, в котором он и будет сохранён
+func (s ShortenerService) validateAlias(alias string) error {
+	if s.Fold != nil {
+		alias = s.Fold(alias)
+	}
+	return ValidateAlias(alias, s.Generator.Alphabet())
+}
+
+// Проверяет, свободен ли пользовательский код. exists - код уже занят этой же ссылкой
+func (s ShortenerService) checkAlias(ctx context.Context, link model.Link) (exists bool, err error) {
+	longCheck, err := s.Storage.GetLongUrl(ctx, link.ShortURL)
+	if err == nil {
+		if longCheck == link.LongURL {
+			return true, nil
+		}
+		return false, ErrAliasTaken
+	} else if err != storage.ErrNotFound && err != storage.ErrExpired {
+		return false, err
+	}
+	return false, nil
+}
+
+func (s ShortenerService) Expansion(ctx context.Context, shortUrl string) (string, error) {
+	res, err := s.Storage.GetLongUrl(ctx, shortUrl)
+	s.Metrics.observeExpand(err)
 	return res, err
 }
 
@@ -55,23 +162,21 @@ func (s ShortenerService) Expansion(shortUrl string) (string, error) {
 
 // Функция для преобразования байтов в строку фиксированной длины
 func EncodeHash(input string) string {
-	hasher := sha256.New()
-	hasher.Write([]byte(input))
-	hash := hasher.Sum(nil)
-
-	result := ""
-	for i := 0; i < hashLength; i++ {
-		index := int(hash[i]) % len(Alphabet) // Получаем индекс из хэша
-		result += string(Alphabet[index])     // Добавляем символ из алфавита
-	}
-	return result
+	return EncodeHashWith(input, Alphabet, hashLength)
+}
+
+// Хэш SHA-256 строки в виде length символов из alphabet
+func EncodeHashWith(input, alphabet string, length int) string {
+	hash := sha256.Sum256([]byte(input))
+	return encodeDigest(hash[:], alphabet, length)
 }
 
 // Функция для преобразования числа из 10-тичной системы счисления в двухразрядное число в 63-ной системе
 func IntToIndex63(id int) string {
-	res := ""
-	res += string(Alphabet[id/len(Alphabet)])
-	res += string(Alphabet[id%len(Alphabet)])
-	return res
+	return IntToIndex(id, Alphabet, suffixLength)
+}
 
+// Число в системе счисления по основанию len(alphabet), ровно width разрядов (старшие отбрасываются)
+func IntToIndex(id int, alphabet string, width int) string {
+	return encodeIndex(id, alphabet, width)
 }
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS clicks (
+  id BIGSERIAL PRIMARY KEY,
+  short_url varchar(10) NOT NULL,
+  clicked_at timestamptz NOT NULL DEFAULT now(),
+  referrer text NOT NULL DEFAULT '',
+  user_agent text NOT NULL DEFAULT '',
+  client_ip varchar(45) NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS clicks_short_url_clicked_at_idx ON clicks (short_url, clicked_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS clicks;
+-- +goose StatementEnd
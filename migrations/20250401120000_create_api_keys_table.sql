@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS api_keys (
+  id varchar(32) PRIMARY KEY,
+  name text NOT NULL,
+  key_hash char(64) UNIQUE NOT NULL,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  revoked_at timestamptz NULL
+);
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS owner varchar(32) NULL REFERENCES api_keys (id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE urls DROP COLUMN IF EXISTS owner;
+DROP TABLE IF EXISTS api_keys;
+-- +goose StatementEnd
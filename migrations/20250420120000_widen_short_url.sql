@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE urls ALTER COLUMN short_url TYPE varchar(32);
+ALTER TABLE clicks ALTER COLUMN short_url TYPE varchar(32);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+-- Откат невозможен, пока сохранены коды длиннее 10 символов
+ALTER TABLE clicks ALTER COLUMN short_url TYPE varchar(10);
+ALTER TABLE urls ALTER COLUMN short_url TYPE varchar(10);
+-- +goose StatementEnd
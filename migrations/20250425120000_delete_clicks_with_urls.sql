@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Переходы удалённых ранее ссылок не относятся ни к одной записи urls и мешают внешнему ключу
+DELETE FROM clicks WHERE NOT EXISTS (SELECT 1 FROM urls WHERE urls.short_url = clicks.short_url);
+ALTER TABLE clicks ADD CONSTRAINT clicks_short_url_fkey
+  FOREIGN KEY (short_url) REFERENCES urls (short_url) ON DELETE CASCADE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE clicks DROP CONSTRAINT IF EXISTS clicks_short_url_fkey;
+-- +goose StatementEnd
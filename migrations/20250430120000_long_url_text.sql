@@ -0,0 +1,7 @@
+-- +goose Up
+-- Длина ссылки ограничивается сервисом (не больше 2048 байт), колонка её не ограничивает
+ALTER TABLE urls ALTER COLUMN long_url TYPE text;
+
+-- +goose Down
+-- Откат невозможен, пока сохранены ссылки длиннее 255 символов
+ALTER TABLE urls ALTER COLUMN long_url TYPE varchar(255);
@@ -0,0 +1,8 @@
+// Package migrations содержит SQL-миграции схемы PostgreSQL в формате goose,
+// встроенные в исполняемый файл сервера
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,71 @@
+// Пакет alphabet содержит наборы символов коротких кодов и проверку пользовательских наборов
+package alphabet
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// Исходный алфавит сервиса: цифры, латинские буквы и "_"
+	Legacy = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz_"
+	// Цифры и латинские буквы обоих регистров
+	Base62 = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	// Base32 Дугласа Крокфорда: цифры и заглавные буквы без I, L, O и U
+	Crockford32 = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+	// Цифры и строчные латинские буквы
+	Lowercase = "0123456789abcdefghijklmnopqrstuvwxyz"
+	// Без символов, которые легко спутать при чтении: 0/O/o, 1/I/l/i
+	Human = "23456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghjkmnpqrstuvwxyz"
+)
+
+// Наборы символов по именам
+var Presets = map[string]string{
+	"legacy":      Legacy,
+	"base62":      Base62,
+	"crockford32": Crockford32,
+	"lowercase":   Lowercase,
+	"human":       Human,
+}
+
+// Возвращает набор символов по имени, а значение, не совпадающее с именем, - как есть
+func Resolve(s string) string {
+	if chars, ok := Presets[strings.ToLower(s)]; ok {
+		return chars
+	}
+	return s
+}
+
+// Проверяет набор символов: не меньше двух различных символов, только латинские буквы, цифры и "_",
+// чтобы коды оставались допустимыми пользовательскими кодами и не требовали экранирования в URL
+func Validate(chars string) error {
+	if len(chars) < 2 {
+		return errors.New("must contain at least 2 characters")
+	}
+	seen := make(map[rune]bool)
+	for _, r := range chars {
+		if !(r >= '0' && r <= '9' || r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' || r == '_') {
+			return fmt.Errorf("character %q is not a latin letter, digit or _", r)
+		}
+		if seen[r] {
+			return fmt.Errorf("character %q repeats", r)
+		}
+		seen[r] = true
+	}
+	return nil
+}
+
+// Функция приведения кода к регистру набора символов для регистронезависимого поиска.
+// ok = false, если в наборе есть буквы обоих регистров
+func Fold(chars string) (fold func(string) string, ok bool) {
+	hasUpper := strings.ContainsAny(chars, "ABCDEFGHIJKLMNOPQRSTUVWXYZ")
+	hasLower := strings.ContainsAny(chars, "abcdefghijklmnopqrstuvwxyz")
+	switch {
+	case hasUpper && hasLower:
+		return nil, false
+	case hasUpper:
+		return strings.ToUpper, true
+	}
+	return strings.ToLower, true
+}
@@ -0,0 +1,154 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// Сокращает ссылку и возвращает короткий код. Повторяется только после ответа 429 и ошибки соединения:
+// после 5xx ссылка могла быть сохранена, а повтор с псевдонимом или сроком действия вернёт другой результат
+func (c *Client) Shorten(ctx context.Context, req ShortenRequest) (string, error) {
+	var resp struct {
+		ShortURL string `json:"short_url"`
+	}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/shorten", token: c.apiKey, body: req}, &resp)
+	return resp.ShortURL, err
+}
+
+// Сокращает пакет ссылок. Ошибки отдельных ссылок возвращаются в результатах (см. BatchResult.Err),
+// ошибка метода означает, что пакет целиком не обработан
+func (c *Client) ShortenBatch(ctx context.Context, reqs []ShortenRequest) ([]BatchResult, error) {
+	if reqs == nil {
+		reqs = []ShortenRequest{}
+	}
+	var results []BatchResult
+	err := c.do(ctx, request{method: http.MethodPost, path: "/shorten/batch", token: c.apiKey, body: reqs}, &results)
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// Возвращает длинную ссылку по короткому коду. Запрос учитывается в статистике переходов
+func (c *Client) Expand(ctx context.Context, code string) (string, error) {
+	var resp struct {
+		LongURL string `json:"long_url"`
+	}
+	body := struct {
+		ShortURL string `json:"short_url"`
+	}{code}
+	err := c.do(ctx, request{method: http.MethodGet, path: "/expand", body: body, idempotent: true}, &resp)
+	return resp.LongURL, err
+}
+
+// Выполняет переход по короткой ссылке GET /{code} без следования перенаправлению
+// и возвращает адрес из заголовка Location
+func (c *Client) Resolve(ctx context.Context, code string) (string, error) {
+	resp, err := c.send(ctx, request{method: http.MethodGet, path: "/" + url.PathEscape(code), idempotent: true, noRedirect: true})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return "", readError(resp)
+	}
+	io.Copy(io.Discard, resp.Body)
+	location := resp.Header.Get("Location")
+	if resp.StatusCode < 300 || resp.StatusCode >= 400 || location == "" {
+		return "", fmt.Errorf("client: unexpected response %s without redirect", resp.Status)
+	}
+	return location, nil
+}
+
+// Сведения о короткой ссылке
+func (c *Client) GetLink(ctx context.Context, code string) (Link, error) {
+	var link Link
+	err := c.do(ctx, request{method: http.MethodGet, path: linkPath(code), token: c.apiKey, idempotent: true}, &link)
+	return link, err
+}
+
+// Меняет длинную ссылку и срок действия, незаполненные поля update не меняются
+func (c *Client) UpdateLink(ctx context.Context, code string, update LinkUpdate) (Link, error) {
+	var link Link
+	err := c.do(ctx, request{method: http.MethodPatch, path: linkPath(code), token: c.apiKey, body: update, idempotent: true}, &link)
+	return link, err
+}
+
+// Удаляет короткую ссылку. Если ответ на первую попытку потерян, повтор вернёт ErrNotFound
+func (c *Client) DeleteLink(ctx context.Context, code string) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: linkPath(code), token: c.apiKey, idempotent: true}, nil)
+}
+
+// Статистика переходов за последние days дней, при days <= 0 используется период сервера по умолчанию
+func (c *Client) Stats(ctx context.Context, code string, days int) (LinkStats, error) {
+	var query url.Values
+	if days > 0 {
+		query = url.Values{"days": {strconv.Itoa(days)}}
+	}
+	var stats LinkStats
+	err := c.do(ctx, request{method: http.MethodGet, path: linkPath(code) + "/stats", query: query, token: c.apiKey, idempotent: true}, &stats)
+	return stats, err
+}
+
+// Создаёт API-ключ. Значение ключа доступно только в возвращённом результате.
+// Повторяется только после ответа 429 и ошибки соединения, чтобы не создать лишний ключ
+func (c *Client) CreateKey(ctx context.Context, name string) (NewAPIKey, error) {
+	body := struct {
+		Name string `json:"name"`
+	}{name}
+	var key NewAPIKey
+	err := c.do(ctx, request{method: http.MethodPost, path: "/admin/keys", token: c.adminToken, body: body}, &key)
+	return key, err
+}
+
+func (c *Client) ListKeys(ctx context.Context) ([]APIKey, error) {
+	var keys []APIKey
+	err := c.do(ctx, request{method: http.MethodGet, path: "/admin/keys", token: c.adminToken, idempotent: true}, &keys)
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (c *Client) RevokeKey(ctx context.Context, id string) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: "/admin/keys/" + url.PathEscape(id), token: c.adminToken, idempotent: true}, nil)
+}
+
+// Проверяет, что процесс сервиса запущен (GET /healthz)
+func (c *Client) Health(ctx context.Context) error {
+	return c.do(ctx, request{method: http.MethodGet, path: "/healthz", idempotent: true}, nil)
+}
+
+// Готовность сервиса (GET /readyz). Состояние зависимостей возвращается и для неготового сервиса,
+// вместе с ошибкой ErrServer. Не повторяется: неготовность - ожидаемый ответ, а не сбой
+func (c *Client) Ready(ctx context.Context) (Readiness, error) {
+	var readiness Readiness
+	resp, err := c.send(ctx, request{method: http.MethodGet, path: "/readyz"})
+	if err != nil {
+		return readiness, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	if err != nil {
+		return readiness, err
+	}
+	if resp.StatusCode == http.StatusOK {
+		if err := json.Unmarshal(data, &readiness); err != nil {
+			return readiness, fmt.Errorf("client: decode response: %w", err)
+		}
+		return readiness, nil
+	}
+	if json.Unmarshal(data, &readiness) == nil && readiness.Status != "" {
+		return readiness, &Error{StatusCode: resp.StatusCode, Message: "service is " + readiness.Status}
+	}
+	return Readiness{}, parseError(resp, data)
+}
+
+func linkPath(code string) string {
+	return "/links/" + url.PathEscape(code)
+}
@@ -0,0 +1,215 @@
+// Пакет client - Go-клиент HTTP API сервиса сокращения ссылок.
+//
+//	c, err := client.New("http://localhost:8080", client.WithAPIKey(key))
+//	code, err := c.Shorten(ctx, client.ShortenRequest{LongURL: "https://example.com"})
+//	if errors.Is(err, client.ErrConflict) { ... }
+//
+// Ответы 429 и 5xx повторяются с экспоненциальной задержкой (см. RetryPolicy),
+// для сервиса, слушающего unix-сокет (LISTEN_TYPE=socket), используется WithUnixSocket
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const defaultBaseURL = "http://localhost:8080"
+
+// Сколько байт тела ответа с ошибкой читается для сообщения
+const maxErrorBody = 64 << 10
+
+// Клиент HTTP API сервиса сокращения ссылок. Безопасен для одновременного использования
+type Client struct {
+	baseURL    *url.URL
+	apiKey     string
+	adminToken string
+	httpClient *http.Client
+	retry      RetryPolicy
+}
+
+type Option func(*Client)
+
+// API-ключ для эндпоинтов, создающих ссылки и управляющих ими
+func WithAPIKey(key string) Option {
+	return func(c *Client) { c.apiKey = key }
+}
+
+// Токен администратора для управления ключами доступа
+func WithAdminToken(token string) Option {
+	return func(c *Client) { c.adminToken = token }
+}
+
+// HTTP-клиент для запросов, по умолчанию http.DefaultClient
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) { c.httpClient = httpClient }
+}
+
+// Подключение к сервису через unix-сокет (например app.sock при LISTEN_TYPE=socket).
+// Хост из адреса сервиса в этом случае передаётся только в заголовке Host
+func WithUnixSocket(path string) Option {
+	return func(c *Client) {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.Proxy = nil
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", path)
+		}
+		c.httpClient = &http.Client{Transport: transport}
+	}
+}
+
+// Политика повторов, по умолчанию DefaultRetryPolicy
+func WithRetry(policy RetryPolicy) Option {
+	return func(c *Client) { c.retry = policy }
+}
+
+// Создаёт клиент для сервиса по адресу baseURL, например http://localhost:8080.
+// Пустой адрес означает http://localhost:8080
+func New(baseURL string, opts ...Option) (*Client, error) {
+	if baseURL == "" {
+		baseURL = defaultBaseURL
+	}
+	u, err := url.Parse(strings.TrimSuffix(baseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("client: invalid base URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("client: invalid base URL %q: expected http(s)://host[:port]", baseURL)
+	}
+	c := &Client{baseURL: u, httpClient: http.DefaultClient, retry: DefaultRetryPolicy}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// Описание запроса к API
+type request struct {
+	method string
+	path   string // Экранированный путь относительно адреса сервиса
+	query  url.Values
+	token  string
+	body   interface{}
+	// Повтор запроса не меняет результат, поэтому его можно повторить после 5xx и сетевой ошибки.
+	// Ответ 429 и ошибка установки соединения означают, что запрос не обработан, и повторяются всегда
+	idempotent bool
+	// Не следовать перенаправлениям, а вернуть ответ 3xx
+	noRedirect bool
+}
+
+// Выполняет запрос с телом в формате JSON и разбирает ответ в out.
+// Ответ с кодом 4xx или 5xx возвращается ошибкой *Error
+func (c *Client) do(ctx context.Context, req request, out interface{}) error {
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return readError(resp)
+	}
+	if out == nil {
+		_, err = io.Copy(io.Discard, resp.Body)
+		return err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("client: decode response: %w", err)
+	}
+	return nil
+}
+
+// Отправляет запрос, повторяя его по политике повторов. Тело ответа закрывает вызывающий
+func (c *Client) send(ctx context.Context, req request) (*http.Response, error) {
+	var body []byte
+	if req.body != nil {
+		var err error
+		if body, err = json.Marshal(req.body); err != nil {
+			return nil, err
+		}
+	}
+	httpClient := c.httpClient
+	if req.noRedirect {
+		noRedirect := *httpClient
+		noRedirect.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+		httpClient = &noRedirect
+	}
+
+	for attempt := 1; ; attempt++ {
+		httpReq, err := c.newRequest(ctx, req, body)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := httpClient.Do(httpReq)
+		if attempt >= c.retry.MaxAttempts || !retryable(req, resp, err) || ctx.Err() != nil {
+			return resp, err
+		}
+		wait := c.retry.backoff(attempt, resp)
+		// Повтор не успеет выполниться до срока контекста, возвращается последний результат
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, maxErrorBody))
+			resp.Body.Close()
+		}
+		if err := sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (c *Client) newRequest(ctx context.Context, req request, body []byte) (*http.Request, error) {
+	// path уже экранирован, экранирование базового адреса сохраняется
+	u := *c.baseURL
+	u.RawPath = c.baseURL.EscapedPath() + req.path
+	var err error
+	if u.Path, err = url.PathUnescape(u.RawPath); err != nil {
+		return nil, err
+	}
+	u.RawQuery = req.query.Encode()
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, u.String(), reader)
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Accept", "application/json")
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	if req.token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+req.token)
+	}
+	return httpReq, nil
+}
+
+func readError(resp *http.Response) error {
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	if err != nil {
+		return err
+	}
+	return parseError(resp, data)
+}
+
+// Разбирает тело ответа с ошибкой в формате ErrorResponse, иначе сообщением становится само тело
+func parseError(resp *http.Response, data []byte) *Error {
+	apiErr := &Error{StatusCode: resp.StatusCode, RetryAfter: retryAfter(resp)}
+	if json.Unmarshal(data, apiErr) != nil || apiErr.Message == "" {
+		apiErr.Message = strings.TrimSpace(string(data))
+		if apiErr.Message == "" {
+			apiErr.Message = http.StatusText(resp.StatusCode)
+		}
+	}
+	return apiErr
+}
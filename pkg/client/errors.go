@@ -0,0 +1,80 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Ошибки по классам ответов сервиса, проверяются через errors.Is:
+//
+//	if errors.Is(err, client.ErrNotFound) { ... }
+var (
+	ErrBadRequest   = errors.New("bad request")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrNotFound     = errors.New("not found")
+	ErrExpired      = errors.New("link expired")
+	ErrConflict     = errors.New("conflict")
+	ErrInvalidURL   = errors.New("invalid long url")
+	ErrInvalidTTL   = errors.New("ttl too long")
+	ErrTooLarge     = errors.New("batch too large")
+	ErrRateLimited  = errors.New("rate limited")
+	ErrServer       = errors.New("server error")
+)
+
+// Причина ответа 422 для времени жизни больше допустимого
+const reasonTTLTooLong = "ttl_too_long"
+
+// Ответ сервиса с кодом 4xx или 5xx. Поля повторяют ErrorResponse и ValidationErrorResponse сервиса
+type Error struct {
+	StatusCode int    `json:"-"`
+	Message    string `json:"message"`
+	Field      string `json:"field,omitempty"`  // Поле запроса с недопустимым значением для ответа 422
+	Reason     string `json:"reason,omitempty"` // Причина отклонения для ответа 422
+	// Задержка из заголовка Retry-After, с которой сервис предлагает повторить запрос (429)
+	RetryAfter time.Duration `json:"-"`
+}
+
+func (e *Error) Error() string {
+	if e.Reason != "" {
+		return fmt.Sprintf("%d %s: %s (%s)", e.StatusCode, http.StatusText(e.StatusCode), e.Message, e.Reason)
+	}
+	return fmt.Sprintf("%d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+func (e *Error) Is(target error) bool {
+	if e.StatusCode == http.StatusUnprocessableEntity && e.Reason == reasonTTLTooLong {
+		return target == ErrInvalidTTL
+	}
+	return statusError(e.StatusCode) == target
+}
+
+// Ошибка класса для HTTP-кода, nil для кодов без отдельного класса
+func statusError(status int) error {
+	switch status {
+	case http.StatusBadRequest:
+		return ErrBadRequest
+	case http.StatusUnauthorized:
+		return ErrUnauthorized
+	case http.StatusForbidden:
+		return ErrForbidden
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusGone:
+		return ErrExpired
+	case http.StatusConflict:
+		return ErrConflict
+	case http.StatusUnprocessableEntity:
+		return ErrInvalidURL
+	case http.StatusRequestEntityTooLarge:
+		return ErrTooLarge
+	case http.StatusTooManyRequests:
+		return ErrRateLimited
+	}
+	if status >= http.StatusInternalServerError {
+		return ErrServer
+	}
+	return nil
+}
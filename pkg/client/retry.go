@@ -0,0 +1,92 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Повторы запросов, получивших 429, 5xx или сетевую ошибку. Задержка перед повтором n
+// растёт как MinBackoff * 2^(n-1) со случайным разбросом до половины и не превышает MaxBackoff.
+// Заголовок Retry-After ответа имеет приоритет над расчётной задержкой
+type RetryPolicy struct {
+	MaxAttempts int // Число попыток вместе с первой, 1 и меньше отключает повторы
+	MinBackoff  time.Duration
+	MaxBackoff  time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 3, MinBackoff: 100 * time.Millisecond, MaxBackoff: 2 * time.Second}
+
+// Политика без повторов
+var NoRetry = RetryPolicy{MaxAttempts: 1}
+
+func retryable(req request, resp *http.Response, err error) bool {
+	if err != nil {
+		return req.idempotent || notSent(err)
+	}
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return true
+	case resp.StatusCode == http.StatusNotImplemented:
+		return false
+	default:
+		return resp.StatusCode >= http.StatusInternalServerError && req.idempotent
+	}
+}
+
+// Соединение не установлено, поэтому запрос не дошёл до сервера
+func notSent(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// Задержка перед повтором после попытки attempt
+func (p RetryPolicy) backoff(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if wait := retryAfter(resp); wait > 0 {
+			return wait
+		}
+	}
+	wait := p.MinBackoff
+	for i := 1; i < attempt && wait < p.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if p.MaxBackoff > 0 && wait > p.MaxBackoff {
+		wait = p.MaxBackoff
+	}
+	if wait <= 0 {
+		return 0
+	}
+	return wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))
+}
+
+// Значение заголовка Retry-After: число секунд или HTTP-дата
+func retryAfter(resp *http.Response) time.Duration {
+	header := resp.Header.Get("Retry-After")
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(header); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(header); err == nil {
+		return time.Until(at)
+	}
+	return 0
+}
+
+// Ожидает d или отмены контекста
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
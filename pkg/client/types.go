@@ -0,0 +1,99 @@
+package client
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Запрос на сокращение ссылки
+type ShortenRequest struct {
+	LongURL    string     `json:"long_url"`
+	Alias      string     `json:"alias,omitempty"`       // Желаемый короткий код
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`  // Момент истечения ссылки
+	TTLSeconds int64      `json:"ttl_seconds,omitempty"` // Время жизни ссылки в секундах
+}
+
+// Результат сокращения одной ссылки пакета
+type BatchResult struct {
+	ShortURL string `json:"short_url,omitempty"`
+	Status   int    `json:"status"` // HTTP-код результата, как для одиночного запроса
+	Error    string `json:"error,omitempty"`
+	Reason   string `json:"reason,omitempty"` // Причина отклонения длинной ссылки для статуса 422
+}
+
+// Ошибка сокращения ссылки в виде *Error, nil при успехе
+func (r BatchResult) Err() error {
+	if r.Status < 400 {
+		return nil
+	}
+	return &Error{StatusCode: r.Status, Message: r.Error, Reason: r.Reason}
+}
+
+type LinkStats struct {
+	ShortURL string        `json:"short_url"`
+	Total    int64         `json:"total"`
+	Daily    []DailyClicks `json:"daily"`
+}
+
+type DailyClicks struct {
+	Date   string `json:"date"` // День в формате YYYY-MM-DD (UTC)
+	Clicks int64  `json:"clicks"`
+}
+
+type APIKey struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Созданный ключ доступа, значение Key сервис показывает только один раз
+type NewAPIKey struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Key       string    `json:"key"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Сведения о короткой ссылке
+type Link struct {
+	ShortURL  string     `json:"short_url"`
+	LongURL   string     `json:"long_url"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // Отсутствует у бессрочных ссылок
+	Owner     string     `json:"owner,omitempty"`      // Идентификатор API-ключа, создавшего ссылку
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// Изменение короткой ссылки, незаполненные поля не меняются
+type LinkUpdate struct {
+	LongURL    string     `json:"long_url,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	TTLSeconds int64      `json:"ttl_seconds,omitempty"` // Новое время жизни в секундах от момента запроса
+	NoExpiry   bool       `json:"-"`                     // Сделать ссылку бессрочной: отправляется "expires_at": null
+}
+
+func (u LinkUpdate) MarshalJSON() ([]byte, error) {
+	type linkUpdate LinkUpdate
+	if !u.NoExpiry {
+		return json.Marshal(linkUpdate(u))
+	}
+	return json.Marshal(struct {
+		linkUpdate
+		ExpiresAt *time.Time `json:"expires_at"`
+	}{linkUpdate: linkUpdate(u)})
+}
+
+// Готовность сервиса и результаты проверки зависимостей
+type Readiness struct {
+	Status       string                      `json:"status"` // ok или unavailable
+	ShuttingDown bool                        `json:"shutting_down"`
+	Checks       map[string]DependencyStatus `json:"checks"`
+}
+
+type DependencyStatus struct {
+	Status     string `json:"status"`
+	Detail     string `json:"detail,omitempty"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
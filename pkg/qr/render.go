@@ -0,0 +1,114 @@
+package qr
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Параметры отрисовки
+type Options struct {
+	// Сторона изображения в пикселях. Модули масштабируются целым множителем, остаток уходит в поле.
+	// Если код с полем не помещается, каждый модуль занимает один пиксель
+	Size       int
+	Margin     int // Ширина светлого поля в модулях, стандарт требует не меньше 4
+	Foreground color.RGBA
+	Background color.RGBA
+}
+
+var (
+	Black = color.RGBA{A: 0xFF}
+	White = color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}
+)
+
+// Разбирает цвет в формате RRGGBB или RGB, с # или без
+func ParseColor(s string) (color.RGBA, error) {
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if len(hex) != 6 || err != nil {
+		return color.RGBA{}, fmt.Errorf("qr: invalid colour %q, expected RRGGBB or RGB in hex", s)
+	}
+	return color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xFF}, nil
+}
+
+// Сторона кода с полем в модулях
+func (c *Code) sizeWithMargin(margin int) int {
+	return c.Size + 2*margin
+}
+
+// Пикселей на модуль и сторона изображения
+func (c *Code) scale(opts Options) (scale, side int) {
+	modules := c.sizeWithMargin(opts.Margin)
+	if opts.Size < modules {
+		return 1, modules
+	}
+	return opts.Size / modules, opts.Size
+}
+
+// Двухцветное изображение кода
+func (c *Code) Image(opts Options) image.Image {
+	scale, side := c.scale(opts)
+	// Код по центру: поле шириной Margin модулей и остаток от деления стороны на модули
+	offset := (side - c.Size*scale) / 2
+	img := image.NewPaletted(image.Rect(0, 0, side, side), color.Palette{opts.Background, opts.Foreground})
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if !c.Black(x, y) {
+				continue
+			}
+			for py := 0; py < scale; py++ {
+				row := img.Pix[(offset+y*scale+py)*img.Stride:]
+				for px := 0; px < scale; px++ {
+					row[offset+x*scale+px] = 1
+				}
+			}
+		}
+	}
+	return img
+}
+
+// Записывает код в формате PNG
+func (c *Code) PNG(w io.Writer, opts Options) error {
+	encoder := png.Encoder{CompressionLevel: png.BestCompression}
+	return encoder.Encode(w, c.Image(opts))
+}
+
+// Записывает код в формате SVG. Координаты задаются в модулях, Size определяет размер изображения
+func (c *Code) SVG(w io.Writer, opts Options) error {
+	_, side := c.scale(opts)
+	modules := c.sizeWithMargin(opts.Margin)
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, `<?xml version="1.0" encoding="UTF-8"?>`+"\n")
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" version="1.1" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`+"\n",
+		side, side, modules, modules)
+	fmt.Fprintf(bw, `<rect width="100%%" height="100%%" fill="%s"/>`+"\n", hexColor(opts.Background))
+	fmt.Fprintf(bw, `<path fill="%s" d="`, hexColor(opts.Foreground))
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if !c.Black(x, y) {
+				continue
+			}
+			// Серия тёмных модулей строки рисуется одним прямоугольником
+			run := 1
+			for c.Black(x+run, y) {
+				run++
+			}
+			fmt.Fprintf(bw, "M%d %dh%dv1h-%dz", x+opts.Margin, y+opts.Margin, run, run)
+			x += run - 1
+		}
+	}
+	fmt.Fprint(bw, "\"/>\n</svg>\n")
+	return bw.Flush()
+}
+
+func hexColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
@@ -0,0 +1,113 @@
+package journal
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// Размер заголовка записи: длина данных и контрольная сумма CRC-32C
+const HeaderSize = 8
+
+// Ограничение на размер одной записи, защищает от чтения мусорной длины
+const MaxRecordSize = 16 << 20
+
+var ErrCorrupted = errors.New("journal: corrupted record")
+
+var table = crc32.MakeTable(crc32.Castagnoli)
+
+// Формат записи: длина (uint32, little-endian), CRC-32C данных (uint32), данные.
+// Возвращает количество записанных байт
+func Append(w io.Writer, payload []byte) (int, error) {
+	if len(payload) > MaxRecordSize {
+		return 0, errors.New("journal: record too large")
+	}
+	buf := make([]byte, HeaderSize+len(payload))
+	binary.LittleEndian.PutUint32(buf[0:4], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(buf[4:8], crc32.Checksum(payload, table))
+	copy(buf[HeaderSize:], payload)
+	return w.Write(buf)
+}
+
+// Последовательно читает записи, начиная с текущей позиции r, и передаёт их в fn вместе со смещением
+// записи относительно начала чтения. Возвращает длину корректной части. Неполная или повреждённая
+// последняя запись (оборванный при сбое хвост, в том числе заполненный нулями) не считается ошибкой:
+// вызывающий может обрезать по возвращённой длине. Повреждённая запись, за которой следуют другие данные,
+// означает порчу журнала - возвращается ErrCorrupted, отбрасывать последующие записи нельзя
+func Replay(r io.Reader, fn func(payload []byte, offset int64) error) (int64, error) {
+	br := bufio.NewReader(r)
+	var valid int64
+	header := make([]byte, HeaderSize)
+	for {
+		if _, err := io.ReadFull(br, header); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return valid, nil
+			}
+			return valid, err
+		}
+		size := binary.LittleEndian.Uint32(header[0:4])
+		if size == 0 || size > MaxRecordSize {
+			// Записи без данных не пишутся, поэтому такой заголовок - мусор или нули недописанного хвоста
+			return valid, checkTail(br, valid)
+		}
+		payload := make([]byte, size)
+		if _, err := io.ReadFull(br, payload); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return valid, nil
+			}
+			return valid, err
+		}
+		if crc32.Checksum(payload, table) != binary.LittleEndian.Uint32(header[4:8]) {
+			return valid, checkTail(br, valid)
+		}
+		if err := fn(payload, valid); err != nil {
+			return valid, err
+		}
+		valid += int64(HeaderSize + len(payload))
+	}
+}
+
+// Проверяет, что повреждённая запись по смещению offset - оборванный хвост: после неё нет данных
+// или только нули (файловая система могла увеличить длину файла раньше, чем записала данные)
+func checkTail(r io.Reader, offset int64) error {
+	rest, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if zeros(rest) {
+		return nil
+	}
+	return fmt.Errorf("%w at offset %d", ErrCorrupted, offset)
+}
+
+func zeros(b []byte) bool {
+	for _, c := range b {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Читает одну запись по смещению
+func ReadAt(r io.ReaderAt, offset int64) ([]byte, error) {
+	header := make([]byte, HeaderSize)
+	if _, err := r.ReadAt(header, offset); err != nil {
+		return nil, err
+	}
+	size := binary.LittleEndian.Uint32(header[0:4])
+	if size > MaxRecordSize {
+		return nil, ErrCorrupted
+	}
+	payload := make([]byte, size)
+	if _, err := r.ReadAt(payload, offset+HeaderSize); err != nil {
+		return nil, err
+	}
+	if crc32.Checksum(payload, table) != binary.LittleEndian.Uint32(header[4:8]) {
+		return nil, ErrCorrupted
+	}
+	return payload, nil
+}
@@ -0,0 +1,27 @@
+package postgres
+
+import (
+	"url-shortener/pkg/metrics"
+)
+
+// Экспортирует статистику пула соединений, значения читаются из pgxpool при каждом запросе метрик
+func (p Pool) RegisterMetrics(registry *metrics.Registry) {
+	registry.NewGaugeFunc("pgxpool_total_conns", "Total number of connections in the pool.",
+		func() float64 { return float64(p.Stat().TotalConns()) })
+	registry.NewGaugeFunc("pgxpool_acquired_conns", "Number of connections currently in use.",
+		func() float64 { return float64(p.Stat().AcquiredConns()) })
+	registry.NewGaugeFunc("pgxpool_idle_conns", "Number of idle connections in the pool.",
+		func() float64 { return float64(p.Stat().IdleConns()) })
+	registry.NewGaugeFunc("pgxpool_constructing_conns", "Number of connections being established.",
+		func() float64 { return float64(p.Stat().ConstructingConns()) })
+	registry.NewGaugeFunc("pgxpool_max_conns", "Maximum size of the pool.",
+		func() float64 { return float64(p.Stat().MaxConns()) })
+	registry.NewCounterFunc("pgxpool_acquire_total", "Successful connection acquisitions from the pool.",
+		func() float64 { return float64(p.Stat().AcquireCount()) })
+	registry.NewCounterFunc("pgxpool_acquire_duration_seconds_total", "Total time spent acquiring connections.",
+		func() float64 { return p.Stat().AcquireDuration().Seconds() })
+	registry.NewCounterFunc("pgxpool_empty_acquire_total", "Acquisitions that had to wait for a connection.",
+		func() float64 { return float64(p.Stat().EmptyAcquireCount()) })
+	registry.NewCounterFunc("pgxpool_canceled_acquire_total", "Acquisitions canceled by context.",
+		func() float64 { return float64(p.Stat().CanceledAcquireCount()) })
+}
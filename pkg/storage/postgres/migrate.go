@@ -0,0 +1,145 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/pressly/goose/v3"
+	"github.com/pressly/goose/v3/lock"
+)
+
+// Ключ рекомендательной блокировки, которую держит экземпляр, применяющий миграции
+const migrationLockKey int64 = 0x75726c73686f7274 // "urlshort"
+
+var ErrNoMigrations = errors.New("no migrations to roll back")
+
+// Миграция в формате goose: файл <версия>_<название>.sql с разделами "-- +goose Up" и "-- +goose Down"
+type Migration struct {
+	Version int64
+	Name    string
+}
+
+type MigrationStatus struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Применяет и откатывает миграции средствами goose, журнал версий ведётся в таблице goose_db_version.
+// Соединения берутся из пула через отдельный *sql.DB, который освобождает Close
+type Migrator struct {
+	provider *goose.Provider
+	db       *sql.DB
+}
+
+func NewMigrator(pool Pool, fsys fs.FS) (*Migrator, error) {
+	locker, err := lock.NewPostgresSessionLocker(lock.WithLockID(migrationLockKey))
+	if err != nil {
+		return nil, err
+	}
+	db := stdlib.OpenDBFromPool(pool.Pool)
+	provider, err := goose.NewProvider(goose.DialectPostgres, db, fsys,
+		goose.WithSessionLocker(discardingLocker{locker}),
+		goose.WithDisableGlobalRegistry(true),
+	)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("load migrations: %w", err)
+	}
+	return &Migrator{provider: provider, db: db}, nil
+}
+
+// Закрывает *sql.DB мигратора, пул соединений остаётся открытым
+func (m *Migrator) Close() error {
+	return m.db.Close()
+}
+
+// Применяет все неприменённые миграции по возрастанию версии и возвращает применённые.
+// На время работы берётся рекомендательная блокировка, поэтому одновременно запущенные
+// экземпляры применяют миграции по очереди, а не параллельно
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	results, err := m.provider.Up(ctx)
+	done := make([]Migration, 0, len(results))
+	for _, res := range results {
+		done = append(done, newMigration(res.Source))
+	}
+	var partial *goose.PartialError
+	if errors.As(err, &partial) {
+		for _, res := range partial.Applied {
+			done = append(done, newMigration(res.Source))
+		}
+	}
+	return done, err
+}
+
+// Откатывает последнюю применённую миграцию
+func (m *Migrator) Down(ctx context.Context) (Migration, error) {
+	res, err := m.provider.Down(ctx)
+	if errors.Is(err, goose.ErrNoNextVersion) {
+		return Migration{}, ErrNoMigrations
+	}
+	if err != nil {
+		return Migration{}, err
+	}
+	return newMigration(res.Source), nil
+}
+
+// Состояние всех известных миграций
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	list, err := m.provider.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]MigrationStatus, len(list))
+	for i, s := range list {
+		statuses[i] = MigrationStatus{Migration: newMigration(s.Source), Applied: s.State == goose.StateApplied, AppliedAt: s.AppliedAt}
+	}
+	return statuses, nil
+}
+
+// Версия последней применённой миграции или 0, если миграции не применялись
+func (m *Migrator) Version(ctx context.Context) (int64, error) {
+	return m.provider.GetDBVersion(ctx)
+}
+
+// Версия последней известной миграции, до которой Up приводит базу
+func (m *Migrator) Latest() int64 {
+	sources := m.provider.ListSources()
+	if len(sources) == 0 {
+		return 0
+	}
+	return sources[len(sources)-1].Version
+}
+
+func newMigration(source *goose.Source) Migration {
+	return Migration{Version: source.Version, Name: strings.TrimSuffix(path.Base(source.Path), ".sql")}
+}
+
+// Блокировка сессии, которая при неудачном снятии закрывает соединение, а не возвращает его в пул:
+// рекомендательная блокировка снимается вместе с сессией, иначе она осталась бы на соединении пула
+// и остальные экземпляры не смогли бы применить миграции
+type discardingLocker struct {
+	lock.SessionLocker
+}
+
+func (l discardingLocker) SessionUnlock(ctx context.Context, conn *sql.Conn) error {
+	err := l.SessionLocker.SessionUnlock(ctx, conn)
+	if err != nil {
+		conn.Raw(func(driverConn any) error {
+			if c, ok := driverConn.(*stdlib.Conn); ok {
+				c.Conn().Close(ctx)
+			}
+			return driver.ErrBadConn
+		})
+		return fmt.Errorf("release migration lock: %w", err)
+	}
+	return nil
+}
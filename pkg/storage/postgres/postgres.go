@@ -2,7 +2,6 @@ package postgres
 
 import (
 	"context"
-	"fmt"
 	"time"
 
 	"errors"
@@ -21,11 +20,10 @@ type Pool struct {
 var ErrNotFound = pgx.ErrNoRows
 
 func NewClient(ctx context.Context, cfg config.DataBase) (Pool, error) {
-	connStr := fmt.Sprintf("postgresql://%s:%s@%s:%s/%s", cfg.Username, cfg.Password, cfg.Host, cfg.Port, cfg.DBName)
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	p, err := pgxpool.New(ctx, connStr)
+	p, err := pgxpool.New(ctx, cfg.DSN())
 	if err != nil {
 		return Pool{}, err
 	}
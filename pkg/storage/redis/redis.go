@@ -0,0 +1,352 @@
+package redis
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+
+	"url-shortener/config"
+)
+
+const dialTimeout = 5 * time.Second
+
+// Ответ сервера с ошибкой (-ERR ...)
+type Error string
+
+func (e Error) Error() string {
+	return string(e)
+}
+
+var errProtocol = errors.New("redis: protocol error")
+
+// Клиент протокола RESP с пулом соединений: открыто не больше PoolSize соединений,
+// запрос при занятых соединениях ждёт освобождения одного из них или отмены контекста.
+// Ответы возвращаются как string (простая и bulk-строка), int64, []interface{} или nil для пустого значения
+type Client struct {
+	addr     string
+	password string
+	db       int
+	idle     chan *conn
+	slots    chan struct{} // Занятые места открытых соединений
+}
+
+type conn struct {
+	net.Conn
+	r *bufio.Reader
+	w *bufio.Writer
+}
+
+func NewClient(ctx context.Context, cfg config.Redis) (*Client, error) {
+	poolSize := cfg.PoolSize
+	if poolSize <= 0 {
+		poolSize = 1
+	}
+	c := &Client{addr: cfg.Addr, password: cfg.Password, db: cfg.DB, idle: make(chan *conn, poolSize), slots: make(chan struct{}, poolSize)}
+
+	ctx, cancel := context.WithTimeout(ctx, dialTimeout)
+	defer cancel()
+	if err := c.Ping(ctx); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Проверяет доступность сервера
+func (c *Client) Ping(ctx context.Context) error {
+	_, err := c.Do(ctx, "PING")
+	return err
+}
+
+// Выполняет одну команду
+func (c *Client) Do(ctx context.Context, args ...string) (interface{}, error) {
+	replies, err := c.Pipeline(ctx, [][]string{args})
+	if err != nil {
+		return nil, err
+	}
+	if err, ok := replies[0].(Error); ok {
+		return nil, err
+	}
+	return replies[0], nil
+}
+
+// Отправляет команды за один сетевой обмен. Ошибки отдельных команд возвращаются
+// в ответах значениями Error, общая ошибка означает, что результат неизвестен
+func (c *Client) Pipeline(ctx context.Context, cmds [][]string) ([]interface{}, error) {
+	cn, err := c.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	replies, err := cn.exec(ctx, cmds)
+	if err != nil {
+		c.discard(cn)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	c.put(cn)
+	return replies, nil
+}
+
+// Наблюдаемый ключ изменился между WATCH и EXEC, транзакцию нужно повторить
+var ErrTxConflict = errors.New("redis: transaction aborted, watched key changed")
+
+// Соединение транзакции, на котором выполняются WATCH и чтение ключей до MULTI
+type Tx struct {
+	cn     *conn
+	ctx    context.Context
+	broken bool // Ошибка соединения, состояние сервера неизвестно
+}
+
+// Выполняет одну команду на соединении транзакции
+func (tx *Tx) Do(args ...string) (interface{}, error) {
+	replies, err := tx.Pipeline([][]string{args})
+	if err != nil {
+		return nil, err
+	}
+	if err, ok := replies[0].(Error); ok {
+		return nil, err
+	}
+	return replies[0], nil
+}
+
+// Отправляет команды на соединении транзакции за один сетевой обмен, как Client.Pipeline
+func (tx *Tx) Pipeline(cmds [][]string) ([]interface{}, error) {
+	replies, err := tx.cn.exec(tx.ctx, cmds)
+	if err != nil {
+		tx.broken = true
+		return nil, err
+	}
+	return replies, nil
+}
+
+// Транзакция с оптимистической блокировкой: fn на выделенном соединении наблюдает ключи (WATCH)
+// и читает их через tx.Do, а возвращённые команды выполняются атомарно в MULTI/EXEC.
+// Если наблюдаемый ключ изменился до EXEC, возвращается ErrTxConflict и транзакцию нужно повторить.
+// Ошибка fn возвращается без выполнения команд, пустой список команд - ничего не выполняет
+func (c *Client) Transaction(ctx context.Context, fn func(tx *Tx) ([][]string, error)) ([]interface{}, error) {
+	cn, err := c.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tx := &Tx{cn: cn, ctx: ctx}
+	cmds, err := fn(tx)
+	if tx.broken {
+		c.discard(cn)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	if err != nil || len(cmds) == 0 {
+		if _, unwatchErr := cn.exec(ctx, [][]string{{"UNWATCH"}}); unwatchErr != nil {
+			c.discard(cn)
+		} else {
+			c.put(cn)
+		}
+		return nil, err
+	}
+
+	pipeline := append([][]string{{"MULTI"}}, cmds...)
+	replies, err := cn.exec(ctx, append(pipeline, []string{"EXEC"}))
+	if err != nil {
+		c.discard(cn)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	c.put(cn)
+	switch reply := replies[len(replies)-1].(type) {
+	case nil:
+		return nil, ErrTxConflict
+	case Error:
+		return nil, reply
+	case []interface{}:
+		return reply, nil
+	default:
+		return nil, errProtocol
+	}
+}
+
+func (c *Client) Close() error {
+	for {
+		select {
+		case cn := <-c.idle:
+			c.discard(cn)
+		default:
+			return nil
+		}
+	}
+}
+
+// Берёт свободное соединение из пула или открывает новое, если открыто меньше PoolSize
+func (c *Client) get(ctx context.Context) (*conn, error) {
+	select {
+	case cn := <-c.idle:
+		return cn, nil
+	default:
+	}
+	select {
+	case cn := <-c.idle:
+		return cn, nil
+	case c.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	var d net.Dialer
+	nc, err := d.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		<-c.slots
+		return nil, err
+	}
+	cn := &conn{Conn: nc, r: bufio.NewReader(nc), w: bufio.NewWriter(nc)}
+
+	var init [][]string
+	if c.password != "" {
+		init = append(init, []string{"AUTH", c.password})
+	}
+	if c.db != 0 {
+		init = append(init, []string{"SELECT", strconv.Itoa(c.db)})
+	}
+	if len(init) == 0 {
+		return cn, nil
+	}
+	replies, err := cn.exec(ctx, init)
+	if err == nil {
+		for _, reply := range replies {
+			if replyErr, ok := reply.(Error); ok {
+				err = replyErr
+				break
+			}
+		}
+	}
+	if err != nil {
+		c.discard(cn)
+		return nil, err
+	}
+	return cn, nil
+}
+
+func (c *Client) put(cn *conn) {
+	select {
+	case c.idle <- cn:
+	default:
+		c.discard(cn)
+	}
+}
+
+// Закрывает соединение и освобождает его место в пуле
+func (c *Client) discard(cn *conn) {
+	cn.Close()
+	<-c.slots
+}
+
+// Выполняет команды на соединении. После ошибки состояние соединения неизвестно, и его нужно закрыть
+func (cn *conn) exec(ctx context.Context, cmds [][]string) (_ []interface{}, err error) {
+	deadline, _ := ctx.Deadline()
+	if err := cn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+	// Отмена контекста прерывает ожидание ответа. Если отмена успела сработать, прошедший срок
+	// остался на соединении, поэтому оно возвращается с ошибкой и не попадает обратно в пул
+	interrupted := make(chan struct{})
+	stop := context.AfterFunc(ctx, func() {
+		cn.SetDeadline(time.Unix(1, 0))
+		close(interrupted)
+	})
+	defer func() {
+		if !stop() {
+			<-interrupted
+			if err == nil {
+				err = ctx.Err()
+			}
+		}
+	}()
+
+	for _, args := range cmds {
+		fmt.Fprintf(cn.w, "*%d\r\n", len(args))
+		for _, arg := range args {
+			fmt.Fprintf(cn.w, "$%d\r\n%s\r\n", len(arg), arg)
+		}
+	}
+	if err := cn.w.Flush(); err != nil {
+		return nil, err
+	}
+
+	replies := make([]interface{}, len(cmds))
+	for i := range cmds {
+		reply, err := ReadReply(cn.r)
+		if err != nil {
+			return nil, err
+		}
+		replies[i] = reply
+	}
+	return replies, nil
+}
+
+// Читает один ответ в формате RESP
+func ReadReply(r *bufio.Reader) (interface{}, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 {
+		return nil, errProtocol
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return Error(line[1:]), nil
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < -1 {
+			return nil, errProtocol
+		}
+		if n == -1 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < -1 {
+			return nil, errProtocol
+		}
+		if n == -1 {
+			return nil, nil
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			if items[i], err = ReadReply(r); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, errProtocol
+	}
+}
+
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return "", errProtocol
+	}
+	return line[:len(line)-2], nil
+}
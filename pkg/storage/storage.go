@@ -5,4 +5,7 @@ import "errors"
 var (
 	ErrNotFound      = errors.New("url not found")
 	ErrAlreadyExists = errors.New("url already exists")
+	ErrExpired       = errors.New("url expired")
+	ErrKeyNotFound   = errors.New("api key not found")
+	ErrForbidden     = errors.New("link belongs to another api key")
 )
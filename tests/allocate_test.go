@@ -0,0 +1,247 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"url-shortener/internal/model"
+	"url-shortener/internal/repository"
+	"url-shortener/internal/service"
+	"url-shortener/pkg/storage"
+)
+
+func allocateBackends(t *testing.T) map[string]service.Storage {
+	fileStorage, err := repository.NewFileStorage(filepath.Join(t.TempDir(), "links.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { fileStorage.Close() })
+	redisStorage, _ := newRedisStorage(t)
+	return map[string]service.Storage{
+		"cache": repository.NewCacheStorage(),
+		"file":  fileStorage,
+		"redis": redisStorage,
+		"lru":   service.NewLRUStorage(repository.NewCacheStorage(), service.LRUOptions{Size: 100, TTL: time.Minute}),
+	}
+}
+
+func TestAllocate(t *testing.T) {
+	ctx := context.Background()
+	for name, s := range allocateBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			code, exists, err := s.Allocate(ctx, model.Link{ShortURL: "abc", LongURL: "https://example.com/"})
+			assert.NoError(t, err)
+			assert.Equal(t, "abc", code)
+			assert.False(t, exists)
+
+			code, exists, err = s.Allocate(ctx, model.Link{ShortURL: "abc", LongURL: "https://example.com/"})
+			assert.NoError(t, err)
+			assert.Equal(t, "abc", code)
+			assert.True(t, exists)
+
+			_, _, err = s.Allocate(ctx, model.Link{ShortURL: "abc", LongURL: "https://example.org/"})
+			assert.ErrorIs(t, err, storage.ErrAlreadyExists)
+
+			// Длинная ссылка уникальна: под другим кодом возвращается уже выданный
+			code, exists, err = s.Allocate(ctx, model.Link{ShortURL: "def", LongURL: "https://example.com/"})
+			assert.NoError(t, err)
+			assert.Equal(t, "abc", code)
+			assert.True(t, exists)
+			assert.ErrorIs(t, s.Insert(ctx, model.Link{ShortURL: "def", LongURL: "https://example.com/"}), storage.ErrAlreadyExists)
+			errs, err := s.InsertBatch(ctx, []model.Link{{ShortURL: "def", LongURL: "https://example.com/"}})
+			assert.NoError(t, err)
+			assert.Equal(t, []error{storage.ErrAlreadyExists}, errs)
+		})
+	}
+}
+
+func TestAllocateBatch(t *testing.T) {
+	ctx := context.Background()
+	for name, s := range allocateBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			require.NoError(t, s.Insert(ctx, model.Link{ShortURL: "abc", LongURL: "https://example.com/"}))
+			past := time.Now().Add(-time.Minute)
+			require.NoError(t, s.Insert(ctx, model.Link{ShortURL: "old", LongURL: "https://example.org/old", ExpiresAt: past}))
+			require.NoError(t, s.Insert(ctx, model.Link{ShortURL: "gone", LongURL: "https://example.org/gone", ExpiresAt: past}))
+
+			res, err := s.AllocateBatch(ctx, []model.Link{
+				{ShortURL: "new", LongURL: "https://example.com/new"},
+				{ShortURL: "abc", LongURL: "https://example.net/"},
+				{ShortURL: "def", LongURL: "https://example.com/"},
+				{ShortURL: "old", LongURL: "https://example.org/reused"},
+				{ShortURL: "again", LongURL: "https://example.org/gone"},
+				{ShortURL: "dup", LongURL: "https://example.com/new"},
+				{ShortURL: "new", LongURL: "https://example.com/other"},
+			})
+			require.NoError(t, err)
+			assert.Equal(t, []model.Allocation{
+				{ShortURL: "new"},
+				{Err: storage.ErrAlreadyExists},
+				{ShortURL: "abc", Exists: true},
+				// Истёкшие код и ссылка занимаются заново
+				{ShortURL: "old"},
+				{ShortURL: "again"},
+				// Ссылка, сохранённая раньше в том же пакете, уже существует
+				{ShortURL: "new", Exists: true},
+				{Err: storage.ErrAlreadyExists},
+			}, res)
+
+			for code, longURL := range map[string]string{"new": "https://example.com/new", "old": "https://example.org/reused", "again": "https://example.org/gone"} {
+				value, err := s.GetLongUrl(ctx, code)
+				assert.NoError(t, err)
+				assert.Equal(t, longURL, value)
+			}
+			_, err = s.GetLongUrl(ctx, "gone")
+			assert.ErrorIs(t, err, storage.ErrNotFound)
+			// Занятые пакетом ссылки уникальны, как после Allocate
+			assert.NoError(t, s.Insert(ctx, model.Link{ShortURL: "other", LongURL: "https://example.org/old"}))
+			assert.ErrorIs(t, s.Insert(ctx, model.Link{ShortURL: "other2", LongURL: "https://example.org/gone"}), storage.ErrAlreadyExists)
+		})
+	}
+}
+
+// Параллельные запросы за один код с разными ссылками: код получает ровно одна ссылка,
+// запросы с той же ссылкой получают её код, остальные - ErrAlreadyExists
+func TestAllocate_Concurrent(t *testing.T) {
+	const workers, urls = 32, 4
+	ctx := context.Background()
+	for name, s := range allocateBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			type result struct {
+				longURL, code string
+				exists        bool
+				err           error
+			}
+			results := make([]result, workers)
+			start := make(chan struct{})
+			var wg sync.WaitGroup
+			for i := 0; i < workers; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					<-start
+					longURL := fmt.Sprintf("https://example.com/%d", i%urls)
+					code, exists, err := s.Allocate(ctx, model.Link{ShortURL: "race", LongURL: longURL})
+					results[i] = result{longURL, code, exists, err}
+				}(i)
+			}
+			close(start)
+			wg.Wait()
+
+			winner, err := s.GetLongUrl(ctx, "race")
+			require.NoError(t, err)
+			created := 0
+			for _, res := range results {
+				if res.longURL != winner {
+					assert.ErrorIs(t, res.err, storage.ErrAlreadyExists)
+					continue
+				}
+				require.NoError(t, res.err)
+				assert.Equal(t, "race", res.code)
+				if !res.exists {
+					created++
+				}
+			}
+			assert.Equal(t, 1, created)
+		})
+	}
+}
+
+// Сокращение одних и тех же ссылок из многих горутин, в том числе при занятых другими ссылками кодах:
+// каждая ссылка получает один код, коды разных ссылок не совпадают
+func TestShortening_ConcurrentStress(t *testing.T) {
+	const workers, urls, rounds = 16, 50, 4
+	ctx := context.Background()
+	for name, s := range allocateBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			shortener := service.NewShortenerService(s, service.URLNormalizer{AllowedSchemes: []string{"https"}})
+			longURL := func(i int) string { return fmt.Sprintf("https://example.com/page/%d", i) }
+			// Каждая третья ссылка сталкивается с занятым кодом и переходит к следующему номеру коллизии
+			for i := 0; i < urls; i += 3 {
+				collision := model.Link{ShortURL: service.EncodeHash(longURL(i)) + "00", LongURL: fmt.Sprintf("https://other.com/%d", i)}
+				require.NoError(t, s.Insert(ctx, collision))
+			}
+
+			codes := make([][]string, workers)
+			start := make(chan struct{})
+			var wg sync.WaitGroup
+			for w := 0; w < workers; w++ {
+				wg.Add(1)
+				go func(w int) {
+					defer wg.Done()
+					<-start
+					codes[w] = make([]string, urls)
+					for r := 0; r < rounds; r++ {
+						for i := 0; i < urls; i++ {
+							n := (i + w*7) % urls // Горутины проходят ссылки в разном порядке
+							code, err := shortener.Shortening(ctx, model.Link{LongURL: longURL(n)})
+							if !assert.NoError(t, err) {
+								return
+							}
+							if codes[w][n] == "" {
+								codes[w][n] = code
+							}
+							assert.Equal(t, codes[w][n], code, "code of %s changed", longURL(n))
+						}
+					}
+				}(w)
+			}
+			close(start)
+			wg.Wait()
+
+			owner := make(map[string]int)
+			for i := 0; i < urls; i++ {
+				code := codes[0][i]
+				for w := 1; w < workers; w++ {
+					assert.Equal(t, code, codes[w][i], "workers got different codes for %s", longURL(i))
+				}
+				if j, ok := owner[code]; ok {
+					t.Errorf("code %s shared by %s and %s", code, longURL(j), longURL(i))
+				}
+				owner[code] = i
+
+				stored, err := s.GetLongUrl(ctx, code)
+				assert.NoError(t, err)
+				assert.Equal(t, longURL(i), stored)
+				if i%3 == 0 {
+					assert.Equal(t, service.EncodeHash(longURL(i))+"01", code)
+				}
+			}
+		})
+	}
+}
+
+// Параллельные запросы с одним пользовательским кодом и одной ссылкой: все получают этот код
+func TestCustomShortening_Concurrent(t *testing.T) {
+	const workers = 16
+	ctx := context.Background()
+	for name, s := range allocateBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			shortener := service.NewShortenerService(s, service.URLNormalizer{AllowedSchemes: []string{"https"}})
+			errs := make([]error, workers)
+			start := make(chan struct{})
+			var wg sync.WaitGroup
+			for i := 0; i < workers; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					<-start
+					var code string
+					code, errs[i] = shortener.CustomShortening(ctx, model.Link{ShortURL: "promo", LongURL: "https://example.com/"})
+					if errs[i] == nil {
+						assert.Equal(t, "promo", code)
+					}
+				}(i)
+			}
+			close(start)
+			wg.Wait()
+			for _, err := range errs {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
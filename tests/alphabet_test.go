@@ -0,0 +1,134 @@
+package tests
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"url-shortener/internal/model"
+	"url-shortener/internal/repository"
+	"url-shortener/internal/service"
+	"url-shortener/pkg/alphabet"
+	"url-shortener/pkg/storage"
+)
+
+func TestAlphabet_Presets(t *testing.T) {
+	for name, chars := range alphabet.Presets {
+		assert.NoError(t, alphabet.Validate(chars), name)
+		assert.Equal(t, chars, alphabet.Resolve(name))
+		assert.Equal(t, chars, alphabet.Resolve(strings.ToUpper(name)))
+	}
+	assert.Len(t, alphabet.Crockford32, 32)
+	assert.Len(t, alphabet.Base62, 62)
+	assert.Equal(t, service.Alphabet, alphabet.Legacy)
+	assert.NotContains(t, alphabet.Human, "0")
+	assert.NotContains(t, alphabet.Human, "l")
+	assert.Equal(t, "abc", alphabet.Resolve("abc"))
+
+	assert.ErrorContains(t, alphabet.Validate("a"), "at least 2")
+	assert.ErrorContains(t, alphabet.Validate("aba"), "repeats")
+	assert.ErrorContains(t, alphabet.Validate("ab-"), "is not a latin letter")
+}
+
+func TestAlphabet_Fold(t *testing.T) {
+	fold, ok := alphabet.Fold(alphabet.Crockford32)
+	require.True(t, ok)
+	assert.Equal(t, "ABC123", fold("aBc123"))
+	fold, ok = alphabet.Fold(alphabet.Lowercase)
+	require.True(t, ok)
+	assert.Equal(t, "abc123", fold("aBc123"))
+	fold, ok = alphabet.Fold("0123456789")
+	require.True(t, ok)
+	assert.Equal(t, "abc", fold("ABC"))
+	_, ok = alphabet.Fold(alphabet.Human)
+	assert.False(t, ok)
+}
+
+func TestEncodeHashWith(t *testing.T) {
+	assert.Equal(t, service.EncodeHash("https://example.com/"), service.EncodeHashWith("https://example.com/", service.Alphabet, 8))
+	code := service.EncodeHashWith("https://example.com/", alphabet.Crockford32, 20)
+	assert.Len(t, code, 20)
+	assert.Empty(t, strings.Trim(code, alphabet.Crockford32))
+
+	assert.Equal(t, "0010", service.IntToIndex(2, "01", 4))
+	assert.Equal(t, "10", service.IntToIndex(32, alphabet.Crockford32, 2))
+	assert.Equal(t, service.IntToIndex63(64), service.IntToIndex(64, service.Alphabet, 2))
+}
+
+func TestNewCodeGenerator_Preset(t *testing.T) {
+	g, err := service.NewCodeGenerator(service.StrategyRandom, service.CodeOptions{Alphabet: "lowercase", Length: 24}, nil, nil)
+	require.NoError(t, err)
+	code, err := g.Code(context.Background(), "", 0)
+	require.NoError(t, err)
+	assert.Len(t, code, 24)
+	assert.Empty(t, strings.Trim(code, alphabet.Lowercase))
+
+	_, err = service.NewCodeGenerator(service.StrategyHash, service.CodeOptions{Alphabet: "ab-", Length: 8}, nil, nil)
+	assert.ErrorContains(t, err, "code alphabet")
+}
+
+func TestCaseFoldStorage(t *testing.T) {
+	ctx := context.Background()
+	fold, _ := alphabet.Fold(alphabet.Crockford32)
+	s := service.NewCaseFoldStorage(repository.NewCacheStorage(), fold)
+	generator, err := service.NewCodeGenerator(service.StrategyHash, service.CodeOptions{Alphabet: "crockford32", Length: 12}, nil, nil)
+	require.NoError(t, err)
+	shortener := service.NewShortenerService(s, service.URLNormalizer{AllowedSchemes: []string{"https"}})
+	shortener.Generator = generator
+	shortener.Fold = fold
+
+	code, err := shortener.Shortening(ctx, model.Link{LongURL: "https://example.com/"})
+	require.NoError(t, err)
+	assert.Equal(t, strings.ToUpper(code), code)
+	for _, variant := range []string{code, strings.ToLower(code)} {
+		longURL, err := shortener.Expansion(ctx, variant)
+		assert.NoError(t, err)
+		assert.Equal(t, "https://example.com/", longURL)
+	}
+
+	// Пользовательский код сохраняется в регистре алфавита и занят в любом регистре
+	_, err = shortener.CustomShortening(ctx, model.Link{ShortURL: "Batch", LongURL: "https://example.com/batch"})
+	require.NoError(t, err)
+	link, err := s.GetLink(ctx, "batch")
+	require.NoError(t, err)
+	assert.Equal(t, "BATCH", link.ShortURL)
+	_, err = shortener.CustomShortening(ctx, model.Link{ShortURL: "BATCH", LongURL: "https://example.org/"})
+	assert.ErrorIs(t, err, service.ErrAliasTaken)
+	// Символы вне алфавита генератора недопустимы в любом регистре
+	_, err = shortener.CustomShortening(ctx, model.Link{ShortURL: "promo", LongURL: "https://example.com/promo"})
+	assert.ErrorIs(t, err, service.ErrInvalidAlias)
+
+	require.NoError(t, s.Delete(ctx, "batch", ""))
+	_, err = s.GetLongUrl(ctx, "BATCH")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+
+	// Код в смешанном регистре, сохранённый до включения режима, доступен по исходному написанию
+	links := repository.NewCacheStorage()
+	s = service.NewCaseFoldStorage(links, fold)
+	require.NoError(t, links.Insert(ctx, model.Link{ShortURL: "aB3x", LongURL: "https://example.com/legacy"}))
+	longURL, err := s.GetLongUrl(ctx, "aB3x")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/legacy", longURL)
+	_, err = s.GetLongUrl(ctx, "AB3X")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+	updated, err := s.Update(ctx, model.Link{ShortURL: "aB3x", LongURL: "https://example.com/legacy2"})
+	require.NoError(t, err)
+	assert.Equal(t, "aB3x", updated.ShortURL)
+
+	legacyClicks := service.NewCaseFoldAnalyticsStorage(repository.NewCacheAnalyticsStorage(), links, fold)
+	require.NoError(t, legacyClicks.InsertClick(ctx, model.Click{ShortURL: "aB3x", Timestamp: time.Now()}))
+	total, _, err := legacyClicks.ClickStats(ctx, "aB3x", time.Now().AddDate(0, 0, -1))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	require.NoError(t, s.Delete(ctx, "aB3x", ""))
+
+	analytics := service.NewCaseFoldAnalyticsStorage(repository.NewCacheAnalyticsStorage(), links, fold)
+	require.NoError(t, analytics.InsertClick(ctx, model.Click{ShortURL: strings.ToLower(code), Timestamp: time.Now()}))
+	require.NoError(t, analytics.InsertClick(ctx, model.Click{ShortURL: code, Timestamp: time.Now()}))
+	total, _, err = analytics.ClickStats(ctx, strings.ToLower(code), time.Now().AddDate(0, 0, -1))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+}
@@ -0,0 +1,180 @@
+package tests
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"url-shortener/internal/model"
+	"url-shortener/internal/repository"
+	"url-shortener/internal/service"
+	"url-shortener/migrations"
+	"url-shortener/pkg/storage"
+	"url-shortener/pkg/storage/postgres"
+)
+
+func TestAnonymizeIP(t *testing.T) {
+	assert.Equal(t, "203.0.113.0", service.AnonymizeIP("203.0.113.57"))
+	assert.Equal(t, "2001:db8:85a3::", service.AnonymizeIP("2001:db8:85a3:8d3:1319:8a2e:370:7348"))
+	assert.Equal(t, "", service.AnonymizeIP("not an ip"))
+}
+
+func TestAnalyticsService_Stats(t *testing.T) {
+	links := repository.NewCacheStorage()
+	assert.NoError(t, links.Insert(context.Background(), model.Link{ShortURL: "abc", LongURL: "https://example.com"}))
+
+	analytics := service.NewAnalyticsService(links, repository.NewCacheAnalyticsStorage(), 16, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		analytics.Run(ctx)
+		close(done)
+	}()
+
+	now := time.Now().UTC()
+	analytics.RecordClick(model.Click{ShortURL: "abc", Timestamp: now, ClientIP: "198.51.100.7"})
+	analytics.RecordClick(model.Click{ShortURL: "abc", Timestamp: now})
+	analytics.RecordClick(model.Click{ShortURL: "abc", Timestamp: now.AddDate(0, 0, -10)})
+	cancel()
+	<-done
+
+	stats, err := analytics.Stats(context.Background(), "abc", "", 7)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), stats.Total)
+	assert.Len(t, stats.Daily, 7)
+	assert.Equal(t, now.Format("2006-01-02"), stats.Daily[6].Date)
+	assert.Equal(t, int64(2), stats.Daily[6].Clicks)
+
+	_, err = analytics.Stats(context.Background(), "missing", "", 7)
+	assert.Equal(t, storage.ErrNotFound, err)
+}
+
+func TestAnalyticsService_StatsOwner(t *testing.T) {
+	links := repository.NewCacheStorage()
+	assert.NoError(t, links.Insert(context.Background(), model.Link{ShortURL: "abc", LongURL: "https://example.com", Owner: "key1"}))
+	analytics := service.NewAnalyticsService(links, repository.NewCacheAnalyticsStorage(), 16, nil)
+
+	_, err := analytics.Stats(context.Background(), "abc", "key1", 7)
+	assert.NoError(t, err)
+	_, err = analytics.Stats(context.Background(), "abc", "key2", 7)
+	assert.ErrorIs(t, err, service.ErrForbidden)
+}
+
+func TestAnalyticsStorage_Backends(t *testing.T) {
+	for name, backend := range analyticsBackends(t, "abc") {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			require.NoError(t, backend.links.Insert(ctx, model.Link{ShortURL: "abc", LongURL: "https://example.com"}))
+			today := time.Now().UTC().Truncate(24 * time.Hour)
+			for _, at := range []time.Time{today.Add(time.Hour), today.Add(2 * time.Hour), today.AddDate(0, 0, -1), today.AddDate(0, 0, -10)} {
+				require.NoError(t, backend.analytics.InsertClick(ctx, model.Click{ShortURL: "abc", Timestamp: at}))
+			}
+
+			total, daily, err := backend.analytics.ClickStats(ctx, "abc", today.AddDate(0, 0, -6))
+			require.NoError(t, err)
+			assert.Equal(t, int64(4), total)
+			assert.Equal(t, map[string]int64{
+				today.Format("2006-01-02"):                   2,
+				today.AddDate(0, 0, -1).Format("2006-01-02"): 1,
+			}, daily)
+
+			// Счётчики удаляются вместе со ссылкой и не достаются новой ссылке с тем же кодом
+			require.NoError(t, backend.links.Delete(ctx, "abc", ""))
+			require.NoError(t, backend.links.Insert(ctx, model.Link{ShortURL: "abc", LongURL: "https://example.org"}))
+			total, daily, err = backend.analytics.ClickStats(ctx, "abc", today.AddDate(0, 0, -6))
+			require.NoError(t, err)
+			assert.Zero(t, total)
+			assert.Empty(t, daily)
+		})
+	}
+}
+
+// Хранилища ссылок и их аналитики. PostgreSQL проверяется, только если задан TEST_DATABASE_URL:
+// к базе применяются миграции, записи с кодами codes удаляются до и после теста
+func analyticsBackends(t *testing.T, codes ...string) map[string]analyticsBackend {
+	redisStorage, client := newRedisStorage(t)
+	fileStorage, err := repository.NewFileStorage(filepath.Join(t.TempDir(), "links.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { fileStorage.Close() })
+
+	backends := map[string]analyticsBackend{
+		"redis": {redisStorage, repository.NewRedisAnalyticsStorage(client)},
+		"file":  {fileStorage, repository.NewFileAnalyticsStorage(fileStorage)},
+	}
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		return backends
+	}
+	ctx := context.Background()
+	pgxPool, err := pgxpool.New(ctx, dsn)
+	require.NoError(t, err)
+	t.Cleanup(pgxPool.Close)
+	pool := &postgres.Pool{Pool: pgxPool}
+	migrator, err := postgres.NewMigrator(*pool, migrations.FS)
+	require.NoError(t, err)
+	defer migrator.Close()
+	_, err = migrator.Up(ctx)
+	require.NoError(t, err)
+	cleanup := func() {
+		_, err := pool.Exec(ctx, "DELETE FROM urls WHERE short_url = ANY($1)", codes)
+		require.NoError(t, err)
+	}
+	cleanup()
+	t.Cleanup(cleanup)
+	backends["postgres"] = analyticsBackend{repository.NewDataBaseStorage(pool), repository.NewDataBaseAnalyticsStorage(pool)}
+	return backends
+}
+
+type analyticsBackend struct {
+	links     service.Storage
+	analytics service.AnalyticsStorage
+}
+
+func TestAnalyticsStorage_ExpiredAlias(t *testing.T) {
+	for name, backend := range analyticsBackends(t, "reused") {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			expired := model.Link{ShortURL: "reused", LongURL: "https://example.com/" + name, ExpiresAt: time.Now().Add(-time.Minute)}
+			require.NoError(t, backend.links.Insert(ctx, expired))
+			require.NoError(t, backend.analytics.InsertClick(ctx, model.Click{ShortURL: "reused", Timestamp: time.Now()}))
+
+			// Новый владелец истёкшего кода получает ссылку без чужой статистики переходов
+			require.NoError(t, backend.links.Insert(ctx, model.Link{ShortURL: "reused", LongURL: "https://example.org/" + name}))
+			total, daily, err := backend.analytics.ClickStats(ctx, "reused", time.Time{})
+			require.NoError(t, err)
+			assert.Zero(t, total)
+			assert.Empty(t, daily)
+		})
+	}
+}
+
+// Хранилище аналитики, проверяющее, что запись перехода ограничена по времени
+type deadlineAnalyticsStorage struct {
+	service.AnalyticsStorage
+	t *testing.T
+}
+
+func (s deadlineAnalyticsStorage) InsertClick(ctx context.Context, click model.Click) error {
+	deadline, ok := ctx.Deadline()
+	assert.True(s.t, ok)
+	assert.LessOrEqual(s.t, time.Until(deadline), 5*time.Second)
+	return s.AnalyticsStorage.InsertClick(ctx, click)
+}
+
+func TestAnalyticsService_InsertTimeout(t *testing.T) {
+	clicks := repository.NewCacheAnalyticsStorage()
+	analytics := service.NewAnalyticsService(repository.NewCacheStorage(), deadlineAnalyticsStorage{clicks, t}, 16, nil)
+	analytics.RecordClick(model.Click{ShortURL: "abc", Timestamp: time.Now()})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	analytics.Run(ctx)
+
+	total, _, err := clicks.ClickStats(context.Background(), "abc", time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+}
@@ -0,0 +1,168 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"url-shortener/internal/controller"
+	"url-shortener/internal/model"
+	"url-shortener/internal/repository"
+	"url-shortener/internal/service"
+	"url-shortener/pkg/storage"
+
+	"url-shortener/tests/mocks"
+)
+
+func TestAuthService_CreateAndRevoke(t *testing.T) {
+	auth := service.NewAuthService(repository.NewCacheKeyStorage())
+
+	created, err := auth.CreateKey(context.Background(), "reports")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, created.Key)
+
+	key, err := auth.Authenticate(context.Background(), created.Key)
+	assert.NoError(t, err)
+	assert.Equal(t, created.ID, key.ID)
+
+	assert.NoError(t, auth.RevokeKey(context.Background(), created.ID))
+	_, err = auth.Authenticate(context.Background(), created.Key)
+	assert.ErrorIs(t, err, service.ErrUnauthorized)
+}
+
+func TestShortenRequiresApiKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+
+	auth := service.NewAuthService(repository.NewCacheKeyStorage())
+	created, err := auth.CreateKey(context.Background(), "reports")
+	assert.NoError(t, err)
+
+	mockService := new(mocks.MockShortenerService)
+	mockService.On("Shortening", mock.Anything, mock.MatchedBy(func(link model.Link) bool {
+		return link.Owner == created.ID
+	})).Return("test_short_url", nil).Once()
+
+	authHandler := handler.NewAuthHandler(auth, "", nil)
+	h := handler.NewHandler(mockService, new(mocks.MockAnalyticsService), nil, handler.Options{RedirectStatus: http.StatusFound})
+	h.Register(router, handler.Middlewares{Auth: []gin.HandlerFunc{authHandler.Authenticate}})
+
+	req := httptest.NewRequest(http.MethodPost, "/shorten", convertToJSON(model.LongURL{URL: "https://example.com"}))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	req = httptest.NewRequest(http.MethodPost, "/shorten", convertToJSON(model.LongURL{URL: "https://example.com"}))
+	req.Header.Set("Authorization", "Bearer "+created.Key)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	mockService.AssertExpectations(t)
+}
+
+// Статистика доступна только с ключом, которым создана ссылка
+func TestStatsRequiresOwner(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	auth := service.NewAuthService(repository.NewCacheKeyStorage())
+	owner, err := auth.CreateKey(context.Background(), "owner")
+	assert.NoError(t, err)
+	other, err := auth.CreateKey(context.Background(), "other")
+	assert.NoError(t, err)
+
+	links := repository.NewCacheStorage()
+	assert.NoError(t, links.Insert(context.Background(), model.Link{ShortURL: "promo", LongURL: "https://example.com/", Owner: owner.ID}))
+	analytics := service.NewAnalyticsService(links, repository.NewCacheAnalyticsStorage(), 16, nil)
+
+	authHandler := handler.NewAuthHandler(auth, "", nil)
+	h := handler.NewHandler(new(mocks.MockShortenerService), analytics, nil, handler.Options{})
+	h.Register(router, handler.Middlewares{Auth: []gin.HandlerFunc{authHandler.Authenticate}})
+
+	for _, tc := range []struct {
+		key    string
+		status int
+	}{
+		{"", http.StatusUnauthorized},
+		{other.Key, http.StatusForbidden},
+		{owner.Key, http.StatusOK},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/links/promo/stats", nil)
+		if tc.key != "" {
+			req.Header.Set("Authorization", "Bearer "+tc.key)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, tc.status, w.Code)
+	}
+}
+
+func TestKeyStorage_Backends(t *testing.T) {
+	_, client := newRedisStorage(t)
+	fileStorage, err := repository.NewFileStorage(filepath.Join(t.TempDir(), "links.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { fileStorage.Close() })
+
+	backends := map[string]service.KeyStorage{
+		"cache": repository.NewCacheKeyStorage(),
+		"redis": repository.NewRedisKeyStorage(client),
+		"file":  repository.NewFileKeyStorage(fileStorage),
+	}
+	for name, keys := range backends {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			now := time.Now().UTC().Truncate(time.Second)
+			first := model.APIKey{ID: "k1", Name: "reports", Hash: "h1", CreatedAt: now}
+			second := model.APIKey{ID: "k2", Name: "import", Hash: "h2", CreatedAt: now.Add(time.Second)}
+			require.NoError(t, keys.InsertKey(ctx, second))
+			require.NoError(t, keys.InsertKey(ctx, first))
+			assert.ErrorIs(t, keys.InsertKey(ctx, first), storage.ErrAlreadyExists)
+
+			key, err := keys.GetKeyByHash(ctx, "h1")
+			require.NoError(t, err)
+			assert.Equal(t, "k1", key.ID)
+			assert.Equal(t, "reports", key.Name)
+			_, err = keys.GetKeyByHash(ctx, "missing")
+			assert.ErrorIs(t, err, storage.ErrKeyNotFound)
+
+			revokedAt := now.Add(time.Minute)
+			require.NoError(t, keys.RevokeKey(ctx, "k1", revokedAt))
+			require.NoError(t, keys.RevokeKey(ctx, "k1", revokedAt.Add(time.Hour)))
+			assert.ErrorIs(t, keys.RevokeKey(ctx, "missing", revokedAt), storage.ErrKeyNotFound)
+
+			list, err := keys.ListKeys(ctx)
+			require.NoError(t, err)
+			require.Len(t, list, 2)
+			assert.Equal(t, "k1", list[0].ID)
+			require.NotNil(t, list[0].RevokedAt)
+			assert.True(t, revokedAt.Equal(*list[0].RevokedAt))
+			assert.Nil(t, list[1].RevokedAt)
+		})
+	}
+}
+
+func TestFileKeyStorage_PersistsAcrossRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "links.db")
+	fileStorage, err := repository.NewFileStorage(path)
+	require.NoError(t, err)
+	auth := service.NewAuthService(repository.NewFileKeyStorage(fileStorage))
+	created, err := auth.CreateKey(context.Background(), "reports")
+	require.NoError(t, err)
+	require.NoError(t, fileStorage.Close())
+
+	fileStorage, err = repository.NewFileStorage(path)
+	require.NoError(t, err)
+	defer fileStorage.Close()
+	auth = service.NewAuthService(repository.NewFileKeyStorage(fileStorage))
+	key, err := auth.Authenticate(context.Background(), created.Key)
+	assert.NoError(t, err)
+	assert.Equal(t, created.ID, key.ID)
+}
@@ -0,0 +1,236 @@
+package tests
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"url-shortener/internal/controller"
+	"url-shortener/internal/model"
+	"url-shortener/internal/repository"
+	"url-shortener/internal/service"
+	"url-shortener/pkg/storage"
+
+	"url-shortener/tests/mocks"
+)
+
+func TestBatchShortening(t *testing.T) {
+	cache := repository.NewCacheStorage()
+	assert.NoError(t, cache.Insert(context.Background(), model.Link{ShortURL: "taken", LongURL: "https://example.org/"}))
+	s := service.NewShortenerService(cache, service.URLNormalizer{AllowedSchemes: []string{"http", "https"}})
+
+	links := []model.Link{
+		{LongURL: "https://example.com/a"},
+		{LongURL: "ftp://example.com"},
+		{LongURL: "https://example.com/a"},
+		{ShortURL: "promo", LongURL: "https://example.com/b"},
+		{ShortURL: "taken", LongURL: "https://example.com/c"},
+		{ShortURL: "taken", LongURL: "https://example.org/"},
+	}
+	results := s.BatchShortening(context.Background(), links, 3)
+	assert.Len(t, results, len(links))
+
+	assert.NoError(t, results[0].Err)
+	assert.Equal(t, service.EncodeHash("https://example.com/a")+"00", results[0].ShortURL)
+	var validationErr *service.URLValidationError
+	assert.ErrorAs(t, results[1].Err, &validationErr)
+	assert.Equal(t, results[0], results[2])
+	assert.Equal(t, service.BatchResult{ShortURL: "promo"}, results[3])
+	assert.ErrorIs(t, results[4].Err, service.ErrAliasTaken)
+	assert.Equal(t, service.BatchResult{ShortURL: "taken"}, results[5])
+
+	value, err := cache.GetLongUrl(context.Background(), results[0].ShortURL)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/a", value)
+	value, err = cache.GetLongUrl(context.Background(), "promo")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/b", value)
+}
+
+func TestBatchShortening_StorageError(t *testing.T) {
+	mockStorage := new(mocks.MockStorage)
+	mockStorage.On("GetLongUrl", mock.Anything, mock.Anything).Return("", storage.ErrNotFound)
+	mockStorage.On("InsertBatch", mock.Anything, mock.Anything).Return(nil, assert.AnError).Once()
+	mockStorage.On("AllocateBatch", mock.Anything, mock.Anything).Return(nil, assert.AnError).Once()
+	mockStorage.On("Insert", mock.Anything, mock.Anything).Return(nil)
+	code := service.EncodeHash("https://example.com/") + "00"
+	mockStorage.On("Allocate", mock.Anything, mock.Anything).Return(code, false, nil)
+	s := service.NewShortenerService(mockStorage, service.URLNormalizer{})
+
+	results := s.BatchShortening(context.Background(), []model.Link{{LongURL: "https://example.com"}, {ShortURL: "promo", LongURL: "https://example.org"}}, 2)
+
+	// При ошибке пакетных обращений ссылки сохраняются по одной
+	assert.Equal(t, service.BatchResult{ShortURL: code}, results[0])
+	assert.Equal(t, service.BatchResult{ShortURL: "promo"}, results[1])
+	mockStorage.AssertNumberOfCalls(t, "Allocate", 1)
+	mockStorage.AssertNumberOfCalls(t, "Insert", 1)
+}
+
+// Хранилище, считающее обращения за кодами
+type countingStorage struct {
+	service.Storage
+	allocate, allocateBatch int
+}
+
+func (s *countingStorage) Allocate(ctx context.Context, link model.Link) (string, bool, error) {
+	s.allocate++
+	return s.Storage.Allocate(ctx, link)
+}
+
+func (s *countingStorage) AllocateBatch(ctx context.Context, links []model.Link) ([]model.Allocation, error) {
+	s.allocateBatch++
+	return s.Storage.AllocateBatch(ctx, links)
+}
+
+func TestBatchShortening_AllocateCalls(t *testing.T) {
+	ctx := context.Background()
+	cache := repository.NewCacheStorage()
+	links := make([]model.Link, 1000)
+	for i := range links {
+		links[i] = model.Link{LongURL: fmt.Sprintf("https://example.com/%d", i)}
+	}
+	// Первые коды части ссылок заняты другими ссылками
+	for _, i := range []int{1, 500, 999} {
+		code := service.EncodeHash(links[i].LongURL) + "00"
+		assert.NoError(t, cache.Insert(ctx, model.Link{ShortURL: code, LongURL: "https://example.org/" + code}))
+	}
+	counting := &countingStorage{Storage: cache}
+	s := service.NewShortenerService(counting, service.URLNormalizer{})
+
+	results := s.BatchShortening(ctx, links, 8)
+
+	// Одно обращение на попытку генератора, а не на ссылку
+	assert.Equal(t, 2, counting.allocateBatch)
+	assert.Zero(t, counting.allocate)
+	for i, res := range results {
+		assert.NoError(t, res.Err)
+		suffix := "00"
+		if i == 1 || i == 500 || i == 999 {
+			suffix = "01"
+		}
+		assert.Equal(t, service.EncodeHash(links[i].LongURL)+suffix, res.ShortURL)
+	}
+}
+
+func TestShortenBatchEndpoint(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+
+	mockService := new(mocks.MockShortenerService)
+	mockService.On("BatchShortening", mock.Anything, []model.Link{{LongURL: "https://example.com"}, {ShortURL: "promo", LongURL: "https://example.org"}}, 4).
+		Return([]service.BatchResult{{ShortURL: "abc"}, {Err: service.ErrAliasTaken}}).Once()
+
+	h := handler.NewHandler(mockService, new(mocks.MockAnalyticsService), nil, handler.Options{BatchWorkers: 4})
+	h.Register(router, handler.Middlewares{})
+
+	body := `[{"long_url": "https://example.com"}, {"long_url": ""}, {"long_url": "https://example.org", "alias": "promo"},
+		{"long_url": "https://example.net", "ttl_seconds": 9223372036854775807}]`
+	req := httptest.NewRequest(http.MethodPost, "/shorten/batch", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var results []model.BatchItemResult
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &results))
+	assert.Len(t, results, 4)
+	assert.Equal(t, model.BatchItemResult{ShortURL: "abc", Status: http.StatusOK}, results[0])
+	assert.Equal(t, http.StatusBadRequest, results[1].Status)
+	assert.Equal(t, http.StatusConflict, results[2].Status)
+	assert.Equal(t, http.StatusUnprocessableEntity, results[3].Status)
+	assert.Equal(t, service.ReasonTTLTooLong, results[3].Reason)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestShortenBatchEndpointNDJSON(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+
+	mockService := new(mocks.MockShortenerService)
+	mockService.On("BatchShortening", mock.Anything, []model.Link{{LongURL: "https://example.com"}, {LongURL: "https://example.org"}}, mock.Anything).
+		Return([]service.BatchResult{{ShortURL: "abc"}, {ShortURL: "def"}}).Once()
+
+	h := handler.NewHandler(mockService, new(mocks.MockAnalyticsService), nil, handler.Options{})
+	h.Register(router, handler.Middlewares{})
+
+	body := "{\"long_url\": \"https://example.com\"}\n{\"long_url\": \"https://example.org\"}\n"
+	req := httptest.NewRequest(http.MethodPost, "/shorten/batch", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+	var codes []string
+	scanner := bufio.NewScanner(bytes.NewReader(w.Body.Bytes()))
+	for scanner.Scan() {
+		var res model.BatchItemResult
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &res))
+		codes = append(codes, res.ShortURL)
+	}
+	assert.Equal(t, []string{"abc", "def"}, codes)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestShortenBatchEndpointTooLarge(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+
+	h := handler.NewHandler(new(mocks.MockShortenerService), new(mocks.MockAnalyticsService), nil, handler.Options{BatchMaxSize: 1})
+	h.Register(router, handler.Middlewares{})
+
+	body := `[{"long_url": "https://example.com"}, {"long_url": "https://example.org"}]`
+	req := httptest.NewRequest(http.MethodPost, "/shorten/batch", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+}
+
+// Пакет списывает из лимита по одному запросу на каждую ссылку
+func TestShortenBatchEndpointRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	mockService := new(mocks.MockShortenerService)
+	mockService.On("BatchShortening", mock.Anything, mock.Anything, mock.Anything).
+		Return([]service.BatchResult{{ShortURL: "abc"}, {ShortURL: "def"}}).Once()
+
+	limiter := handler.NewRateLimiter(repository.NewCacheRateLimitStore(), nil, handler.RateLimiterOptions{})
+	h := handler.NewHandler(mockService, new(mocks.MockAnalyticsService), nil, handler.Options{})
+	h.Register(router, handler.Middlewares{Batch: []gin.HandlerFunc{limiter.LimitBatch("batch", service.RateLimitPolicy{Rate: 0.1, Burst: 3})}})
+
+	send := func() *httptest.ResponseRecorder {
+		body := `[{"long_url": "https://example.com"}, {"long_url": "https://example.org"}]`
+		req := httptest.NewRequest(http.MethodPost, "/shorten/batch", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send()
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Remaining"))
+
+	// На второй пакет из двух ссылок остался один запрос
+	w = send()
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+
+	mockService.AssertExpectations(t)
+}
@@ -1,26 +1,193 @@
 package tests
 
 import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"url-shortener/internal/model"
 	"url-shortener/internal/repository"
 	"url-shortener/pkg/storage"
+	"url-shortener/pkg/storage/journal"
 )
 
 func TestCache_InsertAndGet(t *testing.T) {
 	cache := repository.NewCacheStorage()
 
 	// Тестируем вставку значения
-	err := cache.Insert("test_key", "test_value")
+	err := cache.Insert(context.Background(), model.Link{ShortURL: "test_key", LongURL: "test_value"})
 	assert.NoError(t, err)
 
 	// Тестируем получение существующего значения
-	value, err := cache.GetLongUrl("test_key")
+	value, err := cache.GetLongUrl(context.Background(), "test_key")
 	assert.NoError(t, err)
 	assert.Equal(t, "test_value", value)
 
 	// Тестируем получение несуществующего значения
-	_, err = cache.GetLongUrl("nonexistent_key")
+	_, err = cache.GetLongUrl(context.Background(), "nonexistent_key")
 	assert.Equal(t, storage.ErrNotFound, err)
 }
+
+func TestCache_Expiry(t *testing.T) {
+	cache := repository.NewCacheStorage()
+
+	err := cache.Insert(context.Background(), model.Link{ShortURL: "expired", LongURL: "https://example.com", ExpiresAt: time.Now().Add(-time.Second)})
+	assert.NoError(t, err)
+
+	_, err = cache.GetLongUrl(context.Background(), "expired")
+	assert.Equal(t, storage.ErrExpired, err)
+
+	// Истёкший код можно занять повторно
+	err = cache.Insert(context.Background(), model.Link{ShortURL: "expired", LongURL: "https://example.org"})
+	assert.NoError(t, err)
+	value, err := cache.GetLongUrl(context.Background(), "expired")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.org", value)
+
+	err = cache.Insert(context.Background(), model.Link{ShortURL: "stale", LongURL: "https://example.net", ExpiresAt: time.Now().Add(-time.Second)})
+	assert.NoError(t, err)
+	n, err := cache.DeleteExpired(context.Background(), time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	_, err = cache.GetLongUrl(context.Background(), "stale")
+	assert.Equal(t, storage.ErrNotFound, err)
+}
+
+func TestCache_PersistsWithLog(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	cache, err := repository.OpenCacheStorage(dir, 1000)
+	require.NoError(t, err)
+	assert.NoError(t, cache.Insert(ctx, model.Link{ShortURL: "one", LongURL: "https://example.com/1"}))
+	assert.NoError(t, cache.Insert(ctx, model.Link{ShortURL: "two", LongURL: "https://example.com/2"}))
+	assert.NoError(t, cache.Delete(ctx, "two", ""))
+
+	// Процесс завершился без снимка: данные восстанавливаются из журнала
+	cache, err = repository.OpenCacheStorage(dir, 1000)
+	require.NoError(t, err)
+	value, err := cache.GetLongUrl(ctx, "one")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/1", value)
+	_, err = cache.GetLongUrl(ctx, "two")
+	assert.Equal(t, storage.ErrNotFound, err)
+	// Индекс длинных ссылок восстанавливается вместе с записями
+	code, exists, err := cache.Allocate(ctx, model.Link{ShortURL: "four", LongURL: "https://example.com/1"})
+	assert.NoError(t, err)
+	assert.Equal(t, "one", code)
+	assert.True(t, exists)
+
+	// Снимок при закрытии заменяет журналы, последующие изменения применяются поверх снимка
+	assert.NoError(t, cache.Close())
+	assert.Len(t, globDir(t, dir, "snapshot-*.db"), 1)
+	cache, err = repository.OpenCacheStorage(dir, 1000)
+	require.NoError(t, err)
+	assert.NoError(t, cache.Insert(ctx, model.Link{ShortURL: "three", LongURL: "https://example.com/3"}))
+	assert.NoError(t, cache.Close())
+
+	cache, err = repository.OpenCacheStorage(dir, 1000)
+	require.NoError(t, err)
+	defer cache.Close()
+	for _, code := range []string{"one", "three"} {
+		_, err = cache.GetLongUrl(ctx, code)
+		assert.NoError(t, err)
+	}
+}
+
+// Снимок пишется по количеству записей журнала, независимо от удаления истёкших ссылок
+func TestCache_SnapshotByRecords(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	cache, err := repository.OpenCacheStorage(dir, 10)
+	require.NoError(t, err)
+	// Десятая запись запускает ровно один фоновый снимок
+	for i := 0; i < 10; i++ {
+		require.NoError(t, cache.Insert(ctx, model.Link{ShortURL: fmt.Sprintf("c%d", i), LongURL: fmt.Sprintf("https://example.com/%d", i)}))
+	}
+	assert.Eventually(t, func() bool { return len(globDir(t, dir, "snapshot-*.db")) > 0 }, time.Second, 10*time.Millisecond)
+
+	assert.NoError(t, cache.Insert(ctx, model.Link{ShortURL: "last", LongURL: "https://example.com/last"}))
+
+	// Копия каталога без итогового снимка, как после сбоя: фоновый снимок и журналы после него
+	crashed := t.TempDir()
+	for _, path := range append(globDir(t, dir, "snapshot-*.db"), globDir(t, dir, "wal-*.log")...) {
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue // Журнал, вошедший в снимок, удалён после него
+		}
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(crashed, filepath.Base(path)), data, 0o644))
+	}
+	assert.NoError(t, cache.Close())
+
+	restored, err := repository.OpenCacheStorage(crashed, 10)
+	require.NoError(t, err)
+	defer restored.Close()
+	for _, code := range []string{"c0", "c9", "last"} {
+		_, err := restored.GetLongUrl(ctx, code)
+		assert.NoError(t, err, code)
+	}
+}
+
+func TestCache_SkipsTornLogRecord(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	cache, err := repository.OpenCacheStorage(dir, 1000)
+	require.NoError(t, err)
+	assert.NoError(t, cache.Insert(ctx, model.Link{ShortURL: "one", LongURL: "https://example.com/1"}))
+	assert.NoError(t, cache.Insert(ctx, model.Link{ShortURL: "two", LongURL: "https://example.com/2"}))
+
+	// Повреждаем последний байт журнала: контрольная сумма записи не сойдётся
+	logs := globDir(t, dir, "wal-*.log")
+	require.Len(t, logs, 1)
+	data, err := os.ReadFile(logs[0])
+	require.NoError(t, err)
+	data[len(data)-1] ^= 0xff
+	require.NoError(t, os.WriteFile(logs[0], data, 0o644))
+
+	cache, err = repository.OpenCacheStorage(dir, 1000)
+	require.NoError(t, err)
+	defer cache.Close()
+	_, err = cache.GetLongUrl(ctx, "one")
+	assert.NoError(t, err)
+	_, err = cache.GetLongUrl(ctx, "two")
+	assert.Equal(t, storage.ErrNotFound, err)
+}
+
+// Повреждённая запись в середине журнала не отбрасывает следующие за ней записи, а останавливает запуск
+func TestCache_RejectsCorruptedLog(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	cache, err := repository.OpenCacheStorage(dir, 1000)
+	require.NoError(t, err)
+	assert.NoError(t, cache.Insert(ctx, model.Link{ShortURL: "one", LongURL: "https://example.com/1"}))
+	assert.NoError(t, cache.Insert(ctx, model.Link{ShortURL: "two", LongURL: "https://example.com/2"}))
+
+	logs := globDir(t, dir, "wal-*.log")
+	require.Len(t, logs, 1)
+	data, err := os.ReadFile(logs[0])
+	require.NoError(t, err)
+	data[journal.HeaderSize] ^= 0xff
+	require.NoError(t, os.WriteFile(logs[0], data, 0o644))
+
+	_, err = repository.OpenCacheStorage(dir, 1000)
+	assert.ErrorIs(t, err, journal.ErrCorrupted)
+	after, err := os.ReadFile(logs[0])
+	require.NoError(t, err)
+	assert.Equal(t, data, after, "corrupted log is left intact")
+}
+
+func globDir(t *testing.T, dir, pattern string) []string {
+	matches, err := filepath.Glob(filepath.Join(dir, pattern))
+	require.NoError(t, err)
+	return matches
+}
@@ -0,0 +1,260 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"url-shortener/internal/controller"
+	"url-shortener/internal/repository"
+	"url-shortener/internal/service"
+	"url-shortener/pkg/client"
+)
+
+const testAdminToken = "admin-secret"
+
+// Сервис на хранилище в памяти с аутентификацией по API-ключам
+func newTestServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(newTestRouter())
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTestRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	links := repository.NewCacheStorage()
+	shortener := service.NewShortenerService(links, service.URLNormalizer{AllowedSchemes: []string{"http", "https"}})
+	analytics := service.NewAnalyticsService(links, repository.NewCacheAnalyticsStorage(), 16, nil)
+	authHandler := handler.NewAuthHandler(service.NewAuthService(repository.NewCacheKeyStorage()), testAdminToken, nil)
+	authHandler.Register(router)
+	h := handler.NewHandler(shortener, analytics, nil, handler.Options{RedirectStatus: http.StatusFound})
+	h.Register(router, handler.Middlewares{Auth: []gin.HandlerFunc{authHandler.Authenticate}})
+	handler.NewHealthHandler(service.NewHealthService(time.Second)).Register(router)
+	return router
+}
+
+func TestClient_Endpoints(t *testing.T) {
+	server := newTestServer(t)
+	ctx := context.Background()
+
+	admin, err := client.New(server.URL, client.WithAdminToken(testAdminToken))
+	require.NoError(t, err)
+	key, err := admin.CreateKey(ctx, "cli")
+	require.NoError(t, err)
+	assert.NotEmpty(t, key.Key)
+
+	c, err := client.New(server.URL+"/", client.WithAPIKey(key.Key))
+	require.NoError(t, err)
+
+	code, err := c.Shorten(ctx, client.ShortenRequest{LongURL: "https://example.com/guide", Alias: "guide"})
+	require.NoError(t, err)
+	assert.Equal(t, "guide", code)
+
+	_, err = c.Shorten(ctx, client.ShortenRequest{LongURL: "https://example.com/other", Alias: "guide"})
+	assert.ErrorIs(t, err, client.ErrConflict)
+
+	_, err = c.Shorten(ctx, client.ShortenRequest{LongURL: "ftp://example.com"})
+	var apiErr *client.Error
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusUnprocessableEntity, apiErr.StatusCode)
+	assert.Equal(t, "long_url", apiErr.Field)
+	assert.NotEmpty(t, apiErr.Reason)
+	assert.ErrorIs(t, err, client.ErrInvalidURL)
+
+	longURL, err := c.Expand(ctx, "guide")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/guide", longURL)
+
+	_, err = c.Expand(ctx, "missing")
+	assert.ErrorIs(t, err, client.ErrNotFound)
+
+	results, err := c.ShortenBatch(ctx, []client.ShortenRequest{
+		{LongURL: "https://example.com/a"},
+		{LongURL: "https://example.com/b", Alias: "guide"},
+	})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.NoError(t, results[0].Err())
+	assert.NotEmpty(t, results[0].ShortURL)
+	assert.ErrorIs(t, results[1].Err(), client.ErrConflict)
+
+	location, err := c.Resolve(ctx, "guide")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/guide", location)
+	_, err = c.Resolve(ctx, "missing")
+	assert.ErrorIs(t, err, client.ErrNotFound)
+
+	link, err := c.GetLink(ctx, "guide")
+	assert.NoError(t, err)
+	assert.Equal(t, key.ID, link.Owner)
+	assert.Nil(t, link.ExpiresAt)
+	link, err = c.UpdateLink(ctx, "guide", client.LinkUpdate{TTLSeconds: 3600})
+	assert.NoError(t, err)
+	assert.NotNil(t, link.ExpiresAt)
+	link, err = c.UpdateLink(ctx, "guide", client.LinkUpdate{NoExpiry: true})
+	assert.NoError(t, err)
+	assert.Nil(t, link.ExpiresAt)
+	_, err = c.UpdateLink(ctx, "guide", client.LinkUpdate{})
+	assert.ErrorIs(t, err, client.ErrBadRequest)
+	_, err = c.UpdateLink(ctx, "guide", client.LinkUpdate{TTLSeconds: math.MaxInt64})
+	assert.ErrorIs(t, err, client.ErrInvalidTTL)
+	assert.False(t, errors.Is(err, client.ErrInvalidURL))
+
+	stats, err := c.Stats(ctx, "guide", 7)
+	assert.NoError(t, err)
+	assert.Equal(t, "guide", stats.ShortURL)
+	assert.Len(t, stats.Daily, 7)
+
+	assert.NoError(t, c.DeleteLink(ctx, "guide"))
+	assert.ErrorIs(t, c.DeleteLink(ctx, "guide"), client.ErrNotFound)
+
+	keys, err := admin.ListKeys(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, keys, 1)
+	assert.NoError(t, admin.RevokeKey(ctx, key.ID))
+	assert.ErrorIs(t, admin.RevokeKey(ctx, "missing"), client.ErrNotFound)
+
+	_, err = c.Shorten(ctx, client.ShortenRequest{LongURL: "https://example.com"})
+	assert.ErrorIs(t, err, client.ErrUnauthorized)
+	_, err = c.ListKeys(ctx)
+	assert.ErrorIs(t, err, client.ErrUnauthorized)
+}
+
+func TestClient_ServerError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "upstream failure", http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	c, err := client.New(server.URL, client.WithRetry(client.NoRetry))
+	require.NoError(t, err)
+	_, err = c.Expand(context.Background(), "abc")
+	var apiErr *client.Error
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusBadGateway, apiErr.StatusCode)
+	assert.Equal(t, "upstream failure", apiErr.Message)
+	assert.ErrorIs(t, err, client.ErrServer)
+	assert.False(t, errors.Is(err, client.ErrNotFound))
+
+	_, err = client.New("localhost:8080")
+	assert.Error(t, err)
+}
+
+func TestClient_Retry(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch n := attempts.Add(1); {
+		case r.URL.Path == "/admin/keys":
+			w.WriteHeader(http.StatusInternalServerError)
+		case n == 1:
+			w.Header().Set("Retry-After", "0")
+			http.Error(w, `{"message": "rate limit exceeded"}`, http.StatusTooManyRequests)
+		case n == 2:
+			http.Error(w, `{"message": "storage timeout"}`, http.StatusGatewayTimeout)
+		default:
+			w.Write([]byte(`{"long_url": "https://example.com"}`))
+		}
+	}))
+	defer server.Close()
+
+	policy := client.RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond}
+	c, err := client.New(server.URL, client.WithRetry(policy))
+	require.NoError(t, err)
+	longURL, err := c.Expand(context.Background(), "abc")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com", longURL)
+	assert.Equal(t, int32(3), attempts.Load())
+
+	// Создание ключа не повторяется после ошибки сервера
+	attempts.Store(0)
+	_, err = c.CreateKey(context.Background(), "reports")
+	assert.ErrorIs(t, err, client.ErrServer)
+	assert.Equal(t, int32(1), attempts.Load())
+
+	attempts.Store(0)
+	c, err = client.New(server.URL, client.WithRetry(client.NoRetry))
+	require.NoError(t, err)
+	_, err = c.Expand(context.Background(), "abc")
+	assert.ErrorIs(t, err, client.ErrRateLimited)
+	assert.Equal(t, int32(1), attempts.Load())
+}
+
+func TestClient_RetryShorten(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		http.Error(w, `{"message": "storage timeout"}`, http.StatusGatewayTimeout)
+	}))
+	defer server.Close()
+
+	policy := client.RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond}
+	c, err := client.New(server.URL, client.WithRetry(policy))
+	require.NoError(t, err)
+	// Ссылка могла быть сохранена до ошибки сервера, поэтому сокращение не повторяется
+	_, err = c.Shorten(context.Background(), client.ShortenRequest{LongURL: "https://example.com"})
+	assert.ErrorIs(t, err, client.ErrServer)
+	assert.Equal(t, int32(1), attempts.Load())
+	_, err = c.ShortenBatch(context.Background(), []client.ShortenRequest{{LongURL: "https://example.com"}})
+	assert.ErrorIs(t, err, client.ErrServer)
+	assert.Equal(t, int32(2), attempts.Load())
+
+	// Запрос, для которого не удалось установить соединение, повторяется
+	var dials atomic.Int32
+	transport := &http.Transport{DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dials.Add(1)
+		return nil, &net.OpError{Op: "dial", Net: network, Err: errors.New("connection refused")}
+	}}
+	c, err = client.New(server.URL, client.WithRetry(policy), client.WithHTTPClient(&http.Client{Transport: transport}))
+	require.NoError(t, err)
+	_, err = c.Shorten(context.Background(), client.ShortenRequest{LongURL: "https://example.com"})
+	assert.Error(t, err)
+	assert.Equal(t, int32(3), dials.Load())
+}
+
+func TestClient_RetryStopsAtDeadline(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.Header().Set("Retry-After", "60")
+		http.Error(w, `{"message": "rate limit exceeded"}`, http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	c, err := client.New(server.URL)
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, err = c.Expand(ctx, "abc")
+	var apiErr *client.Error
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, time.Minute, apiErr.RetryAfter)
+	assert.Equal(t, int32(1), attempts.Load())
+}
+
+func TestClient_UnixSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "app.sock")
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	server := &http.Server{Handler: newTestRouter()}
+	go server.Serve(listener)
+	defer server.Close()
+
+	c, err := client.New("", client.WithUnixSocket(socket))
+	require.NoError(t, err)
+	assert.NoError(t, c.Health(context.Background()))
+	readiness, err := c.Ready(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", readiness.Status)
+}
@@ -0,0 +1,150 @@
+package tests
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"url-shortener/internal/model"
+	"url-shortener/internal/repository"
+	"url-shortener/internal/service"
+)
+
+func TestHashGenerator_DefaultFormat(t *testing.T) {
+	// Коды по умолчанию совпадают с исходным форматом: EncodeHash и номер коллизии
+	g := service.NewHashGenerator(service.DefaultCodeOptions)
+	for attempt, suffix := range map[int]string{0: "00", 1: "01", 63: "10", 3968: "__"} {
+		code, err := g.Code(context.Background(), "https://example.com/", attempt)
+		require.NoError(t, err)
+		assert.Equal(t, service.EncodeHash("https://example.com/")+suffix, code)
+		assert.Equal(t, suffix, service.IntToIndex63(attempt))
+	}
+	assert.Equal(t, 3968, g.MaxAttempts())
+}
+
+func TestHMACGenerator(t *testing.T) {
+	ctx := context.Background()
+	opts := service.CodeOptions{Alphabet: service.AlphabetBase62, Length: 7}
+	g := service.NewHMACGenerator(opts, []byte("secret"))
+	code, err := g.Code(ctx, "https://example.com/", 0)
+	require.NoError(t, err)
+	assert.Len(t, code, 7)
+	assert.True(t, strings.HasSuffix(code, "00"))
+
+	again, _ := g.Code(ctx, "https://example.com/", 0)
+	assert.Equal(t, code, again, "deterministic for the same key")
+	other, _ := service.NewHMACGenerator(opts, []byte("another")).Code(ctx, "https://example.com/", 0)
+	assert.NotEqual(t, code, other, "depends on the key")
+	plain, _ := service.NewHashGenerator(opts).Code(ctx, "https://example.com/", 0)
+	assert.NotEqual(t, code, plain, "differs from the public hash")
+}
+
+func TestRandomGenerator(t *testing.T) {
+	g := service.NewRandomGenerator(service.CodeOptions{Alphabet: "abc", Length: 12})
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		code, err := g.Code(context.Background(), "https://example.com/", 0)
+		require.NoError(t, err)
+		assert.Len(t, code, 12)
+		assert.Empty(t, strings.Trim(code, "abc"))
+		seen[code] = true
+	}
+	assert.Greater(t, len(seen), 95)
+}
+
+func TestCounterGenerator(t *testing.T) {
+	ctx := context.Background()
+	// 16 номеров на пространстве из 16 кодов дают все коды ровно по одному разу
+	opts := service.CodeOptions{Alphabet: "01", Length: 4}
+	g := service.NewCounterGenerator(opts, []byte("secret"), service.NewMemoryCounter(0))
+	codes := make(map[string]bool)
+	sequential := 0
+	for n := 0; n < 16; n++ {
+		code, err := g.Code(ctx, "", 0)
+		require.NoError(t, err)
+		assert.Len(t, code, 4)
+		codes[code] = true
+		if code == binaryCode(n) {
+			sequential++
+		}
+	}
+	assert.Len(t, codes, 16)
+	assert.Less(t, sequential, 16, "codes are permuted")
+
+	// Перестановка зависит от ключа и на большом пространстве
+	base62 := service.CodeOptions{Alphabet: service.AlphabetBase62, Length: 8}
+	a, _ := service.NewCounterGenerator(base62, []byte("a"), service.NewMemoryCounter(1000)).Code(ctx, "", 0)
+	b, _ := service.NewCounterGenerator(base62, []byte("b"), service.NewMemoryCounter(1000)).Code(ctx, "", 0)
+	next, _ := service.NewCounterGenerator(base62, []byte("a"), service.NewMemoryCounter(1001)).Code(ctx, "", 0)
+	assert.Len(t, a, 8)
+	assert.NotEqual(t, a, b)
+	assert.NotEqual(t, a[:6], next[:6], "neighbouring numbers give unrelated codes")
+}
+
+func binaryCode(n int) string {
+	var b strings.Builder
+	for bit := 3; bit >= 0; bit-- {
+		b.WriteByte('0' + byte(n>>bit&1))
+	}
+	return b.String()
+}
+
+func TestRedisCounter(t *testing.T) {
+	_, client := newRedisStorage(t)
+	counter := repository.NewRedisCounter(client)
+	first, err := counter.Next(context.Background())
+	require.NoError(t, err)
+	second, err := counter.Next(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, first+1, second)
+}
+
+func TestNewCodeGenerator(t *testing.T) {
+	opts := service.CodeOptions{Length: 8}
+	_, err := service.NewCodeGenerator(service.StrategyHMAC, opts, nil, nil)
+	assert.ErrorContains(t, err, "requires a secret")
+	_, err = service.NewCodeGenerator(service.StrategyCounter, opts, []byte("secret"), nil)
+	assert.ErrorContains(t, err, "requires a counter")
+	_, err = service.NewCodeGenerator("uuid", opts, nil, nil)
+	assert.ErrorContains(t, err, "unknown code strategy")
+
+	// Пустой алфавит: base62 для random и counter
+	g, err := service.NewCodeGenerator(service.StrategyRandom, opts, nil, nil)
+	require.NoError(t, err)
+	for i := 0; i < 50; i++ {
+		code, err := g.Code(context.Background(), "", 0)
+		require.NoError(t, err)
+		assert.NotContains(t, code, "_")
+	}
+}
+
+func TestShortening_Strategies(t *testing.T) {
+	ctx := context.Background()
+	opts := service.CodeOptions{Length: 6}
+	for _, strategy := range []string{service.StrategyHash, service.StrategyRandom, service.StrategyCounter, service.StrategyHMAC} {
+		t.Run(strategy, func(t *testing.T) {
+			generator, err := service.NewCodeGenerator(strategy, opts, []byte("secret"), service.NewMemoryCounter(0))
+			require.NoError(t, err)
+			cache := repository.NewCacheStorage()
+			shortener := service.NewShortenerService(cache, service.URLNormalizer{AllowedSchemes: []string{"https"}})
+			shortener.Generator = generator
+
+			first, err := shortener.Shortening(ctx, model.Link{LongURL: "https://example.com/a"})
+			require.NoError(t, err)
+			second, err := shortener.Shortening(ctx, model.Link{LongURL: "https://example.com/b"})
+			require.NoError(t, err)
+			assert.Len(t, first, 6)
+			assert.NotEqual(t, first, second)
+			longURL, err := shortener.Expansion(ctx, first)
+			assert.NoError(t, err)
+			assert.Equal(t, "https://example.com/a", longURL)
+
+			// Длинная ссылка уникальна, поэтому при любой стратегии возвращается уже выданный код
+			again, err := shortener.Shortening(ctx, model.Link{LongURL: "https://example.com/a"})
+			require.NoError(t, err)
+			assert.Equal(t, first, again)
+		})
+	}
+}
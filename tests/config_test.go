@@ -0,0 +1,167 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"url-shortener/config"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func TestLoadConfig_Defaults(t *testing.T) {
+	cfg, err := config.Load(config.Options{Env: map[string]string{}})
+	require.NoError(t, err)
+	assert.Equal(t, "cache", cfg.Storage)
+	assert.Equal(t, config.Listen{Type: "port", BindIP: "127.0.0.1", Port: "8080"}, cfg.Listen)
+	assert.Equal(t, "postgres", cfg.DataBase.Host)
+	assert.Equal(t, "5432", cfg.DataBase.Port)
+	assert.Equal(t, 5*time.Second, cfg.Timeouts.Write)
+	assert.Equal(t, []string{"http", "https"}, cfg.URL.AllowedSchemes)
+}
+
+func TestLoadConfig_Precedence(t *testing.T) {
+	envFile := writeFile(t, ".env", "PORT=8081\nBIND_IP=0.0.0.0\nBATCH_WORKERS=2\nDB_NAME=fromenvfile\n")
+	configFile := writeFile(t, "config.yaml", `
+storage: postgres
+listen:
+  port: 8082
+  bind_ip: 10.0.0.1
+database:
+  db_name: fromfile
+url:
+  allowed_schemes: [https]
+lru:
+  negative_ttl: 10s
+`)
+	cfg, err := config.Load(config.Options{
+		Args:    []string{"-config", configFile, "-listen.port=8084", "-lru.enabled", "-lru.negative-ttl=15s"},
+		Env:     map[string]string{"PORT": "8083", "DB_NAME": "fromenv"},
+		EnvFile: envFile,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "postgres", cfg.Storage)
+	assert.Equal(t, "8084", cfg.Listen.Port)        // флаг
+	assert.Equal(t, "fromenv", cfg.DataBase.DBName) // окружение
+	assert.Equal(t, "10.0.0.1", cfg.Listen.BindIP)  // файл конфигурации
+	assert.Equal(t, 2, cfg.Batch.Workers)           // .env
+	assert.Equal(t, []string{"https"}, cfg.URL.AllowedSchemes)
+	assert.Equal(t, 15*time.Second, cfg.LRU.NegativeTTL)
+	assert.True(t, cfg.LRU.Enabled)
+}
+
+func TestLoadConfig_TOML(t *testing.T) {
+	configFile := writeFile(t, "config.toml", `
+storage = "redis"
+
+[redis]
+addr = "redis:6380"
+pool_size = 4
+
+[rate_limit]
+enabled = true
+shorten_rate = 0.5
+`)
+	cfg, err := config.Load(config.Options{Env: map[string]string{"CONFIG_FILE": configFile}})
+	require.NoError(t, err)
+	assert.Equal(t, "redis", cfg.Storage)
+	assert.Equal(t, "redis:6380", cfg.Redis.Addr)
+	assert.Equal(t, 4, cfg.Redis.PoolSize)
+	assert.True(t, cfg.RateLimit.Enabled)
+	assert.Equal(t, 0.5, cfg.RateLimit.ShortenRate)
+}
+
+func TestLoadConfig_Errors(t *testing.T) {
+	unknown := writeFile(t, "config.yaml", "listen:\n  prot: 8080\n")
+	_, err := config.Load(config.Options{Args: []string{"-config", unknown}, Env: map[string]string{}})
+	assert.ErrorContains(t, err, "unknown key listen.prot")
+
+	_, err = config.Load(config.Options{Args: []string{"-config", writeFile(t, "config.json", "{}")}, Env: map[string]string{}})
+	assert.ErrorContains(t, err, "unsupported format")
+
+	_, err = config.Load(config.Options{Args: []string{"-storage=memory"}, Env: map[string]string{"PORT": "70000", "LISTEN_TYPE": "tcp"}})
+	assert.ErrorContains(t, err, `storage: unsupported value "memory"`)
+	assert.ErrorContains(t, err, "listen.type")
+
+	_, err = config.Load(config.Options{Env: map[string]string{"PORT": "70000"}})
+	assert.ErrorContains(t, err, `listen.port: "70000" is not a port`)
+
+	_, err = config.Load(config.Options{Args: []string{"-storage=postgres"}, Env: map[string]string{"DB_HOST": "", "DB_PORT": "x"}})
+	assert.ErrorContains(t, err, "database.host")
+	assert.ErrorContains(t, err, "database.port")
+
+	_, err = config.Load(config.Options{Env: map[string]string{"RATE_LIMIT_ENABLED": "true", "RATE_LIMIT_STORE": "postgres"}})
+	assert.ErrorContains(t, err, "rate_limit.store: postgres requires storage postgres")
+
+	_, err = config.Load(config.Options{Env: map[string]string{"BATCH_WORKERS": "many"}})
+	assert.Error(t, err)
+
+	_, err = config.Load(config.Options{Env: map[string]string{"TRUSTED_PROXIES": "10.0.0.0/8,proxy.local"}})
+	assert.ErrorContains(t, err, `listen.trusted_proxies: "proxy.local" is not an IP address or CIDR`)
+	cfg, err := config.Load(config.Options{Args: []string{"-listen.trusted-proxies=10.0.0.0/8,192.0.2.1"}, Env: map[string]string{}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.0/8", "192.0.2.1"}, cfg.Listen.TrustedProxies)
+
+	_, err = config.Load(config.Options{Args: []string{"-auto-migrate"}, Env: map[string]string{}})
+	assert.ErrorContains(t, err, "auto_migrate: requires storage postgres")
+
+	_, err = config.Load(config.Options{Args: []string{"-public.base-url=sho.rt"}, Env: map[string]string{}})
+	assert.ErrorContains(t, err, `public.base_url: "sho.rt"`)
+	cfg, err = config.Load(config.Options{Args: []string{"-public.base-url=https://sho.rt/s"}, Env: map[string]string{}})
+	assert.NoError(t, err)
+	assert.Equal(t, "https://sho.rt/s", cfg.Public.BaseURL)
+
+	_, err = config.Load(config.Options{Env: map[string]string{"CODE_STRATEGY": "hmac", "CODE_LENGTH": "33", "CODE_ALPHABET": "abca-"}})
+	assert.ErrorContains(t, err, "code.secret: required for strategy hmac")
+	assert.ErrorContains(t, err, "code.length: 33 is not in range")
+	assert.ErrorContains(t, err, "code.alphabet")
+	_, err = config.Load(config.Options{Args: []string{"-code.strategy=uuid"}, Env: map[string]string{}})
+	assert.ErrorContains(t, err, `code.strategy: unsupported value "uuid"`)
+
+	_, err = config.Load(config.Options{Args: []string{"-code.case-insensitive"}, Env: map[string]string{}})
+	assert.ErrorContains(t, err, "code.case_insensitive: requires code.alphabet")
+	_, err = config.Load(config.Options{Env: map[string]string{"CODE_CASE_INSENSITIVE": "true", "CODE_ALPHABET": "human"}})
+	assert.ErrorContains(t, err, `code.case_insensitive: alphabet "human" mixes upper and lower case letters`)
+	cfg, err = config.Load(config.Options{Env: map[string]string{
+		"CODE_CASE_INSENSITIVE": "true", "CODE_ALPHABET": "crockford32", "CODE_LENGTH": "16",
+	}})
+	assert.NoError(t, err)
+	assert.True(t, cfg.Code.CaseInsensitive)
+	assert.Equal(t, 16, cfg.Code.Length)
+}
+
+func TestConfig_Redacted(t *testing.T) {
+	cfg, err := config.Load(config.Options{Env: map[string]string{
+		"DB_PASSWORD":      "db-secret",
+		"AUTH_ADMIN_TOKEN": "admin-secret",
+	}})
+	require.NoError(t, err)
+	out := cfg.Redacted()
+	assert.NotContains(t, out, "db-secret")
+	assert.NotContains(t, out, "admin-secret")
+	assert.Contains(t, out, "password: '******'")
+	assert.Contains(t, out, "storage: cache")
+	assert.Contains(t, out, "write: 5s")
+
+	// Вывод можно использовать как файл конфигурации
+	configFile := writeFile(t, "config.yaml", strings.ReplaceAll(out, "'******'", "other"))
+	reloaded, err := config.Load(config.Options{Args: []string{"-config", configFile}, Env: map[string]string{}})
+	require.NoError(t, err)
+	assert.Equal(t, "other", reloaded.DataBase.Password)
+	assert.Equal(t, cfg.Timeouts, reloaded.Timeouts)
+	assert.Equal(t, cfg.URL, reloaded.URL)
+}
+
+func TestDataBase_DSN(t *testing.T) {
+	db := config.DataBase{Host: "db", Port: "5432", Username: "app", Password: "p@ss/word", DBName: "links"}
+	assert.Equal(t, "postgresql://app:p%40ss%2Fword@db:5432/links", db.DSN())
+}
@@ -9,8 +9,11 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"url-shortener/internal/controller"
 	"url-shortener/internal/model"
+	"url-shortener/internal/service"
+	"url-shortener/pkg/storage"
 
 	"url-shortener/tests/mocks"
 )
@@ -20,10 +23,10 @@ func TestShortenEndpoint(t *testing.T) {
 	router := gin.Default()
 
 	mockService := new(mocks.MockShortenerService)
-	mockService.On("Shortening", "https://example.com").Return("test_short_url", nil).Once()
+	mockService.On("Shortening", mock.Anything, model.Link{LongURL: "https://example.com"}).Return("test_short_url", nil).Once()
 
-	handler := handler.NewHandler(mockService, nil)
-	handler.Register(router)
+	h := handler.NewHandler(mockService, new(mocks.MockAnalyticsService), nil, handler.Options{RedirectStatus: http.StatusFound})
+	h.Register(router, handler.Middlewares{})
 
 	reqBody := model.LongURL{URL: "https://example.com"}
 	req := httptest.NewRequest(http.MethodPost, "/shorten", convertToJSON(reqBody))
@@ -38,6 +41,121 @@ func TestShortenEndpoint(t *testing.T) {
 	mockService.AssertExpectations(t)
 }
 
+func TestShortenEndpointAliasTaken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+
+	mockService := new(mocks.MockShortenerService)
+	mockService.On("CustomShortening", mock.Anything, model.Link{ShortURL: "promo", LongURL: "https://example.com"}).Return("", service.ErrAliasTaken).Once()
+
+	h := handler.NewHandler(mockService, new(mocks.MockAnalyticsService), nil, handler.Options{RedirectStatus: http.StatusFound})
+	h.Register(router, handler.Middlewares{})
+
+	reqBody := model.LongURL{URL: "https://example.com", Alias: "promo"}
+	req := httptest.NewRequest(http.MethodPost, "/shorten", convertToJSON(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestShortenEndpointInvalidURL(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+
+	mockService := new(mocks.MockShortenerService)
+	validationErr := &service.URLValidationError{Reason: service.ReasonSchemeNotAllowed, Detail: "scheme javascript is not allowed"}
+	mockService.On("Shortening", mock.Anything, model.Link{LongURL: "javascript:alert(1)"}).Return("", validationErr).Once()
+
+	h := handler.NewHandler(mockService, new(mocks.MockAnalyticsService), nil, handler.Options{RedirectStatus: http.StatusFound})
+	h.Register(router, handler.Middlewares{})
+
+	req := httptest.NewRequest(http.MethodPost, "/shorten", convertToJSON(model.LongURL{URL: "javascript:alert(1)"}))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.JSONEq(t, `{"message": "invalid url: scheme javascript is not allowed", "field": "long_url", "reason": "scheme_not_allowed"}`, w.Body.String())
+
+	mockService.AssertExpectations(t)
+}
+
+func TestRedirectEndpoint(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+
+	mockService := new(mocks.MockShortenerService)
+	mockService.On("Expansion", mock.Anything, "abc").Return("https://пример.рф/путь?q=a b#top", nil).Once()
+	mockAnalytics := new(mocks.MockAnalyticsService)
+	mockAnalytics.On("RecordClick", mock.MatchedBy(func(click model.Click) bool {
+		return click.ShortURL == "abc" && click.Referrer == "https://news.example"
+	})).Once()
+
+	h := handler.NewHandler(mockService, mockAnalytics, nil, handler.Options{RedirectStatus: http.StatusMovedPermanently})
+	h.Register(router, handler.Middlewares{})
+
+	req := httptest.NewRequest(http.MethodGet, "/abc", nil)
+	req.Header.Set("Referer", "https://news.example")
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, "https://xn--e1afmkfd.xn--p1ai/%D0%BF%D1%83%D1%82%D1%8C?q=a%20b#top", w.Header().Get("Location"))
+
+	mockService.AssertExpectations(t)
+	mockAnalytics.AssertExpectations(t)
+}
+
+func TestRedirectNotFound(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+
+	mockService := new(mocks.MockShortenerService)
+	mockService.On("Expansion", mock.Anything, "missing").Return("", storage.ErrNotFound).Once()
+
+	h := handler.NewHandler(mockService, new(mocks.MockAnalyticsService), nil, handler.Options{RedirectStatus: http.StatusFound})
+	h.Register(router, handler.Middlewares{})
+
+	req := httptest.NewRequest(http.MethodGet, "/missing", nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Empty(t, w.Header().Get("Location"))
+
+	mockService.AssertExpectations(t)
+}
+
+func TestStatsEndpoint(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+
+	mockAnalytics := new(mocks.MockAnalyticsService)
+	stats := model.LinkStats{ShortURL: "abc", Total: 3, Daily: []model.DailyClicks{{Date: "2025-03-25", Clicks: 3}}}
+	mockAnalytics.On("Stats", mock.Anything, "abc", "", 7).Return(stats, nil).Once()
+
+	h := handler.NewHandler(new(mocks.MockShortenerService), mockAnalytics, nil, handler.Options{RedirectStatus: http.StatusFound})
+	h.Register(router, handler.Middlewares{})
+
+	req := httptest.NewRequest(http.MethodGet, "/links/abc/stats?days=7", nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"short_url": "abc", "total": 3, "daily": [{"date": "2025-03-25", "clicks": 3}]}`, w.Body.String())
+
+	mockAnalytics.AssertExpectations(t)
+}
+
 func convertToJSON(data interface{}) *bytes.Buffer {
 	body, _ := json.Marshal(data)
 	return bytes.NewBuffer(body)
@@ -0,0 +1,139 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"url-shortener/internal/model"
+	"url-shortener/internal/repository"
+	"url-shortener/pkg/storage"
+)
+
+func TestFile_PersistsAcrossRestart(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "data", "links.db")
+
+	fileStorage, err := repository.NewFileStorage(path)
+	require.NoError(t, err)
+	assert.NoError(t, fileStorage.Insert(ctx, model.Link{ShortURL: "abc", LongURL: "https://example.com", Owner: "alice"}))
+	assert.NoError(t, fileStorage.Insert(ctx, model.Link{ShortURL: "gone", LongURL: "https://example.net"}))
+	assert.NoError(t, fileStorage.Delete(ctx, "gone", ""))
+	_, err = fileStorage.Update(ctx, model.Link{ShortURL: "abc", LongURL: "https://example.org"})
+	assert.NoError(t, err)
+	assert.NoError(t, fileStorage.Close())
+
+	fileStorage, err = repository.NewFileStorage(path)
+	require.NoError(t, err)
+	defer fileStorage.Close()
+	link, err := fileStorage.GetLink(ctx, "abc")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.org", link.LongURL)
+	assert.Equal(t, "alice", link.Owner)
+	_, err = fileStorage.GetLongUrl(ctx, "gone")
+	assert.Equal(t, storage.ErrNotFound, err)
+}
+
+func TestFile_RejectsForeignFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "links.db")
+	require.NoError(t, os.WriteFile(path, []byte(strings.Repeat("not a database\n", 1024)), 0o644))
+	_, err := repository.NewFileStorage(path)
+	assert.Error(t, err)
+}
+
+func TestFile_DuplicatesAndExpiry(t *testing.T) {
+	ctx := context.Background()
+	fileStorage, err := repository.NewFileStorage(filepath.Join(t.TempDir(), "links.db"))
+	require.NoError(t, err)
+	defer fileStorage.Close()
+
+	assert.NoError(t, fileStorage.Insert(ctx, model.Link{ShortURL: "abc", LongURL: "https://example.com"}))
+	assert.Equal(t, storage.ErrAlreadyExists, fileStorage.Insert(ctx, model.Link{ShortURL: "abc", LongURL: "https://example.org"}))
+
+	// Как UNIQUE(long_url) в Postgres: ссылка, сохранённая под другим кодом, не сохраняется повторно
+	assert.Equal(t, storage.ErrAlreadyExists, fileStorage.Insert(ctx, model.Link{ShortURL: "alias", LongURL: "https://example.com"}))
+	code, exists, err := fileStorage.Allocate(ctx, model.Link{ShortURL: "other", LongURL: "https://example.com"})
+	assert.NoError(t, err)
+	assert.Equal(t, "abc", code)
+	assert.True(t, exists)
+	_, err = fileStorage.Update(ctx, model.Link{ShortURL: "abc", LongURL: "https://example.com/"})
+	assert.NoError(t, err)
+	assert.NoError(t, fileStorage.Insert(ctx, model.Link{ShortURL: "alias", LongURL: "https://example.com"}))
+	_, err = fileStorage.Update(ctx, model.Link{ShortURL: "alias", LongURL: "https://example.com/"})
+	assert.Equal(t, storage.ErrAlreadyExists, err)
+
+	assert.NoError(t, fileStorage.Insert(ctx, model.Link{ShortURL: "old", LongURL: "https://example.net", ExpiresAt: time.Now().Add(-time.Second)}))
+	_, err = fileStorage.GetLongUrl(ctx, "old")
+	assert.Equal(t, storage.ErrExpired, err)
+
+	// Как в Postgres: пакетная вставка не занимает истёкшие коды, одиночная - занимает
+	errs, err := fileStorage.InsertBatch(ctx, []model.Link{
+		{ShortURL: "new", LongURL: "https://example.com/new"},
+		{ShortURL: "abc", LongURL: "https://example.com/abc"},
+		{ShortURL: "old", LongURL: "https://example.com/old"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []error{nil, storage.ErrAlreadyExists, storage.ErrAlreadyExists}, errs)
+	assert.NoError(t, fileStorage.Insert(ctx, model.Link{ShortURL: "old", LongURL: "https://example.org"}))
+}
+
+func TestFile_DeleteExpired(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "links.db")
+	fileStorage, err := repository.NewFileStorage(path)
+	require.NoError(t, err)
+	defer fileStorage.Close()
+
+	longURL := "https://example.com/" + strings.Repeat("a", 4096)
+	fill := func(round int) {
+		for i := 0; i < 200; i++ {
+			link := model.Link{ShortURL: fmt.Sprintf("c%d", i), LongURL: fmt.Sprintf("%s/%d/%d", longURL, round, i), ExpiresAt: time.Now().Add(time.Minute)}
+			require.NoError(t, fileStorage.Insert(ctx, link))
+		}
+	}
+	fill(0)
+	assert.NoError(t, fileStorage.Insert(ctx, model.Link{ShortURL: "keep", LongURL: "https://example.com"}))
+	n, err := fileStorage.DeleteExpired(ctx, time.Now().Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(200), n)
+	before, err := os.Stat(path)
+	require.NoError(t, err)
+
+	// Коды и ссылки удалённых записей освобождены, место в файле переиспользуется
+	fill(1)
+	n, err = fileStorage.DeleteExpired(ctx, time.Now().Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(200), n)
+	after, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.LessOrEqual(t, after.Size(), before.Size()*3/2)
+	value, err := fileStorage.GetLongUrl(ctx, "keep")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com", value)
+}
+
+func TestFile_DeleteExpiredClicks(t *testing.T) {
+	ctx := context.Background()
+	fileStorage, err := repository.NewFileStorage(filepath.Join(t.TempDir(), "links.db"))
+	require.NoError(t, err)
+	defer fileStorage.Close()
+	analytics := repository.NewFileAnalyticsStorage(fileStorage)
+
+	require.NoError(t, fileStorage.Insert(ctx, model.Link{ShortURL: "gone", LongURL: "https://example.com", ExpiresAt: time.Now().Add(time.Minute)}))
+	require.NoError(t, analytics.InsertClick(ctx, model.Click{ShortURL: "gone", Timestamp: time.Now()}))
+	n, err := fileStorage.DeleteExpired(ctx, time.Now().Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	// Вместе со ссылкой удаляется и её статистика переходов
+	total, daily, err := analytics.ClickStats(ctx, "gone", time.Time{})
+	assert.NoError(t, err)
+	assert.Zero(t, total)
+	assert.Empty(t, daily)
+}
@@ -0,0 +1,102 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"url-shortener/config"
+	"url-shortener/internal/controller"
+	"url-shortener/internal/model"
+	"url-shortener/internal/service"
+	"url-shortener/pkg/storage/redis"
+
+	"url-shortener/tests/mocks"
+)
+
+func TestHealthService_Ready(t *testing.T) {
+	health := service.NewHealthService(time.Second)
+	health.AddCheck("storage", func(context.Context) (string, error) { return "cache", nil })
+	res := health.Ready(context.Background())
+	assert.Equal(t, service.HealthOK, res.Status)
+	assert.False(t, res.ShuttingDown)
+	assert.Equal(t, "cache", res.Checks["storage"].Detail)
+
+	health.AddCheck("postgres", func(context.Context) (string, error) { return "", errors.New("connection refused") })
+	res = health.Ready(context.Background())
+	assert.Equal(t, service.HealthUnavailable, res.Status)
+	assert.Equal(t, service.HealthOK, res.Checks["storage"].Status)
+	assert.Equal(t, service.HealthUnavailable, res.Checks["postgres"].Status)
+	assert.Equal(t, "connection refused", res.Checks["postgres"].Error)
+}
+
+func TestHealthService_CheckTimeout(t *testing.T) {
+	health := service.NewHealthService(20 * time.Millisecond)
+	health.AddCheck("slow", func(ctx context.Context) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	})
+	res := health.Ready(context.Background())
+	assert.Equal(t, service.HealthUnavailable, res.Checks["slow"].Status)
+	assert.Equal(t, context.DeadlineExceeded.Error(), res.Checks["slow"].Error)
+}
+
+func TestHealthService_ShuttingDown(t *testing.T) {
+	health := service.NewHealthService(time.Second)
+	health.AddCheck("storage", func(context.Context) (string, error) { return "", nil })
+	health.StartShutdown()
+	res := health.Ready(context.Background())
+	assert.Equal(t, service.HealthUnavailable, res.Status)
+	assert.True(t, res.ShuttingDown)
+}
+
+func TestHealthEndpoints(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	health := service.NewHealthService(time.Second)
+	health.AddCheck("storage", func(context.Context) (string, error) { return "cache", nil })
+	handler.NewHealthHandler(health).Register(router)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"status": "ok"}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	var res model.Readiness
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	assert.Equal(t, "ok", res.Checks["storage"].Status)
+
+	health.StartShutdown()
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	assert.True(t, res.ShuttingDown)
+
+	// Процесс жив, пока завершает работу
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestRedisClient_Ping(t *testing.T) {
+	server, err := mocks.NewMockRedisServer()
+	assert.NoError(t, err)
+	client, err := redis.NewClient(context.Background(), config.Redis{Addr: server.Addr(), PoolSize: 1})
+	assert.NoError(t, err)
+	defer client.Close()
+	assert.NoError(t, client.Ping(context.Background()))
+
+	server.Close()
+	client.Close()
+	assert.Error(t, client.Ping(context.Background()))
+}
@@ -0,0 +1,59 @@
+package tests
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"url-shortener/pkg/storage/journal"
+)
+
+func journalOf(t *testing.T, records ...string) []byte {
+	var buf bytes.Buffer
+	for _, rec := range records {
+		_, err := journal.Append(&buf, []byte(rec))
+		require.NoError(t, err)
+	}
+	return buf.Bytes()
+}
+
+func replayAll(data []byte) ([]string, int64, error) {
+	var got []string
+	valid, err := journal.Replay(bytes.NewReader(data), func(payload []byte, _ int64) error {
+		got = append(got, string(payload))
+		return nil
+	})
+	return got, valid, err
+}
+
+func TestJournal_TornTail(t *testing.T) {
+	data := journalOf(t, "one", "two", "three")
+	full := int64(len(journalOf(t, "one", "two")))
+
+	// Оборванная последняя запись
+	got, valid, err := replayAll(data[:len(data)-2])
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"one", "two"}, got)
+	assert.Equal(t, full, valid)
+
+	// Последняя запись дописана нулями: длина файла увеличена, данные не записаны
+	torn := append([]byte{}, data...)
+	for i := full + journal.HeaderSize; i < int64(len(torn)); i++ {
+		torn[i] = 0
+	}
+	got, valid, err = replayAll(append(torn, make([]byte, 64)...))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"one", "two"}, got)
+	assert.Equal(t, full, valid)
+}
+
+func TestJournal_CorruptedMiddle(t *testing.T) {
+	data := journalOf(t, "one", "two", "three")
+	// Порча данных второй записи, за которой лежит целая третья
+	data[len(journalOf(t, "one"))+journal.HeaderSize] ^= 0xff
+	got, valid, err := replayAll(data)
+	assert.ErrorIs(t, err, journal.ErrCorrupted)
+	assert.Equal(t, []string{"one"}, got)
+	assert.Equal(t, int64(len(journalOf(t, "one"))), valid)
+}
@@ -0,0 +1,169 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"url-shortener/internal/controller"
+	"url-shortener/internal/model"
+	"url-shortener/internal/repository"
+	"url-shortener/internal/service"
+	"url-shortener/pkg/storage"
+
+	"url-shortener/tests/mocks"
+)
+
+func TestLinkManagement(t *testing.T) {
+	cache := repository.NewCacheStorage()
+	assert.NoError(t, cache.Insert(context.Background(), model.Link{ShortURL: "promo", LongURL: "https://example.com/", Owner: "alice"}))
+	s := service.NewShortenerService(cache, service.URLNormalizer{AllowedSchemes: []string{"https"}})
+
+	link, err := s.GetLink(context.Background(), "promo", "alice")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/", link.LongURL)
+	assert.False(t, link.CreatedAt.IsZero())
+
+	_, err = s.GetLink(context.Background(), "promo", "bob")
+	assert.ErrorIs(t, err, service.ErrForbidden)
+
+	// Без аутентификации владелец не проверяется
+	_, err = s.GetLink(context.Background(), "promo", "")
+	assert.NoError(t, err)
+
+	expiresAt := time.Now().Add(time.Hour).UTC()
+	updated, err := s.UpdateLink(context.Background(), "promo", "alice", "https://Example.org", &expiresAt)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.org/", updated.LongURL)
+	assert.Equal(t, expiresAt, updated.ExpiresAt)
+	assert.Equal(t, link.CreatedAt, updated.CreatedAt)
+	assert.False(t, updated.UpdatedAt.Before(link.UpdatedAt))
+
+	// Нулевой срок делает ссылку бессрочной
+	updated, err = s.UpdateLink(context.Background(), "promo", "alice", "", &time.Time{})
+	assert.NoError(t, err)
+	assert.True(t, updated.ExpiresAt.IsZero())
+
+	_, err = s.UpdateLink(context.Background(), "promo", "alice", "ftp://example.org", nil)
+	var validationErr *service.URLValidationError
+	assert.ErrorAs(t, err, &validationErr)
+
+	assert.ErrorIs(t, s.DeleteLink(context.Background(), "promo", "bob"), service.ErrForbidden)
+	assert.NoError(t, s.DeleteLink(context.Background(), "promo", "alice"))
+	_, err = cache.GetLongUrl(context.Background(), "promo")
+	assert.Equal(t, storage.ErrNotFound, err)
+	assert.ErrorIs(t, s.DeleteLink(context.Background(), "promo", "alice"), storage.ErrNotFound)
+}
+
+func TestLinkEndpoints(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+
+	createdAt := time.Date(2025, 4, 10, 12, 0, 0, 0, time.UTC)
+	link := model.Link{ShortURL: "promo", LongURL: "https://example.com/", CreatedAt: createdAt, UpdatedAt: createdAt}
+	mockService := new(mocks.MockShortenerService)
+	mockService.On("GetLink", mock.Anything, "promo", "").Return(link, nil).Once()
+	mockService.On("UpdateLink", mock.Anything, "promo", "", "https://example.org", (*time.Time)(nil)).Return(link, nil).Once()
+	mockService.On("UpdateLink", mock.Anything, "promo", "", "https://example.net", (*time.Time)(nil)).Return(model.Link{}, storage.ErrAlreadyExists).Once()
+	mockService.On("UpdateLink", mock.Anything, "promo", "", "", &time.Time{}).Return(link, nil).Once()
+	mockService.On("DeleteLink", mock.Anything, "missing", "").Return(storage.ErrNotFound).Once()
+	mockService.On("DeleteLink", mock.Anything, "promo", "").Return(service.ErrForbidden).Once()
+
+	h := handler.NewHandler(mockService, new(mocks.MockAnalyticsService), nil, handler.Options{})
+	h.Register(router, handler.Middlewares{})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/links/promo", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	var info model.LinkInfo
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &info))
+	assert.Equal(t, "https://example.com/", info.LongURL)
+	assert.Equal(t, createdAt, info.CreatedAt)
+	assert.Nil(t, info.ExpiresAt)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPatch, "/links/promo", strings.NewReader(`{"long_url": "https://example.org"}`)))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPatch, "/links/promo", strings.NewReader(`{"long_url": "https://example.net"}`)))
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPatch, "/links/promo", strings.NewReader(`{}`)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPatch, "/links/promo", strings.NewReader(`{"ttl_seconds": 9223372036854775807}`)))
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Contains(t, w.Body.String(), `"field":"ttl_seconds"`)
+
+	// "expires_at": null снимает срок действия, вместе с ttl_seconds - противоречивый запрос
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPatch, "/links/promo", strings.NewReader(`{"expires_at": null}`)))
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPatch, "/links/promo", strings.NewReader(`{"expires_at": null, "ttl_seconds": 60}`)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/links/missing", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/links/promo", nil))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	mockService.AssertExpectations(t)
+	mockService.AssertNotCalled(t, "UpdateLink", "promo", "", "", mock.Anything)
+}
+
+func TestStorage_UpdateDeleteOwner(t *testing.T) {
+	ctx := context.Background()
+	for name, s := range allocateBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Millisecond)
+			require.NoError(t, s.Insert(ctx, model.Link{ShortURL: "promo", LongURL: "https://example.com/", Owner: "alice", ExpiresAt: expiresAt}))
+			require.NoError(t, s.Insert(ctx, model.Link{ShortURL: "other", LongURL: "https://example.net/", Owner: "bob"}))
+
+			_, err := s.Update(ctx, model.Link{ShortURL: "promo", LongURL: "https://example.org/", Owner: "bob"})
+			assert.ErrorIs(t, err, storage.ErrForbidden)
+			_, err = s.Update(ctx, model.Link{ShortURL: "missing", LongURL: "https://example.org/", Owner: "bob"})
+			assert.ErrorIs(t, err, storage.ErrNotFound)
+			_, err = s.Update(ctx, model.Link{ShortURL: "promo", LongURL: "https://example.net/", Owner: "alice"})
+			assert.ErrorIs(t, err, storage.ErrAlreadyExists)
+
+			// Пустая длинная ссылка и нулевой срок не меняются
+			updated, err := s.Update(ctx, model.Link{ShortURL: "promo", Owner: "alice"})
+			require.NoError(t, err)
+			assert.Equal(t, "https://example.com/", updated.LongURL)
+			assert.True(t, expiresAt.Equal(updated.ExpiresAt))
+			updated, err = s.Update(ctx, model.Link{ShortURL: "promo", LongURL: "https://example.org/", Owner: "alice"})
+			require.NoError(t, err)
+			assert.Equal(t, "https://example.org/", updated.LongURL)
+			assert.True(t, expiresAt.Equal(updated.ExpiresAt))
+
+			// Срок model.NoExpiry делает ссылку бессрочной
+			updated, err = s.Update(ctx, model.Link{ShortURL: "promo", ExpiresAt: model.NoExpiry, Owner: "alice"})
+			require.NoError(t, err)
+			assert.True(t, updated.ExpiresAt.IsZero())
+			stored, err := s.GetLink(ctx, "promo")
+			require.NoError(t, err)
+			assert.True(t, stored.ExpiresAt.IsZero())
+
+			assert.ErrorIs(t, s.Delete(ctx, "promo", "bob"), storage.ErrForbidden)
+			assert.NoError(t, s.Delete(ctx, "promo", "alice"))
+			assert.ErrorIs(t, s.Delete(ctx, "promo", "alice"), storage.ErrNotFound)
+			// Без владельца удаляется любая ссылка
+			assert.NoError(t, s.Delete(ctx, "other", ""))
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"url-shortener/internal/model"
+	"url-shortener/internal/service"
+	"url-shortener/pkg/storage"
+
+	"url-shortener/tests/mocks"
+)
+
+func TestLRUStorage_ReadThrough(t *testing.T) {
+	ctx := context.Background()
+	mockStorage := new(mocks.MockStorage)
+	mockStorage.On("GetLink", mock.Anything, "abc").Return(model.Link{ShortURL: "abc", LongURL: "https://example.com"}, nil).Once()
+	lru := service.NewLRUStorage(mockStorage, service.LRUOptions{Size: 10, TTL: time.Minute})
+
+	for i := 0; i < 3; i++ {
+		value, err := lru.GetLongUrl(ctx, "abc")
+		assert.NoError(t, err)
+		assert.Equal(t, "https://example.com", value)
+	}
+	assert.Equal(t, uint64(2), lru.Hits())
+	assert.Equal(t, uint64(1), lru.Misses())
+	mockStorage.AssertExpectations(t)
+}
+
+func TestLRUStorage_NegativeCaching(t *testing.T) {
+	ctx := context.Background()
+	mockStorage := new(mocks.MockStorage)
+	mockStorage.On("GetLink", mock.Anything, "new").Return(model.Link{}, storage.ErrNotFound).Once()
+	mockStorage.On("Insert", mock.Anything, mock.Anything).Return(nil).Once()
+	lru := service.NewLRUStorage(mockStorage, service.LRUOptions{Size: 10, TTL: time.Minute, NegativeTTL: time.Minute})
+
+	_, err := lru.GetLongUrl(ctx, "new")
+	assert.Equal(t, storage.ErrNotFound, err)
+	_, err = lru.GetLongUrl(ctx, "new")
+	assert.Equal(t, storage.ErrNotFound, err)
+	assert.Equal(t, uint64(1), lru.Hits())
+
+	// Вставка через кэш сбрасывает отметку об отсутствии
+	assert.NoError(t, lru.Insert(ctx, model.Link{ShortURL: "new", LongURL: "https://example.com"}))
+	mockStorage.On("GetLink", mock.Anything, "new").Return(model.Link{ShortURL: "new", LongURL: "https://example.com"}, nil).Once()
+	value, err := lru.GetLongUrl(ctx, "new")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com", value)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestLRUStorage_EvictionAndExpiry(t *testing.T) {
+	ctx := context.Background()
+	mockStorage := new(mocks.MockStorage)
+	mockStorage.On("GetLink", mock.Anything, "a").Return(model.Link{ShortURL: "a", LongURL: "https://example.com/a"}, nil).Twice()
+	mockStorage.On("GetLink", mock.Anything, "b").Return(model.Link{ShortURL: "b", LongURL: "https://example.com/b"}, nil).Once()
+	mockStorage.On("GetLink", mock.Anything, "soon").
+		Return(model.Link{ShortURL: "soon", LongURL: "https://example.com", ExpiresAt: time.Now().Add(20 * time.Millisecond)}, nil).Once()
+	lru := service.NewLRUStorage(mockStorage, service.LRUOptions{Size: 2, TTL: time.Minute})
+
+	// Размер кэша - два кода, "a" вытесняется последним использованным "soon"
+	_, _ = lru.GetLongUrl(ctx, "a")
+	_, _ = lru.GetLongUrl(ctx, "b")
+	_, _ = lru.GetLongUrl(ctx, "soon")
+	_, _ = lru.GetLongUrl(ctx, "a")
+
+	// Срок действия ссылки проверяется при попадании в кэш
+	time.Sleep(30 * time.Millisecond)
+	_, err := lru.GetLongUrl(ctx, "soon")
+	assert.Equal(t, storage.ErrExpired, err)
+	mockStorage.AssertExpectations(t)
+}
@@ -0,0 +1,133 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"url-shortener/internal/controller"
+	"url-shortener/internal/model"
+	"url-shortener/internal/repository"
+	"url-shortener/internal/service"
+	"url-shortener/pkg/metrics"
+	"url-shortener/pkg/storage"
+
+	"url-shortener/tests/mocks"
+)
+
+func scrape(registry *metrics.Registry) string {
+	w := httptest.NewRecorder()
+	registry.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	return w.Body.String()
+}
+
+func TestRegistry_TextFormat(t *testing.T) {
+	registry := metrics.NewRegistry()
+	counter := registry.NewCounterVec("test_total", "Test counter.", "kind")
+	counter.With(`a"b`).Add(2)
+	histogram := registry.NewHistogramVec("test_seconds", "Test histogram.", []float64{0.1, 1})
+	histogram.With().Observe(0.05)
+	histogram.With().Observe(0.5)
+	histogram.With().Observe(3)
+	registry.NewGaugeFunc("test_gauge", "Test gauge.", func() float64 { return 7 })
+
+	out := scrape(registry)
+	assert.Contains(t, out, "# TYPE test_total counter\ntest_total{kind=\"a\\\"b\"} 2\n")
+	assert.Contains(t, out, "# TYPE test_seconds histogram\n"+
+		"test_seconds_bucket{le=\"0.1\"} 1\n"+
+		"test_seconds_bucket{le=\"1\"} 2\n"+
+		"test_seconds_bucket{le=\"+Inf\"} 3\n"+
+		"test_seconds_sum 3.55\n"+
+		"test_seconds_count 3\n")
+	assert.Contains(t, out, "# TYPE test_gauge gauge\ntest_gauge 7\n")
+}
+
+func TestHTTPMetrics_RouteAndStatus(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	registry := metrics.NewRegistry()
+	httpMetrics := handler.NewHTTPMetrics(registry)
+	router.Use(httpMetrics.Middleware())
+	httpMetrics.Register(router)
+
+	mockService := new(mocks.MockShortenerService)
+	mockService.On("Expansion", mock.Anything, "missing").Return("", storage.ErrNotFound).Once()
+	h := handler.NewHandler(mockService, new(mocks.MockAnalyticsService), nil, handler.Options{RedirectStatus: http.StatusFound})
+	h.Register(router, handler.Middlewares{})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/missing", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/no/such/route", nil))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/plain")
+	assert.Contains(t, w.Body.String(), `http_requests_total{method="GET",route="/:code",status="404"} 1`)
+	assert.Contains(t, w.Body.String(), `http_requests_total{method="POST",route="unmatched",status="404"} 1`)
+	assert.Contains(t, w.Body.String(), `http_request_duration_seconds_count{method="GET",route="/:code",status="404"} 1`)
+	mockService.AssertExpectations(t)
+}
+
+func TestShortenerService_Metrics(t *testing.T) {
+	ctx := context.Background()
+	registry := metrics.NewRegistry()
+	serviceMetrics := service.NewMetrics(registry, service.NewHashGenerator(service.DefaultCodeOptions).MaxAttempts())
+	var store service.Storage = repository.NewCacheStorage()
+	store = service.NewMetricsStorage(store, "cache", serviceMetrics)
+	svc := service.NewShortenerService(store, service.URLNormalizer{})
+	svc.Metrics = serviceMetrics
+
+	shortUrl, err := svc.Shortening(ctx, model.Link{LongURL: "https://example.com"})
+	assert.NoError(t, err)
+	_, err = svc.Shortening(ctx, model.Link{LongURL: "https://example.com"})
+	assert.NoError(t, err)
+	_, err = svc.Shortening(ctx, model.Link{LongURL: "not a url"})
+	assert.Error(t, err)
+	_, err = svc.CustomShortening(ctx, model.Link{ShortURL: shortUrl, LongURL: "https://example.org"})
+	assert.ErrorIs(t, err, service.ErrAliasTaken)
+	_, err = svc.Expansion(ctx, shortUrl)
+	assert.NoError(t, err)
+	_, err = svc.Expansion(ctx, "missing")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+	svc.BatchShortening(ctx, []model.Link{{LongURL: "https://example.com/a"}, {LongURL: "https://example.com/a"}}, 2)
+
+	out := scrape(registry)
+	assert.Contains(t, out, `shortener_shorten_total{mode="hash",outcome="created"} 1`)
+	assert.Contains(t, out, `shortener_shorten_total{mode="hash",outcome="existing"} 1`)
+	assert.Contains(t, out, `shortener_shorten_total{mode="hash",outcome="invalid"} 1`)
+	assert.Contains(t, out, `shortener_shorten_total{mode="custom",outcome="taken"} 1`)
+	assert.Contains(t, out, `shortener_shorten_total{mode="batch",outcome="created"} 1`)
+	assert.Contains(t, out, `shortener_shorten_total{mode="batch",outcome="existing"} 1`)
+	assert.Contains(t, out, `shortener_expand_total{outcome="found"} 1`)
+	assert.Contains(t, out, `shortener_expand_total{outcome="not_found"} 1`)
+	assert.Contains(t, out, `shortener_collision_depth_bucket{le="0"} 4`)
+	// Последняя корзина - последняя попытка генератора
+	assert.Contains(t, out, `shortener_collision_depth_bucket{le="3967"} 4`)
+	// Вычисленные коды пакета занимаются одним обращением AllocateBatch
+	assert.Contains(t, out, `shortener_storage_operation_duration_seconds_count{backend="cache",operation="allocate",result="ok"} 2`)
+	assert.Contains(t, out, `shortener_storage_operation_duration_seconds_count{backend="cache",operation="allocate_batch",result="ok"} 1`)
+}
+
+// Режим сокращения - стратегия генератора, корзины глубины ограничены его количеством попыток
+func TestShortenerService_MetricsStrategy(t *testing.T) {
+	ctx := context.Background()
+	generator, err := service.NewCodeGenerator(service.StrategyRandom, service.CodeOptions{Length: 8}, nil, nil)
+	require.NoError(t, err)
+	registry := metrics.NewRegistry()
+	svc := service.NewShortenerService(repository.NewCacheStorage(), service.URLNormalizer{})
+	svc.Generator = generator
+	svc.Metrics = service.NewMetrics(registry, generator.MaxAttempts())
+
+	_, err = svc.Shortening(ctx, model.Link{LongURL: "https://example.com"})
+	assert.NoError(t, err)
+
+	out := scrape(registry)
+	assert.Contains(t, out, `shortener_shorten_total{mode="random",outcome="created"} 1`)
+	assert.Contains(t, out, `shortener_collision_depth_bucket{le="9"} 1`)
+	assert.NotContains(t, out, `le="10"`)
+}
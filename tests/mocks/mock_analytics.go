@@ -0,0 +1,22 @@
+package mocks
+
+import (
+	"context"
+
+	"url-shortener/internal/model"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockAnalyticsService struct {
+	mock.Mock
+}
+
+func (m *MockAnalyticsService) RecordClick(click model.Click) {
+	m.Called(click)
+}
+
+func (m *MockAnalyticsService) Stats(ctx context.Context, shortUrl, owner string, days int) (model.LinkStats, error) {
+	args := m.Called(ctx, shortUrl, owner, days)
+	return args.Get(0).(model.LinkStats), args.Error(1)
+}
@@ -0,0 +1,12 @@
+package mocks
+
+// MockLogger отбрасывает все сообщения
+type MockLogger struct{}
+
+func (MockLogger) Info(args ...interface{})  {}
+func (MockLogger) Error(args ...interface{}) {}
+func (MockLogger) Fatal(args ...interface{}) {}
+
+func (MockLogger) Infof(format string, args ...interface{})  {}
+func (MockLogger) Errorf(format string, args ...interface{}) {}
+func (MockLogger) Fatalf(format string, args ...interface{}) {}
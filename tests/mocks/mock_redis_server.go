@@ -0,0 +1,357 @@
+package mocks
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"url-shortener/pkg/storage/redis"
+)
+
+// MockRedisServer - сервер протокола RESP в памяти процесса с командами
+// PING, AUTH, SELECT, GET, SET (NX, XX, EX, PX, PXAT), DEL, INCR, PTTL, хэшами HGET, HSET, HEXISTS,
+// HINCRBY, HGETALL и транзакциями WATCH, MULTI, EXEC
+type MockRedisServer struct {
+	listener net.Listener
+	data     map[string]redisEntry
+	versions map[string]uint64 // Номер изменения ключа для WATCH
+	mu       sync.Mutex
+	accepted atomic.Int64
+}
+
+// Состояние транзакции соединения
+type redisConnState struct {
+	watched map[string]uint64
+	queue   [][]string
+	multi   bool
+}
+
+type redisEntry struct {
+	value     string
+	fields    map[string]string // Поля ключа-хэша, nil для строкового ключа
+	expiresAt time.Time
+}
+
+func NewMockRedisServer() (*MockRedisServer, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &MockRedisServer{listener: listener, data: make(map[string]redisEntry), versions: make(map[string]uint64)}
+	go s.serve()
+	return s, nil
+}
+
+func (s *MockRedisServer) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Число принятых соединений
+func (s *MockRedisServer) Accepted() int64 {
+	return s.accepted.Load()
+}
+
+func (s *MockRedisServer) Close() error {
+	return s.listener.Close()
+}
+
+func (s *MockRedisServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.accepted.Add(1)
+		go s.handle(conn)
+	}
+}
+
+func (s *MockRedisServer) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+	var state redisConnState
+	for {
+		req, err := redis.ReadReply(r)
+		if err != nil {
+			return
+		}
+		items, _ := req.([]interface{})
+		args := make([]string, len(items))
+		for i, item := range items {
+			args[i], _ = item.(string)
+		}
+		if len(args) == 0 {
+			fmt.Fprint(w, "-ERR empty command\r\n")
+		} else {
+			s.exec(w, &state, args)
+		}
+		if r.Buffered() == 0 {
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *MockRedisServer) exec(w *bufio.Writer, state *redisConnState, args []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	cmd := strings.ToUpper(args[0])
+	if state.multi && cmd != "EXEC" && cmd != "DISCARD" {
+		state.queue = append(state.queue, args)
+		fmt.Fprint(w, "+QUEUED\r\n")
+		return
+	}
+	switch cmd {
+	case "WATCH":
+		if state.watched == nil {
+			state.watched = make(map[string]uint64)
+		}
+		for _, key := range args[1:] {
+			s.get(key, now)
+			state.watched[key] = s.versions[key]
+		}
+		fmt.Fprint(w, "+OK\r\n")
+	case "UNWATCH":
+		state.watched = nil
+		fmt.Fprint(w, "+OK\r\n")
+	case "MULTI":
+		state.multi = true
+		fmt.Fprint(w, "+OK\r\n")
+	case "DISCARD":
+		*state = redisConnState{}
+		fmt.Fprint(w, "+OK\r\n")
+	case "EXEC":
+		if !state.multi {
+			fmt.Fprint(w, "-ERR EXEC without MULTI\r\n")
+			return
+		}
+		queue, watched := state.queue, state.watched
+		*state = redisConnState{}
+		for key, version := range watched {
+			s.get(key, now)
+			if s.versions[key] != version {
+				fmt.Fprint(w, "*-1\r\n")
+				return
+			}
+		}
+		fmt.Fprintf(w, "*%d\r\n", len(queue))
+		for _, args := range queue {
+			s.run(w, args, now)
+		}
+	default:
+		s.run(w, args, now)
+	}
+}
+
+// Выполняет команду вне транзакции или из очереди EXEC. Вызывается под блокировкой
+func (s *MockRedisServer) run(w *bufio.Writer, args []string, now time.Time) {
+	switch strings.ToUpper(args[0]) {
+	case "PING", "AUTH", "SELECT":
+		fmt.Fprint(w, "+OK\r\n")
+	case "GET":
+		entry, ok := s.get(args[1], now)
+		switch {
+		case !ok:
+			fmt.Fprint(w, "$-1\r\n")
+		case entry.fields != nil:
+			fmt.Fprint(w, "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n")
+		default:
+			fmt.Fprintf(w, "$%d\r\n%s\r\n", len(entry.value), entry.value)
+		}
+	case "HGET":
+		entry, _ := s.get(args[1], now)
+		value, ok := entry.fields[args[2]]
+		if !ok {
+			fmt.Fprint(w, "$-1\r\n")
+			return
+		}
+		fmt.Fprintf(w, "$%d\r\n%s\r\n", len(value), value)
+	case "HEXISTS":
+		entry, _ := s.get(args[1], now)
+		if _, ok := entry.fields[args[2]]; ok {
+			fmt.Fprint(w, ":1\r\n")
+		} else {
+			fmt.Fprint(w, ":0\r\n")
+		}
+	case "HSET":
+		if len(args) < 4 || len(args)%2 != 0 {
+			fmt.Fprint(w, "-ERR wrong number of arguments for 'hset' command\r\n")
+			return
+		}
+		entry := s.hash(args[1], now)
+		var n int
+		for i := 2; i < len(args); i += 2 {
+			if _, ok := entry.fields[args[i]]; !ok {
+				n++
+			}
+			entry.fields[args[i]] = args[i+1]
+		}
+		s.put(args[1], entry)
+		fmt.Fprintf(w, ":%d\r\n", n)
+	case "HINCRBY":
+		entry := s.hash(args[1], now)
+		n, err := strconv.ParseInt(entry.fields[args[2]], 10, 64)
+		by, byErr := strconv.ParseInt(args[3], 10, 64)
+		if (entry.fields[args[2]] != "" && err != nil) || byErr != nil {
+			fmt.Fprint(w, "-ERR value is not an integer or out of range\r\n")
+			return
+		}
+		entry.fields[args[2]] = strconv.FormatInt(n+by, 10)
+		s.put(args[1], entry)
+		fmt.Fprintf(w, ":%d\r\n", n+by)
+	case "HGETALL":
+		entry, _ := s.get(args[1], now)
+		fmt.Fprintf(w, "*%d\r\n", 2*len(entry.fields))
+		for field, value := range entry.fields {
+			fmt.Fprintf(w, "$%d\r\n%s\r\n$%d\r\n%s\r\n", len(field), field, len(value), value)
+		}
+	case "SET":
+		s.set(w, args, now)
+	case "DEL":
+		var n int
+		for _, key := range args[1:] {
+			if _, ok := s.get(key, now); ok {
+				s.delete(key)
+				n++
+			}
+		}
+		fmt.Fprintf(w, ":%d\r\n", n)
+	case "INCR":
+		entry, _ := s.get(args[1], now)
+		n, err := strconv.ParseInt(entry.value, 10, 64)
+		if entry.value != "" && err != nil {
+			fmt.Fprint(w, "-ERR value is not an integer or out of range\r\n")
+			return
+		}
+		entry.value = strconv.FormatInt(n+1, 10)
+		s.put(args[1], entry)
+		fmt.Fprintf(w, ":%d\r\n", n+1)
+	case "PTTL":
+		entry, ok := s.get(args[1], now)
+		switch {
+		case !ok:
+			fmt.Fprint(w, ":-2\r\n")
+		case entry.expiresAt.IsZero():
+			fmt.Fprint(w, ":-1\r\n")
+		default:
+			fmt.Fprintf(w, ":%d\r\n", entry.expiresAt.Sub(now).Milliseconds())
+		}
+	case "PEXPIRETIME":
+		entry, ok := s.get(args[1], now)
+		switch {
+		case !ok:
+			fmt.Fprint(w, ":-2\r\n")
+		case entry.expiresAt.IsZero():
+			fmt.Fprint(w, ":-1\r\n")
+		default:
+			fmt.Fprintf(w, ":%d\r\n", entry.expiresAt.UnixMilli())
+		}
+	case "PEXPIREAT", "PERSIST":
+		persist := strings.ToUpper(args[0]) == "PERSIST"
+		entry, ok := s.get(args[1], now)
+		if !ok || (persist && entry.expiresAt.IsZero()) {
+			fmt.Fprint(w, ":0\r\n")
+			return
+		}
+		entry.expiresAt = time.Time{}
+		if !persist {
+			if len(args) < 3 {
+				fmt.Fprint(w, "-ERR wrong number of arguments for 'pexpireat' command\r\n")
+				return
+			}
+			n, err := strconv.ParseInt(args[2], 10, 64)
+			if err != nil {
+				fmt.Fprint(w, "-ERR value is not an integer or out of range\r\n")
+				return
+			}
+			entry.expiresAt = time.UnixMilli(n)
+		}
+		s.put(args[1], entry)
+		fmt.Fprint(w, ":1\r\n")
+	default:
+		fmt.Fprintf(w, "-ERR unknown command '%s'\r\n", args[0])
+	}
+}
+
+func (s *MockRedisServer) set(w *bufio.Writer, args []string, now time.Time) {
+	if len(args) < 3 {
+		fmt.Fprint(w, "-ERR wrong number of arguments for 'set' command\r\n")
+		return
+	}
+	entry := redisEntry{value: args[2]}
+	var nx, xx bool
+	for i := 3; i < len(args); i++ {
+		switch opt := strings.ToUpper(args[i]); opt {
+		case "NX":
+			nx = true
+		case "XX":
+			xx = true
+		case "EX", "PX", "PXAT":
+			if i+1 >= len(args) {
+				fmt.Fprint(w, "-ERR syntax error\r\n")
+				return
+			}
+			n, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil {
+				fmt.Fprint(w, "-ERR value is not an integer or out of range\r\n")
+				return
+			}
+			i++
+			switch opt {
+			case "EX":
+				entry.expiresAt = now.Add(time.Duration(n) * time.Second)
+			case "PX":
+				entry.expiresAt = now.Add(time.Duration(n) * time.Millisecond)
+			default:
+				entry.expiresAt = time.UnixMilli(n)
+			}
+		default:
+			fmt.Fprint(w, "-ERR syntax error\r\n")
+			return
+		}
+	}
+
+	_, exists := s.get(args[1], now)
+	if (nx && exists) || (xx && !exists) {
+		fmt.Fprint(w, "$-1\r\n")
+		return
+	}
+	s.put(args[1], entry)
+	fmt.Fprint(w, "+OK\r\n")
+}
+
+func (s *MockRedisServer) get(key string, now time.Time) (redisEntry, bool) {
+	entry, ok := s.data[key]
+	if ok && !entry.expiresAt.IsZero() && !entry.expiresAt.After(now) {
+		s.delete(key)
+		return redisEntry{}, false
+	}
+	return entry, ok
+}
+
+// Ключ-хэш для изменения, отсутствующий ключ создаётся пустым
+func (s *MockRedisServer) hash(key string, now time.Time) redisEntry {
+	entry, ok := s.get(key, now)
+	if !ok || entry.fields == nil {
+		entry = redisEntry{fields: make(map[string]string), expiresAt: entry.expiresAt}
+	}
+	return entry
+}
+
+func (s *MockRedisServer) put(key string, entry redisEntry) {
+	s.data[key] = entry
+	s.versions[key]++
+}
+
+func (s *MockRedisServer) delete(key string) {
+	delete(s.data, key)
+	s.versions[key]++
+}
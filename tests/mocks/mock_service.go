@@ -1,8 +1,12 @@
 package mocks
 
 import (
+	"context"
+	"time"
 
 	// "url-shortener/pkg/storage"
+	"url-shortener/internal/model"
+	"url-shortener/internal/service"
 
 	"github.com/stretchr/testify/mock"
 )
@@ -11,12 +15,37 @@ type MockShortenerService struct {
 	mock.Mock
 }
 
-func (m *MockShortenerService) Shortening(longUrl string) (string, error) {
-	args := m.Called(longUrl)
+func (m *MockShortenerService) Shortening(ctx context.Context, link model.Link) (string, error) {
+	args := m.Called(ctx, link)
 	return args.String(0), args.Error(1)
 }
 
-func (m *MockShortenerService) Expansion(shortUrl string) (string, error) {
-	args := m.Called(shortUrl)
+func (m *MockShortenerService) CustomShortening(ctx context.Context, link model.Link) (string, error) {
+	args := m.Called(ctx, link)
 	return args.String(0), args.Error(1)
 }
+
+func (m *MockShortenerService) BatchShortening(ctx context.Context, links []model.Link, workers int) []service.BatchResult {
+	args := m.Called(ctx, links, workers)
+	return args.Get(0).([]service.BatchResult)
+}
+
+func (m *MockShortenerService) Expansion(ctx context.Context, shortUrl string) (string, error) {
+	args := m.Called(ctx, shortUrl)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockShortenerService) GetLink(ctx context.Context, shortUrl, owner string) (model.Link, error) {
+	args := m.Called(ctx, shortUrl, owner)
+	return args.Get(0).(model.Link), args.Error(1)
+}
+
+func (m *MockShortenerService) UpdateLink(ctx context.Context, shortUrl, owner, longUrl string, expiresAt *time.Time) (model.Link, error) {
+	args := m.Called(ctx, shortUrl, owner, longUrl, expiresAt)
+	return args.Get(0).(model.Link), args.Error(1)
+}
+
+func (m *MockShortenerService) DeleteLink(ctx context.Context, shortUrl, owner string) error {
+	args := m.Called(ctx, shortUrl, owner)
+	return args.Error(0)
+}
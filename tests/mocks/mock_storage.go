@@ -4,7 +4,11 @@
 package mocks
 
 import (
+    "context"
     // "url-shortener/pkg/storage"
+    "time"
+
+    "url-shortener/internal/model"
 
     "github.com/stretchr/testify/mock"
 )
@@ -15,19 +19,103 @@ type MockStorage struct {
 }
 
 // GetLongUrl provides a mock function with given fields: ctx, shortUrl
-func (_m *MockStorage) GetLongUrl(shortUrl string) (string, error) {
-    ret := _m.Called(shortUrl)
+func (_m *MockStorage) GetLongUrl(ctx context.Context, shortUrl string) (string, error) {
+    ret := _m.Called(ctx, shortUrl)
 
     var r0 string
-    if rf, ok := ret.Get(0).(func( string) string); ok {
-        r0 = rf( shortUrl)
+    if rf, ok := ret.Get(0).(func(context.Context, string) string); ok {
+        r0 = rf(ctx, shortUrl)
     } else {
         r0 = ret.Get(0).(string)
     }
 
     var r1 error
-    if rf, ok := ret.Get(1).(func( string) error); ok {
-        r1 = rf( shortUrl)
+    if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+        r1 = rf(ctx, shortUrl)
+    } else {
+        r1 = ret.Error(1)
+    }
+
+    return r0, r1
+}
+
+// Insert provides a mock function with given fields: ctx, link
+func (_m *MockStorage) Insert(ctx context.Context, link model.Link) error {
+    ret := _m.Called(ctx, link)
+
+    var r0 error
+    if rf, ok := ret.Get(0).(func(context.Context, model.Link) error); ok {
+        r0 = rf(ctx, link)
+    } else {
+        r0 = ret.Error(0)
+    }
+
+    return r0
+}
+
+// Allocate provides a mock function with given fields: ctx, link
+func (_m *MockStorage) Allocate(ctx context.Context, link model.Link) (string, bool, error) {
+    ret := _m.Called(ctx, link)
+
+    var r0 string
+    if rf, ok := ret.Get(0).(func(context.Context, model.Link) string); ok {
+        r0 = rf(ctx, link)
+    } else {
+        r0 = ret.Get(0).(string)
+    }
+
+    var r1 bool
+    if rf, ok := ret.Get(1).(func(context.Context, model.Link) bool); ok {
+        r1 = rf(ctx, link)
+    } else {
+        r1 = ret.Get(1).(bool)
+    }
+
+    var r2 error
+    if rf, ok := ret.Get(2).(func(context.Context, model.Link) error); ok {
+        r2 = rf(ctx, link)
+    } else {
+        r2 = ret.Error(2)
+    }
+
+    return r0, r1, r2
+}
+
+// AllocateBatch provides a mock function with given fields: ctx, links
+func (_m *MockStorage) AllocateBatch(ctx context.Context, links []model.Link) ([]model.Allocation, error) {
+    ret := _m.Called(ctx, links)
+
+    var r0 []model.Allocation
+    if rf, ok := ret.Get(0).(func(context.Context, []model.Link) []model.Allocation); ok {
+        r0 = rf(ctx, links)
+    } else if ret.Get(0) != nil {
+        r0 = ret.Get(0).([]model.Allocation)
+    }
+
+    var r1 error
+    if rf, ok := ret.Get(1).(func(context.Context, []model.Link) error); ok {
+        r1 = rf(ctx, links)
+    } else {
+        r1 = ret.Error(1)
+    }
+
+    return r0, r1
+}
+
+// InsertBatch provides a mock function with given fields: ctx, links
+func (_m *MockStorage) InsertBatch(ctx context.Context, links []model.Link) ([]error, error) {
+    ret := _m.Called(ctx, links)
+
+    var r0 []error
+    if rf, ok := ret.Get(0).(func(context.Context, []model.Link) []error); ok {
+        r0 = rf(ctx, links)
+    } else if ret.Get(0) != nil {
+        r0 = ret.Get(0).([]error)
+    }
+
+    var r1 error
+    if rf, ok := ret.Get(1).(func(context.Context, []model.Link) error); ok {
+        r1 = rf(ctx, links)
     } else {
         r1 = ret.Error(1)
     }
@@ -35,16 +123,79 @@ func (_m *MockStorage) GetLongUrl(shortUrl string) (string, error) {
     return r0, r1
 }
 
-// Insert provides a mock function with given fields: ctx, shortURL, longURL
-func (_m *MockStorage) Insert(shortURL, longURL string) error {
-    ret := _m.Called(shortURL, longURL)
+// GetLink provides a mock function with given fields: ctx, shortUrl
+func (_m *MockStorage) GetLink(ctx context.Context, shortUrl string) (model.Link, error) {
+    ret := _m.Called(ctx, shortUrl)
+
+    var r0 model.Link
+    if rf, ok := ret.Get(0).(func(context.Context, string) model.Link); ok {
+        r0 = rf(ctx, shortUrl)
+    } else {
+        r0 = ret.Get(0).(model.Link)
+    }
+
+    var r1 error
+    if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+        r1 = rf(ctx, shortUrl)
+    } else {
+        r1 = ret.Error(1)
+    }
+
+    return r0, r1
+}
+
+// Update provides a mock function with given fields: ctx, link
+func (_m *MockStorage) Update(ctx context.Context, link model.Link) (model.Link, error) {
+    ret := _m.Called(ctx, link)
+
+    var r0 model.Link
+    if rf, ok := ret.Get(0).(func(context.Context, model.Link) model.Link); ok {
+        r0 = rf(ctx, link)
+    } else {
+        r0 = ret.Get(0).(model.Link)
+    }
+
+    var r1 error
+    if rf, ok := ret.Get(1).(func(context.Context, model.Link) error); ok {
+        r1 = rf(ctx, link)
+    } else {
+        r1 = ret.Error(1)
+    }
+
+    return r0, r1
+}
+
+// Delete provides a mock function with given fields: ctx, shortUrl, owner
+func (_m *MockStorage) Delete(ctx context.Context, shortUrl string, owner string) error {
+    ret := _m.Called(ctx, shortUrl, owner)
 
     var r0 error
-    if rf, ok := ret.Get(0).(func( string, string) error); ok {
-        r0 = rf(shortURL, longURL)
+    if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+        r0 = rf(ctx, shortUrl, owner)
     } else {
         r0 = ret.Error(0)
     }
 
     return r0
 }
+
+// DeleteExpired provides a mock function with given fields: ctx, now
+func (_m *MockStorage) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+    ret := _m.Called(ctx, now)
+
+    var r0 int64
+    if rf, ok := ret.Get(0).(func(context.Context, time.Time) int64); ok {
+        r0 = rf(ctx, now)
+    } else {
+        r0 = ret.Get(0).(int64)
+    }
+
+    var r1 error
+    if rf, ok := ret.Get(1).(func(context.Context, time.Time) error); ok {
+        r1 = rf(ctx, now)
+    } else {
+        r1 = ret.Error(1)
+    }
+
+    return r0, r1
+}
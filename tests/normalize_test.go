@@ -0,0 +1,58 @@
+package tests
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"url-shortener/internal/service"
+)
+
+func TestNormalize(t *testing.T) {
+	normalizer := service.URLNormalizer{}
+
+	cases := map[string]string{
+		"HTTP://Example.com":            "http://example.com/",
+		"http://example.com/":           "http://example.com/",
+		"https://example.com:443/a?b=1": "https://example.com/a?b=1",
+		"http://example.com:8080/a":     "http://example.com:8080/a",
+		"https://Пример.РФ/путь#раздел": "https://xn--e1afmkfd.xn--p1ai/%D0%BF%D1%83%D1%82%D1%8C#%D1%80%D0%B0%D0%B7%D0%B4%D0%B5%D0%BB",
+		"  https://example.com/a b  ":   "https://example.com/a%20b",
+		"http://[2001:DB8::1]:80/index": "http://[2001:db8::1]/index",
+	}
+	for input, expected := range cases {
+		result, err := normalizer.Normalize(input)
+		assert.NoError(t, err, input)
+		assert.Equal(t, expected, result, input)
+	}
+}
+
+func TestNormalizeOptions(t *testing.T) {
+	normalizer := service.URLNormalizer{SortQuery: true, StripFragment: true}
+
+	result, err := normalizer.Normalize("https://example.com/search?q=go&a=1#results")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/search?a=1&q=go", result)
+}
+
+func TestNormalizeRejects(t *testing.T) {
+	normalizer := service.URLNormalizer{}
+
+	cases := map[string]string{
+		"javascript:alert(1)":    service.ReasonSchemeNotAllowed,
+		"ftp://example.com/file": service.ReasonSchemeNotAllowed,
+		"example":                service.ReasonMissingScheme,
+		"http:///path":           service.ReasonMissingHost,
+		"http://exa mple.com/":   service.ReasonMalformed,
+		"https://example.com/" + strings.Repeat("a", service.MaxURLLength): service.ReasonTooLong,
+	}
+	for input, reason := range cases {
+		_, err := normalizer.Normalize(input)
+		var validationErr *service.URLValidationError
+		if assert.True(t, errors.As(err, &validationErr), input) {
+			assert.Equal(t, reason, validationErr.Reason, input)
+		}
+		assert.ErrorIs(t, err, service.ErrInvalidURL)
+	}
+}
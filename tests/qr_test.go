@@ -0,0 +1,491 @@
+package tests
+
+import (
+	"bytes"
+	"fmt"
+	"image/png"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"url-shortener/internal/controller"
+	"url-shortener/pkg/qr"
+	"url-shortener/pkg/storage"
+
+	"url-shortener/tests/mocks"
+)
+
+func TestQR_Capacity(t *testing.T) {
+	// Ёмкость байтового режима по таблицам стандарта
+	cases := []struct {
+		level    qr.Level
+		version  int
+		capacity int
+	}{
+		{qr.L, 1, 17}, {qr.M, 1, 14}, {qr.Q, 1, 11}, {qr.H, 1, 7},
+		{qr.M, 10, 213}, {qr.Q, 5, 60}, {qr.L, 40, 2953}, {qr.H, 40, 1273},
+	}
+	for _, tc := range cases {
+		code, err := qr.Encode(bytes.Repeat([]byte("a"), tc.capacity), tc.level)
+		require.NoError(t, err)
+		assert.Equal(t, tc.version, code.Version, "level %s, %d bytes", tc.level, tc.capacity)
+		assert.Equal(t, 17+4*tc.version, code.Size)
+
+		code, err = qr.Encode(bytes.Repeat([]byte("a"), tc.capacity+1), tc.level)
+		if tc.version == 40 {
+			assert.ErrorIs(t, err, qr.ErrTooLong)
+			continue
+		}
+		require.NoError(t, err)
+		assert.Equal(t, tc.version+1, code.Version)
+	}
+}
+
+func TestQR_Decode(t *testing.T) {
+	cases := []struct {
+		level   qr.Level
+		length  int
+		version int
+	}{
+		{qr.M, 10, 1},
+		{qr.Q, 55, 5},
+		{qr.L, 150, 7},
+		{qr.H, 110, 10},
+		{qr.L, 2900, 40},
+	}
+	for _, tc := range cases {
+		data := make([]byte, tc.length)
+		for i := range data {
+			data[i] = byte(i*31 + 7)
+		}
+		copy(data, "https://sho.rt/")
+		code, err := qr.Encode(data, tc.level)
+		require.NoError(t, err)
+		require.Equal(t, tc.version, code.Version)
+		assert.Equal(t, data, decodeQR(t, code), "version %d-%s", tc.version, tc.level)
+	}
+}
+
+// Эталонные матрицы без светлого поля от независимых кодировщиков github.com/boombuler/barcode/qr
+// и github.com/skip2/go-qrcode, совпадающих между собой. Строка матрицы - шестнадцатеричное число,
+// старший бит - левый модуль. Выбор маски по штрафам у кодировщиков может расходиться,
+// поэтому взяты данные, для которых все выбирают одну маску
+func TestQR_Reference(t *testing.T) {
+	cases := []struct {
+		content string
+		level   qr.Level
+		rows    []string
+	}{
+		{"https://sho.rt/promo", qr.M, []string{
+			"1fd447f",
+			"1052041",
+			"175ad5d",
+			"174785d",
+			"175cd5d",
+			"1047541",
+			"1fd557f",
+			"000a800",
+			"13f4697",
+			"0fa2e3e",
+			"04cfba9",
+			"1e0b6cf",
+			"1766d41",
+			"10a5612",
+			"1e6c79f",
+			"132dcad",
+			"14ef3f6",
+			"0012316",
+			"1fd1d51",
+			"1052b11",
+			"175ebf3",
+			"1755843",
+			"1746a9f",
+			"104ba37",
+			"1fd3989",
+		}},
+		{"https://sho.rt/x", qr.H, []string{
+			"1fcd1e7f",
+			"1050d941",
+			"174def5d",
+			"1746625d",
+			"1748545d",
+			"10551741",
+			"1fd5557f",
+			"00173c00",
+			"01e19462",
+			"1babf397",
+			"06e5c981",
+			"0316dca3",
+			"13795329",
+			"16918c57",
+			"0bead511",
+			"0c8122aa",
+			"18537fd0",
+			"1d8ec621",
+			"0259aa3d",
+			"071ee31b",
+			"1d6e41f0",
+			"0017f117",
+			"1fd05751",
+			"10528b1a",
+			"1756d3f3",
+			"1743cc2a",
+			"17428f83",
+			"104ceee3",
+			"1fc6e79e",
+		}},
+		{"https://sho.rt/" + strings.Repeat("abcdefghij", 14), qr.L, []string{
+			"1fcc420ad197f",
+			"105b16c72f741",
+			"1740ac731c35d",
+			"175824d42ba5d",
+			"17419f7ce285d",
+			"1051b5461f441",
+			"1fd555555557f",
+			"000113c7d0000",
+			"1f72bdfca3faa",
+			"162042b9e28c8",
+			"1dfef9142d72b",
+			"1f3b61075ca73",
+			"00f2caf8e3f27",
+			"012924bbc08d2",
+			"1a6c86071f4bf",
+			"0d25a893108d1",
+			"0e65bd2c6f3ef",
+			"050e428bc08f8",
+			"125506e42d407",
+			"082aad075c6d9",
+			"02f224f42f347",
+			"149b9f50f3872",
+			"1dfbbd7f2f7f7",
+			"131e57c710b11",
+			"0152bd54e795f",
+			"091d4244f3b1c",
+			"0bf6f8fc0d7ff",
+			"071171835853a",
+			"0de5ca38a3add",
+			"0b2f24d2d182e",
+			"1e5f8e7b2f4db",
+			"1e08ec971c901",
+			"17d0bd8c2b79c",
+			"089842f6d08a4",
+			"1c6407ac0c6c7",
+			"07adbef3d8518",
+			"1acd24d86f7dd",
+			"03369fa9e2aac",
+			"08c87d482d4e3",
+			"0e1a13275c902",
+			"1c40bd7ce3ffc",
+			"00134245e3b14",
+			"1fd0e9d60c557",
+			"104c7247d8313",
+			"1753ca7ce7bf5",
+			"17512483c0b5f",
+			"17574e382d4ec",
+			"1055a8035c6f1",
+			"1fd8bd382f30f",
+		}},
+	}
+	for _, tc := range cases {
+		code, err := qr.Encode([]byte(tc.content), tc.level)
+		require.NoError(t, err)
+		require.Equal(t, len(tc.rows), code.Size, tc.content)
+		for y, row := range tc.rows {
+			want, ok := new(big.Int).SetString(row, 16)
+			require.True(t, ok)
+			for x := 0; x < code.Size; x++ {
+				if code.Black(x, y) != (want.Bit(code.Size-1-x) == 1) {
+					t.Fatalf("version %d-%s: module (%d, %d) differs from reference", code.Version, tc.level, x, y)
+				}
+			}
+		}
+	}
+}
+
+func TestQR_Render(t *testing.T) {
+	code, err := qr.Encode([]byte("https://sho.rt/abc"), qr.M)
+	require.NoError(t, err)
+	fg, err := qr.ParseColor("#336")
+	require.NoError(t, err)
+	bg, err := qr.ParseColor("fafafa")
+	require.NoError(t, err)
+	_, err = qr.ParseColor("#12345")
+	assert.Error(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, code.PNG(&buf, qr.Options{Size: 300, Margin: 4, Foreground: fg, Background: bg}))
+	img, err := png.Decode(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, 300, img.Bounds().Dx())
+	// Код с полем занимает Size+8 модулей, остаток пикселей делится поровну между сторонами
+	scale := 300 / (code.Size + 8)
+	offset := (300 - code.Size*scale) / 2
+	r, g, b, _ := img.At(offset+scale/2, offset+scale/2).RGBA()
+	assert.Equal(t, [3]uint32{0x3333, 0x3333, 0x6666}, [3]uint32{r, g, b}, "finder corner is foreground")
+	r, g, b, _ = img.At(offset-1, offset-1).RGBA()
+	assert.Equal(t, [3]uint32{0xfafa, 0xfafa, 0xfafa}, [3]uint32{r, g, b}, "quiet zone is background")
+
+	buf.Reset()
+	require.NoError(t, code.SVG(&buf, qr.Options{Size: 300, Margin: 2, Foreground: fg, Background: bg}))
+	svg := buf.String()
+	assert.Contains(t, svg, fmt.Sprintf(`width="300" height="300" viewBox="0 0 %d %[1]d"`, code.Size+4))
+	assert.Contains(t, svg, `fill="#333366"`)
+	assert.Contains(t, svg, `fill="#fafafa"`)
+	assert.Contains(t, svg, "M2 2h7v1h-7z", "top row of the top-left finder")
+	assert.True(t, strings.HasSuffix(svg, "</svg>\n"))
+}
+
+func TestQREndpoint(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockService := new(mocks.MockShortenerService)
+	mockService.On("Expansion", mock.Anything, "promo").Return("https://example.com/", nil)
+	mockService.On("Expansion", mock.Anything, "missing").Return("", storage.ErrNotFound)
+	mockService.On("Expansion", mock.Anything, "old").Return("", storage.ErrExpired)
+
+	// Ожидаемое изображение - та же ссылка, закодированная и отрисованная напрямую
+	render := func(content string, level qr.Level, opts qr.Options, svg bool) []byte {
+		code, err := qr.Encode([]byte(content), level)
+		require.NoError(t, err)
+		var buf bytes.Buffer
+		if svg {
+			require.NoError(t, code.SVG(&buf, opts))
+		} else {
+			require.NoError(t, code.PNG(&buf, opts))
+		}
+		return buf.Bytes()
+	}
+	get := func(baseURL, target string) *httptest.ResponseRecorder {
+		router := gin.New()
+		h := handler.NewHandler(mockService, new(mocks.MockAnalyticsService), nil, handler.Options{BaseURL: baseURL})
+		h.Register(router, handler.Middlewares{})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		return w
+	}
+
+	w := get("https://sho.rt/", "/links/promo/qr")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+	assert.Equal(t, "public, max-age=300", w.Header().Get("Cache-Control"))
+	assert.Equal(t, render("https://sho.rt/promo", qr.M, qr.Options{Size: 256, Margin: 4, Foreground: qr.Black, Background: qr.White}, false), w.Body.Bytes())
+
+	w = get("https://sho.rt", "/links/promo/qr?format=svg&size=512&margin=2&level=h&fg=%23336&bg=fafafa")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/svg+xml", w.Header().Get("Content-Type"))
+	fg, _ := qr.ParseColor("336")
+	bg, _ := qr.ParseColor("fafafa")
+	assert.Equal(t, string(render("https://sho.rt/promo", qr.H, qr.Options{Size: 512, Margin: 2, Foreground: fg, Background: bg}, true)), w.Body.String())
+
+	// Без внешнего адреса ссылка собирается из хоста запроса
+	w = get("", "/links/promo/qr?size=64")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "private, max-age=300", w.Header().Get("Cache-Control"))
+	assert.Equal(t, "Host", w.Header().Get("Vary"))
+	assert.Equal(t, render("http://example.com/promo", qr.M, qr.Options{Size: 64, Margin: 4, Foreground: qr.Black, Background: qr.White}, false), w.Body.Bytes())
+
+	assert.Equal(t, http.StatusNotFound, get("", "/links/missing/qr").Code)
+	assert.Equal(t, http.StatusGone, get("", "/links/old/qr").Code)
+	for _, query := range []string{"format=gif", "size=32", "size=big", "margin=17", "level=X", "fg=red", "bg=%2312345"} {
+		assert.Equal(t, http.StatusBadRequest, get("", "/links/promo/qr?"+query).Code, query)
+	}
+}
+
+// Независимый от кодировщика декодер: служебные модули, блоки и коррекция берутся из таблиц стандарта
+
+// Центры выравнивающих узоров (ISO/IEC 18004, приложение E)
+var qrAlignment = map[int][]int{1: nil, 5: {6, 30}, 7: {6, 22, 38}, 10: {6, 28, 50}, 40: {6, 30, 58, 86, 114, 142, 170}}
+
+// Блоки коррекции: количество блоков и байт коррекции в блоке (ISO/IEC 18004, таблица 9)
+var qrBlocks = map[[2]int][2]int{
+	{1, int(qr.M)}: {1, 10}, {5, int(qr.Q)}: {4, 18}, {7, int(qr.L)}: {2, 20}, {10, int(qr.H)}: {8, 28}, {40, int(qr.L)}: {25, 30},
+}
+
+func qrIsFunction(version, x, y int) bool {
+	size := 17 + 4*version
+	switch {
+	case x < 9 && y < 9, x >= size-8 && y < 9, x < 9 && y >= size-8: // Поисковые узоры, разделители, формат
+		return true
+	case x == 6 || y == 6: // Синхронизация
+		return true
+	case version >= 7 && (x >= size-11 && x < size-8 && y < 6 || y >= size-11 && y < size-8 && x < 6):
+		return true
+	}
+	positions := qrAlignment[version]
+	for i, ax := range positions {
+		for j, ay := range positions {
+			if i == 0 && j == 0 || i == 0 && j == len(positions)-1 || i == len(positions)-1 && j == 0 {
+				continue
+			}
+			if x >= ax-2 && x <= ax+2 && y >= ay-2 && y <= ay+2 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func decodeQR(t *testing.T, code *qr.Code) []byte {
+	size, version := code.Size, code.Version
+
+	// Информация о формате в обеих копиях
+	readFormat := func(coords [15][2]int) int {
+		bits := 0
+		for i, c := range coords {
+			if code.Black(c[0], c[1]) {
+				bits |= 1 << i
+			}
+		}
+		return bits ^ 0x5412
+	}
+	var first, second [15][2]int
+	for i := 0; i < 15; i++ {
+		switch {
+		case i < 6:
+			first[i] = [2]int{8, i}
+		case i < 8:
+			first[i] = [2]int{8, i + 1}
+		case i == 8:
+			first[i] = [2]int{7, 8}
+		default:
+			first[i] = [2]int{14 - i, 8}
+		}
+		if i < 8 {
+			second[i] = [2]int{size - 1 - i, 8}
+		} else {
+			second[i] = [2]int{8, size - 15 + i}
+		}
+	}
+	format := readFormat(first)
+	require.Equal(t, format, readFormat(second), "format copies differ")
+	require.True(t, code.Black(8, size-8), "dark module")
+	// Остаток от деления на порождающий многочлен БЧХ x^10+x^8+x^5+x^4+x^2+x+1 должен быть нулевым
+	rem := format
+	for i := 14; i >= 10; i-- {
+		if rem>>i&1 == 1 {
+			rem ^= 0x537 << (i - 10)
+		}
+	}
+	require.Zero(t, rem, "format BCH")
+	levelBits, mask := format>>13, format>>10&7
+	require.Equal(t, map[qr.Level]int{qr.L: 1, qr.M: 0, qr.Q: 3, qr.H: 2}[code.Level], levelBits)
+
+	masks := []func(x, y int) bool{
+		func(x, y int) bool { return (x+y)%2 == 0 },
+		func(x, y int) bool { return y%2 == 0 },
+		func(x, y int) bool { return x%3 == 0 },
+		func(x, y int) bool { return (x+y)%3 == 0 },
+		func(x, y int) bool { return (x/3+y/2)%2 == 0 },
+		func(x, y int) bool { return x*y%2+x*y%3 == 0 },
+		func(x, y int) bool { return (x*y%2+x*y%3)%2 == 0 },
+		func(x, y int) bool { return ((x+y)%2+x*y%3)%2 == 0 },
+	}
+
+	// Чтение зигзагом: пары столбцов справа налево, направление меняется на каждой паре
+	var bits []bool
+	upward := true
+	for right := size - 1; right > 0; right -= 2 {
+		if right == 6 {
+			right--
+		}
+		for i := 0; i < size; i++ {
+			y := i
+			if upward {
+				y = size - 1 - i
+			}
+			for _, x := range []int{right, right - 1} {
+				if !qrIsFunction(version, x, y) {
+					bits = append(bits, code.Black(x, y) != masks[mask](x, y))
+				}
+			}
+		}
+		upward = !upward
+	}
+	codewords := make([]byte, len(bits)/8)
+	for i := range codewords {
+		for j := 0; j < 8; j++ {
+			if bits[i*8+j] {
+				codewords[i] |= 1 << (7 - j)
+			}
+		}
+	}
+
+	// Разбор чередования блоков и проверка синдромов Рида-Соломона
+	blocks := qrBlocks[[2]int{version, int(code.Level)}]
+	require.NotZero(t, blocks[0], "no block table for version %d-%s", version, code.Level)
+	numBlocks, eccLen := blocks[0], blocks[1]
+	dataTotal := len(codewords) - numBlocks*eccLen
+	shortLen := dataTotal / numBlocks
+	numLong := dataTotal % numBlocks
+	data := make([][]byte, numBlocks)
+	ecc := make([][]byte, numBlocks)
+	k := 0
+	for i := 0; i <= shortLen; i++ {
+		for b := 0; b < numBlocks; b++ {
+			if i < shortLen || b >= numBlocks-numLong {
+				data[b] = append(data[b], codewords[k])
+				k++
+			}
+		}
+	}
+	for i := 0; i < eccLen; i++ {
+		for b := 0; b < numBlocks; b++ {
+			ecc[b] = append(ecc[b], codewords[k])
+			k++
+		}
+	}
+	exp, log := gfTables()
+	var payload []byte
+	for b := range data {
+		block := append(append([]byte{}, data[b]...), ecc[b]...)
+		for j := 0; j < eccLen; j++ {
+			var s byte
+			for _, c := range block {
+				if s != 0 {
+					s = exp[(int(log[s])+j)%255]
+				}
+				s ^= c
+			}
+			require.Zero(t, s, "syndrome %d of block %d", j, b)
+		}
+		payload = append(payload, data[b]...)
+	}
+
+	// Сегмент байтового режима
+	readBits := func(pos, n int) int {
+		v := 0
+		for i := 0; i < n; i++ {
+			v = v<<1 | int(payload[(pos+i)/8]>>(7-(pos+i)%8)&1)
+		}
+		return v
+	}
+	require.Equal(t, 0x4, readBits(0, 4), "byte mode")
+	countBits := 8
+	if version > 9 {
+		countBits = 16
+	}
+	n := readBits(4, countBits)
+	result := make([]byte, n)
+	for i := range result {
+		result[i] = byte(readBits(4+countBits+8*i, 8))
+	}
+	return result
+}
+
+func gfTables() (exp [256]byte, log [256]byte) {
+	x := 1
+	for i := 0; i < 255; i++ {
+		exp[i] = byte(x)
+		log[x] = byte(i)
+		x <<= 1
+		if x&0x100 != 0 {
+			x ^= 0x11D
+		}
+	}
+	return exp, log
+}
@@ -0,0 +1,113 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"url-shortener/internal/controller"
+	"url-shortener/internal/repository"
+	"url-shortener/internal/service"
+	"url-shortener/tests/mocks"
+)
+
+func TestApplyGCRA(t *testing.T) {
+	policy := service.RateLimitPolicy{Rate: 1, Burst: 3}
+	now := time.Date(2025, 4, 5, 12, 0, 0, 0, time.UTC)
+
+	var tat time.Time
+	var res service.RateLimitResult
+	for i := 0; i < 3; i++ {
+		tat, res = service.ApplyGCRA(tat, now, policy, 1)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, 2-i, res.Remaining)
+	}
+
+	tat, res = service.ApplyGCRA(tat, now, policy, 1)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, time.Second, res.RetryAfter)
+
+	// Через секунду восстанавливается один запрос
+	_, res = service.ApplyGCRA(tat, now.Add(time.Second), policy, 1)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	limiter := handler.NewRateLimiter(repository.NewCacheRateLimitStore(), nil, handler.RateLimiterOptions{})
+	router.GET("/limited", limiter.Limit("test", service.RateLimitPolicy{Rate: 0.5, Burst: 1}), func(ctx *gin.Context) {
+		ctx.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/limited", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/limited", nil))
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "2", w.Header().Get("Retry-After"))
+}
+
+// Без доверенных прокси подмена X-Forwarded-For не даёт новой квоты
+func TestRateLimitMiddleware_IgnoresForwardedForWithoutTrustedProxies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	assert.NoError(t, router.SetTrustedProxies(nil))
+	limiter := handler.NewRateLimiter(repository.NewCacheRateLimitStore(), nil, handler.RateLimiterOptions{})
+	router.GET("/limited", limiter.Limit("test", service.RateLimitPolicy{Rate: 0.5, Burst: 1}), func(ctx *gin.Context) {
+		ctx.Status(http.StatusOK)
+	})
+
+	for i, forwarded := range []string{"203.0.113.1", "203.0.113.2"} {
+		req := httptest.NewRequest(http.MethodGet, "/limited", nil)
+		req.Header.Set("X-Forwarded-For", forwarded)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if i == 0 {
+			assert.Equal(t, http.StatusOK, w.Code)
+		} else {
+			assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		}
+	}
+}
+
+type failingRateLimitStore struct {
+	deadline bool
+}
+
+func (s *failingRateLimitStore) Take(ctx context.Context, _ string, _ service.RateLimitPolicy, _ int, _ time.Time) (service.RateLimitResult, error) {
+	_, s.deadline = ctx.Deadline()
+	return service.RateLimitResult{}, errors.New("store unavailable")
+}
+
+func TestRateLimitMiddleware_StoreError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for _, failOpen := range []bool{true, false} {
+		store := &failingRateLimitStore{}
+		limiter := handler.NewRateLimiter(store, &mocks.MockLogger{}, handler.RateLimiterOptions{Timeout: time.Second, FailOpen: failOpen})
+		router := gin.New()
+		router.GET("/limited", limiter.Limit("test", service.RateLimitPolicy{Rate: 1, Burst: 1}), func(ctx *gin.Context) {
+			ctx.Status(http.StatusOK)
+		})
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/limited", nil))
+		assert.True(t, store.deadline, "store gets a context with the limiter timeout")
+		if failOpen {
+			assert.Equal(t, http.StatusOK, w.Code)
+		} else {
+			assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		}
+	}
+}
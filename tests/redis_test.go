@@ -0,0 +1,195 @@
+package tests
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"url-shortener/config"
+	"url-shortener/internal/model"
+	"url-shortener/internal/repository"
+	"url-shortener/pkg/storage"
+	"url-shortener/pkg/storage/redis"
+
+	"url-shortener/tests/mocks"
+)
+
+func newRedisStorage(t *testing.T) (*repository.RedisStorage, *redis.Client) {
+	server, err := mocks.NewMockRedisServer()
+	require.NoError(t, err)
+	t.Cleanup(func() { server.Close() })
+
+	client, err := redis.NewClient(context.Background(), config.Redis{Addr: server.Addr(), DB: 1, PoolSize: 2})
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+	return repository.NewRedisStorage(client), client
+}
+
+func TestRedis_InsertAndGet(t *testing.T) {
+	ctx := context.Background()
+	redisStorage, _ := newRedisStorage(t)
+
+	assert.NoError(t, redisStorage.Insert(ctx, model.Link{ShortURL: "abc", LongURL: "https://example.com", Owner: "alice"}))
+	assert.Equal(t, storage.ErrAlreadyExists, redisStorage.Insert(ctx, model.Link{ShortURL: "abc", LongURL: "https://example.org"}))
+
+	value, err := redisStorage.GetLongUrl(ctx, "abc")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com", value)
+
+	link, err := redisStorage.GetLink(ctx, "abc")
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", link.Owner)
+	assert.False(t, link.CreatedAt.IsZero())
+
+	_, err = redisStorage.GetLongUrl(ctx, "missing")
+	assert.Equal(t, storage.ErrNotFound, err)
+}
+
+func TestRedis_Expiry(t *testing.T) {
+	ctx := context.Background()
+	redisStorage, client := newRedisStorage(t)
+
+	expiresAt := time.Now().Add(time.Hour)
+	assert.NoError(t, redisStorage.Insert(ctx, model.Link{ShortURL: "ttl", LongURL: "https://example.com", ExpiresAt: expiresAt}))
+	// Сервер удаляет ключи через сутки после срока действия
+	ttl, err := client.Do(ctx, "PTTL", "url:ttl")
+	assert.NoError(t, err)
+	assert.InDelta(t, (25 * time.Hour).Milliseconds(), ttl, float64(time.Minute.Milliseconds()))
+	sum := sha256.Sum256([]byte("https://example.com"))
+	ttl, err = client.Do(ctx, "PTTL", "long:"+hex.EncodeToString(sum[:]))
+	assert.NoError(t, err)
+	assert.InDelta(t, (25 * time.Hour).Milliseconds(), ttl, float64(time.Minute.Milliseconds()))
+
+	// Бессрочная после изменения ссылка хранится без TTL
+	_, err = redisStorage.Update(ctx, model.Link{ShortURL: "ttl", ExpiresAt: model.NoExpiry})
+	assert.NoError(t, err)
+	ttl, err = client.Do(ctx, "PTTL", "url:ttl")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(-1), ttl)
+
+	// Истёкшая ссылка остаётся в хранилище и отвечает ErrExpired, а её код может быть занят заново
+	assert.NoError(t, redisStorage.Insert(ctx, model.Link{ShortURL: "short", LongURL: "https://example.com/short", ExpiresAt: time.Now().Add(20 * time.Millisecond)}))
+	time.Sleep(30 * time.Millisecond)
+	_, err = redisStorage.GetLongUrl(ctx, "short")
+	assert.Equal(t, storage.ErrExpired, err)
+	assert.NoError(t, redisStorage.Insert(ctx, model.Link{ShortURL: "short", LongURL: "https://example.org"}))
+	value, err := redisStorage.GetLongUrl(ctx, "short")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.org", value)
+	// Индекс длинной ссылки перезаписанной записи освобождается
+	assert.NoError(t, redisStorage.Insert(ctx, model.Link{ShortURL: "again", LongURL: "https://example.com/short"}))
+
+	// Allocate перезаписывает истёкшую запись той же длинной ссылки под новым кодом
+	assert.NoError(t, redisStorage.Insert(ctx, model.Link{ShortURL: "old", LongURL: "https://example.net", ExpiresAt: time.Now().Add(20 * time.Millisecond)}))
+	time.Sleep(30 * time.Millisecond)
+	code, exists, err := redisStorage.Allocate(ctx, model.Link{ShortURL: "new", LongURL: "https://example.net"})
+	assert.NoError(t, err)
+	assert.Equal(t, "new", code)
+	assert.False(t, exists)
+	_, err = redisStorage.GetLink(ctx, "old")
+	assert.Equal(t, storage.ErrNotFound, err)
+}
+
+func TestRedis_ClicksExpiry(t *testing.T) {
+	ctx := context.Background()
+	redisStorage, client := newRedisStorage(t)
+	analytics := repository.NewRedisAnalyticsStorage(client)
+
+	// Счётчики истекающей ссылки удаляются сервером вместе с её записью
+	expiresAt := time.Now().Add(time.Hour)
+	require.NoError(t, redisStorage.Insert(ctx, model.Link{ShortURL: "ttl", LongURL: "https://example.com", ExpiresAt: expiresAt}))
+	require.NoError(t, analytics.InsertClick(ctx, model.Click{ShortURL: "ttl", Timestamp: time.Now()}))
+	require.NoError(t, analytics.InsertClick(ctx, model.Click{ShortURL: "ttl", Timestamp: time.Now()}))
+	linkTTL, err := client.Do(ctx, "PTTL", "url:ttl")
+	require.NoError(t, err)
+	ttl, err := client.Do(ctx, "PTTL", "clicks:ttl")
+	assert.NoError(t, err)
+	assert.InDelta(t, linkTTL, ttl, float64(time.Second.Milliseconds()))
+
+	// Изменение срока действия переносится на счётчики
+	_, err = redisStorage.Update(ctx, model.Link{ShortURL: "ttl", ExpiresAt: expiresAt.Add(time.Hour)})
+	require.NoError(t, err)
+	ttl, err = client.Do(ctx, "PTTL", "clicks:ttl")
+	assert.NoError(t, err)
+	assert.InDelta(t, (26 * time.Hour).Milliseconds(), ttl, float64(time.Minute.Milliseconds()))
+	_, err = redisStorage.Update(ctx, model.Link{ShortURL: "ttl", ExpiresAt: model.NoExpiry})
+	require.NoError(t, err)
+	ttl, err = client.Do(ctx, "PTTL", "clicks:ttl")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(-1), ttl)
+
+	// У бессрочной ссылки счётчики хранятся без TTL
+	require.NoError(t, redisStorage.Insert(ctx, model.Link{ShortURL: "forever", LongURL: "https://example.org"}))
+	require.NoError(t, analytics.InsertClick(ctx, model.Click{ShortURL: "forever", Timestamp: time.Now()}))
+	ttl, err = client.Do(ctx, "PTTL", "clicks:forever")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(-1), ttl)
+}
+
+func TestRedis_BatchUpdateDelete(t *testing.T) {
+	ctx := context.Background()
+	redisStorage, _ := newRedisStorage(t)
+
+	assert.NoError(t, redisStorage.Insert(ctx, model.Link{ShortURL: "taken", LongURL: "https://example.com"}))
+	errs, err := redisStorage.InsertBatch(ctx, []model.Link{
+		{ShortURL: "one", LongURL: "https://example.com/1"},
+		{ShortURL: "taken", LongURL: "https://example.com/2"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []error{nil, storage.ErrAlreadyExists}, errs)
+
+	// Длинная ссылка уникальна, как в таблице urls
+	assert.Equal(t, storage.ErrAlreadyExists, redisStorage.Insert(ctx, model.Link{ShortURL: "two", LongURL: "https://example.com"}))
+	_, err = redisStorage.Update(ctx, model.Link{ShortURL: "one", LongURL: "https://example.com"})
+	assert.Equal(t, storage.ErrAlreadyExists, err)
+
+	updated, err := redisStorage.Update(ctx, model.Link{ShortURL: "one", LongURL: "https://example.org"})
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.org", updated.LongURL)
+	// Прежняя ссылка освобождается
+	assert.NoError(t, redisStorage.Insert(ctx, model.Link{ShortURL: "two", LongURL: "https://example.com/1"}))
+	_, err = redisStorage.Update(ctx, model.Link{ShortURL: "missing", LongURL: "https://example.org"})
+	assert.Equal(t, storage.ErrNotFound, err)
+
+	assert.NoError(t, redisStorage.Delete(ctx, "one", ""))
+	assert.Equal(t, storage.ErrNotFound, redisStorage.Delete(ctx, "one", ""))
+	assert.NoError(t, redisStorage.Insert(ctx, model.Link{ShortURL: "three", LongURL: "https://example.org"}))
+}
+
+func TestRedisClient_PoolLimit(t *testing.T) {
+	server, err := mocks.NewMockRedisServer()
+	require.NoError(t, err)
+	defer server.Close()
+	client, err := redis.NewClient(context.Background(), config.Redis{Addr: server.Addr(), PoolSize: 2})
+	require.NoError(t, err)
+	defer client.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := client.Do(context.Background(), "INCR", "counter")
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	reply, err := client.Do(context.Background(), "GET", "counter")
+	require.NoError(t, err)
+	assert.Equal(t, "20", reply)
+	assert.LessOrEqual(t, server.Accepted(), int64(2))
+
+	// Запрос, прерванный отменой контекста, освобождает место в пуле
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = client.Do(ctx, "PING")
+	assert.ErrorIs(t, err, context.Canceled)
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, client.Ping(context.Background()))
+	}
+}
@@ -1,10 +1,16 @@
 package tests
 
 import (
+	"context"
+	"math"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"url-shortener/internal/model"
 	"url-shortener/internal/service"
+	"url-shortener/pkg/alphabet"
 	"url-shortener/pkg/storage"
 
 	"url-shortener/tests/mocks"
@@ -15,12 +21,16 @@ import (
 func TestShortening(t *testing.T) {
 	mockStorage := new(mocks.MockStorage)
 
-	mockStorage.On("GetLongUrl", mock.Anything, mock.Anything).Return("", storage.ErrNotFound).Once()
-	mockStorage.On("Insert", mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
+	// Первый код занят другой ссылкой, второй свободен
+	hash := service.EncodeHash("https://example.com/")
+	mockStorage.On("Allocate", mock.Anything, model.Link{ShortURL: hash + "00", LongURL: "https://example.com/"}).
+		Return("", false, storage.ErrAlreadyExists).Once()
+	mockStorage.On("Allocate", mock.Anything, model.Link{ShortURL: hash + "01", LongURL: "https://example.com/"}).
+		Return(hash+"01", false, nil).Once()
 
-	service := service.NewShortenerService(mockStorage)
+	service := service.NewShortenerService(mockStorage, service.URLNormalizer{})
 
-	shortURL, err := service.Shortening("https://example.com")
+	shortURL, err := service.Shortening(context.Background(), model.Link{LongURL: "https://example.com"})
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -29,6 +39,7 @@ func TestShortening(t *testing.T) {
 	if len(shortURL) != 10 {
 		t.Errorf("expected length of 10, got %d", len(shortURL))
 	}
+	assert.Equal(t, hash+"01", shortURL)
 
 	// Проверяем, что все ожидания были выполнены
 	mockStorage.AssertExpectations(t)
@@ -36,13 +47,81 @@ func TestShortening(t *testing.T) {
 
 func TestExpansion(t *testing.T) {
 	mockStorage := new(mocks.MockStorage)
-	mockStorage.On("GetLongUrl", "test_short_url").Return("https://example.com", nil).Once()
+	mockStorage.On("GetLongUrl", mock.Anything, "test_short_url").Return("https://example.com", nil).Once()
 
-	service := service.NewShortenerService(mockStorage)
+	service := service.NewShortenerService(mockStorage, service.URLNormalizer{})
 
-	longURL, err := service.Expansion("test_short_url")
+	longURL, err := service.Expansion(context.Background(), "test_short_url")
 	assert.NoError(t, err)
 	assert.Equal(t, "https://example.com", longURL)
 
 	mockStorage.AssertExpectations(t)
 }
+
+func TestCustomShortening(t *testing.T) {
+	mockStorage := new(mocks.MockStorage)
+	mockStorage.On("GetLongUrl", mock.Anything, "q3-report").Return("", storage.ErrNotFound).Once()
+	mockStorage.On("Insert", mock.Anything, model.Link{ShortURL: "q3-report", LongURL: "https://example.com/q3"}).Return(nil).Once()
+
+	service := service.NewShortenerService(mockStorage, service.URLNormalizer{})
+
+	shortURL, err := service.CustomShortening(context.Background(), model.Link{ShortURL: "q3-report", LongURL: "https://example.com/q3"})
+	assert.NoError(t, err)
+	assert.Equal(t, "q3-report", shortURL)
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestCustomShorteningTaken(t *testing.T) {
+	mockStorage := new(mocks.MockStorage)
+	mockStorage.On("GetLongUrl", mock.Anything, "q3-report").Return("https://other.com", nil).Once()
+
+	shortener := service.NewShortenerService(mockStorage, service.URLNormalizer{})
+
+	_, err := shortener.CustomShortening(context.Background(), model.Link{ShortURL: "q3-report", LongURL: "https://example.com/q3"})
+	assert.ErrorIs(t, err, service.ErrAliasTaken)
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestValidateAlias(t *testing.T) {
+	assert.NoError(t, service.ValidateAlias("q3-report", service.Alphabet))
+	assert.ErrorIs(t, service.ValidateAlias("ab", service.Alphabet), service.ErrInvalidAlias)
+	assert.ErrorIs(t, service.ValidateAlias("-report", service.Alphabet), service.ErrInvalidAlias)
+	assert.ErrorIs(t, service.ValidateAlias("q3 report", service.Alphabet), service.ErrInvalidAlias)
+	assert.ErrorIs(t, service.ValidateAlias("Swagger", service.Alphabet), service.ErrInvalidAlias)
+	assert.NoError(t, service.ValidateAlias(strings.Repeat("a", 32), service.Alphabet))
+	assert.ErrorIs(t, service.ValidateAlias(strings.Repeat("a", 33), service.Alphabet), service.ErrInvalidAlias)
+	assert.ErrorIs(t, service.ValidateAlias("q3_report", alphabet.Base62), service.ErrInvalidAlias)
+}
+
+func TestResolveExpiry(t *testing.T) {
+	now := time.Date(2025, 3, 20, 12, 0, 0, 0, time.UTC)
+
+	expiresAt, err := service.ResolveExpiry(nil, 60, now)
+	assert.NoError(t, err)
+	assert.Equal(t, now.Add(time.Minute), expiresAt)
+
+	expiresAt, err = service.ResolveExpiry(nil, 0, now)
+	assert.NoError(t, err)
+	assert.True(t, expiresAt.IsZero())
+
+	past := now.Add(-time.Hour)
+	_, err = service.ResolveExpiry(&past, 0, now)
+	assert.ErrorIs(t, err, service.ErrInvalidExpiry)
+
+	future := now.Add(time.Hour)
+	_, err = service.ResolveExpiry(&future, 60, now)
+	assert.ErrorIs(t, err, service.ErrInvalidExpiry)
+
+	// Время жизни, не представимое в time.Duration, отклоняется, а не переполняется
+	maxTTL := int64(service.MaxTTL / time.Second)
+	expiresAt, err = service.ResolveExpiry(nil, maxTTL, now)
+	assert.NoError(t, err)
+	assert.Equal(t, now.Add(service.MaxTTL), expiresAt)
+	for _, ttl := range []int64{maxTTL + 1, math.MaxInt64} {
+		_, err = service.ResolveExpiry(nil, ttl, now)
+		assert.ErrorIs(t, err, service.ErrTTLTooLong)
+		assert.ErrorIs(t, err, service.ErrInvalidExpiry)
+	}
+}
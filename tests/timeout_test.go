@@ -0,0 +1,57 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"url-shortener/internal/controller"
+	"url-shortener/internal/service"
+
+	"url-shortener/tests/mocks"
+)
+
+func TestTimeoutStorage(t *testing.T) {
+	mockStorage := new(mocks.MockStorage)
+	mockStorage.On("GetLongUrl", mock.Anything, "slow").Return(
+		func(context.Context, string) string { return "" },
+		func(ctx context.Context, _ string) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	)
+	storage := service.NewTimeoutStorage(mockStorage, service.Timeouts{Read: 10 * time.Millisecond})
+
+	start := time.Now()
+	_, err := storage.GetLongUrl(context.Background(), "slow")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+
+	// Отмена контекста запроса прерывает операцию раньше срока
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = storage.GetLongUrl(ctx, "slow")
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestExpandEndpointTimeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+
+	mockService := new(mocks.MockShortenerService)
+	mockService.On("Expansion", mock.Anything, "slow").Return("", context.DeadlineExceeded).Once()
+
+	h := handler.NewHandler(mockService, new(mocks.MockAnalyticsService), &mocks.MockLogger{}, handler.Options{})
+	h.Register(router, handler.Middlewares{})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+	mockService.AssertExpectations(t)
+}